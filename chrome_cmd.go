@@ -0,0 +1,149 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: whosee chrome 子命令 - 不启动HTTP服务管理Chrome二进制，用于CI构建镜像和离线环境预置
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"whosee/utils"
+)
+
+const chromeUsage = `用法: whosee chrome <命令> [参数]
+
+命令:
+  info                     显示平台、Chrome路径、安装记录和可用下载源
+  download [--version V]   下载Chrome；V为Chrome for Testing版本号（如131.0.6778.108）或Chromium快照修订号
+  test                     以无头模式启动Chrome，确认可以正常运行
+  cleanup                  删除下载目录中当前安装以外的旧版本和残留压缩包
+  verify                   按安装记录校验文件的SHA-256，按清单或上游校验和确认压缩包，并检查可执行文件的代码签名
+
+离线安装: CHROME_ARCHIVE_PATH 本地压缩包，CHROME_MIRROR_BASE 内部镜像，CHROME_VERSION 固定版本，
+CHROME_SHA256_MANIFEST SHA-256清单，CHROME_OFFLINE_STRICT=true 禁止访问公网
+`
+
+// runChromeCommand 执行chrome子命令，返回进程退出码：0成功，1失败，2参数错误
+func runChromeCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, chromeUsage)
+		return 2
+	}
+
+	downloader := utils.NewChromeDownloader()
+	switch args[0] {
+	case "info":
+		return chromeInfo(downloader)
+	case "download":
+		return chromeDownload(downloader, args[1:])
+	case "test":
+		if err := downloader.TestChrome(); err != nil {
+			fmt.Fprintf(os.Stderr, "Chrome测试失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Chrome测试通过: %s\n", downloader.GetChromeExecutablePath())
+		return 0
+	case "cleanup":
+		removed, err := downloader.CleanupOldVersions()
+		for _, path := range removed {
+			fmt.Printf("已删除: %s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "清理失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("清理完成，共删除 %d 项\n", len(removed))
+		return 0
+	case "verify":
+		return chromeVerify(downloader)
+	case "help", "-h", "--help":
+		fmt.Print(chromeUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n%s", args[0], chromeUsage)
+		return 2
+	}
+}
+
+func chromeInfo(downloader *utils.ChromeDownloader) int {
+	data, err := json.MarshalIndent(downloader.GetChromeInfo(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "序列化Chrome信息失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+func chromeDownload(downloader *utils.ChromeDownloader, args []string) int {
+	flags := flag.NewFlagSet("download", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	version := flags.String("version", "", "Chrome版本号或快照修订号，默认CHROME_VERSION或最新可用版本")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		fmt.Fprint(os.Stderr, chromeUsage)
+		return 2
+	}
+	if *version != "" {
+		if err := downloader.SetVersion(*version); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if err := downloader.DownloadChrome(); err != nil {
+		fmt.Fprintf(os.Stderr, "下载失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Chrome已下载: %s\n", downloader.GetChromeExecutablePath())
+	return 0
+}
+
+func chromeVerify(downloader *utils.ChromeDownloader) int {
+	result, err := downloader.VerifyChrome()
+	if result != nil {
+		fmt.Printf("版本: %s\n来源: %s\n压缩包SHA-256: %s\n已校验文件: %d\n",
+			result.Record.Version, result.Record.SourceURL, result.Record.ArchiveSHA256, result.Checked)
+		if result.Record.Revision != "" {
+			fmt.Printf("快照修订号: %s\n", result.Record.Revision)
+		}
+		if result.ManifestChecked {
+			fmt.Println("压缩包SHA-256与清单一致")
+		}
+		if result.UpstreamChecked {
+			fmt.Println("压缩包MD5与下载源公布的校验和一致")
+		}
+		for _, name := range result.Missing {
+			fmt.Printf("缺失: %s\n", name)
+		}
+		for _, name := range result.Modified {
+			fmt.Printf("已修改: %s\n", name)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "校验失败: %v\n", err)
+		return 1
+	}
+
+	switch result.Signature {
+	case utils.SignatureValid:
+		fmt.Printf("代码签名有效: %s\n", result.Executable)
+	case utils.SignatureUnsigned:
+		fmt.Printf("警告: %s 没有代码签名（Chromium快照构建不签名）\n", result.Executable)
+	default:
+		fmt.Println("当前平台的Chrome发行包没有代码签名")
+	}
+
+	// 只与安装记录一致不能说明文件可信：记录是安装时写入的，可能连同文件一起被替换
+	if !result.Trusted() {
+		fmt.Fprintln(os.Stderr, "校验失败: 文件只与安装时写入的记录一致，没有可信的清单、上游校验和或代码签名可以确认；"+
+			"请配置CHROME_SHA256_MANIFEST，或从公布校验和的下载源重新下载")
+		return 1
+	}
+	fmt.Println("校验通过")
+	return 0
+}
@@ -61,17 +61,20 @@ func WhoisHandler(c *gin.Context) {
 
 		// 构建响应数据
 		result := gin.H{
-			"available":      response.Available,
-			"domain":         response.Domain,
-			"registrar":      response.Registrar,
-			"creationDate":   response.CreateDate,
-			"expiryDate":     response.ExpiryDate,
-			"status":         response.Status,
-			"nameServers":    response.NameServers,
-			"updatedDate":    response.UpdateDate,
-			"statusCode":     response.StatusCode,
-			"statusMessage":  response.StatusMessage,
-			"sourceProvider": response.SourceProvider,
+			"available":         response.Available,
+			"domain":            response.Domain,
+			"registrar":         response.Registrar,
+			"creationDate":      response.CreateDate,
+			"expiryDate":        response.ExpiryDate,
+			"status":            response.Status,
+			"nameServers":       response.NameServers,
+			"updatedDate":       response.UpdateDate,
+			"statusCode":        response.StatusCode,
+			"statusMessage":     response.StatusMessage,
+			"sourceProvider":    response.SourceProvider,
+			"subdomain":         response.Subdomain,
+			"registrableDomain": response.RegistrableDomain,
+			"effectiveTLD":      response.EffectiveTLD,
 		}
 
 		// 简化缓存日志
@@ -410,18 +413,21 @@ func RDAPHandler(c *gin.Context) {
 
 		// 构建RDAP专用响应数据
 		result := gin.H{
-			"available":      response.Available,
-			"domain":         response.Domain,
-			"registrar":      response.Registrar,
-			"creationDate":   response.CreateDate,
-			"expiryDate":     response.ExpiryDate,
-			"status":         response.Status,
-			"nameServers":    response.NameServers,
-			"updatedDate":    response.UpdateDate,
-			"statusCode":     response.StatusCode,
-			"statusMessage":  response.StatusMessage,
-			"sourceProvider": "IANA-RDAP",
-			"protocol":       "RDAP",
+			"available":         response.Available,
+			"domain":            response.Domain,
+			"registrar":         response.Registrar,
+			"creationDate":      response.CreateDate,
+			"expiryDate":        response.ExpiryDate,
+			"status":            response.Status,
+			"nameServers":       response.NameServers,
+			"updatedDate":       response.UpdateDate,
+			"statusCode":        response.StatusCode,
+			"statusMessage":     response.StatusMessage,
+			"sourceProvider":    "IANA-RDAP",
+			"protocol":          "RDAP",
+			"subdomain":         response.Subdomain,
+			"registrableDomain": response.RegistrableDomain,
+			"effectiveTLD":      response.EffectiveTLD,
 		}
 
 		// 简化缓存日志
@@ -131,26 +131,39 @@ func DNSQuery(c *gin.Context, rdb *redis.Client) {
 	domainStr := domain.(string)
 	log.Printf("DNSQuery: 开始查询域名: %s", domainStr)
 
-	// 尝试从Redis获取缓存
-	cacheKey := utils.BuildCacheKey("cache", "dns", utils.SanitizeDomain(domainStr))
-	log.Printf("DNSQuery: 尝试从Redis获取缓存，键: %s", cacheKey)
-	if cached, ok := getDNSCache(context.Background(), rdb, cacheKey); ok {
+	response := lookupDNSRecords(context.Background(), rdb, domainStr)
+	if response.IsCached {
 		c.Header("X-Cache", "HIT")
-		c.JSON(200, cached)
+		c.JSON(200, response)
 		return
 	}
 
+	elapsedTime := time.Since(startTime)
+	log.Printf("DNSQuery: 完成查询，域名: %s, 耗时: %v, 找到记录数: %d", domainStr, elapsedTime, len(response.Records))
+
+	c.Header("X-Cache", "MISS")
+	c.JSON(200, response)
+}
+
+// lookupDNSRecords 查询域名的全部DNS记录，优先使用缓存，新结果缓存1小时
+func lookupDNSRecords(ctx context.Context, rdb *redis.Client, domain string) *DNSResponse {
+	// 尝试从Redis获取缓存
+	cacheKey := utils.BuildCacheKey("cache", "dns", utils.SanitizeDomain(domain))
+	if cached, ok := getDNSCache(ctx, rdb, cacheKey); ok {
+		return cached
+	}
+
 	// 查询各种DNS记录（分解后的调用）
 	records := []DNSRecord{}
-	records = append(records, queryAAndAAAA(domainStr)...)
-	records = append(records, queryMX(domainStr)...)
-	records = append(records, queryNS(domainStr)...)
-	records = append(records, queryTXT(domainStr)...)
-	records = append(records, queryCNAME(domainStr)...)
+	records = append(records, queryAAndAAAA(domain)...)
+	records = append(records, queryMX(domain)...)
+	records = append(records, queryNS(domain)...)
+	records = append(records, queryTXT(domain)...)
+	records = append(records, queryCNAME(domain)...)
 
 	// 构建响应
 	response := &DNSResponse{
-		Domain:    domainStr,
+		Domain:    domain,
 		Records:   records,
 		QueryTime: time.Now().Format("2006-01-02 15:04:05"),
 		IsCached:  false,
@@ -158,13 +171,6 @@ func DNSQuery(c *gin.Context, rdb *redis.Client) {
 	}
 
 	// 缓存结果 (1小时)
-	setDNSCache(context.Background(), rdb, cacheKey, response, 1*time.Hour)
-
-	elapsedTime := time.Since(startTime)
-	log.Printf("DNSQuery: 完成查询，域名: %s, 耗时: %v, 找到记录数: %d", domainStr, elapsedTime, len(records))
-
-	c.Header("X-Cache", "MISS")
-	// 确保响应中包含缓存状态字段
-	response.IsCached = false
-	c.JSON(200, response)
+	setDNSCache(ctx, rdb, cacheKey, response, 1*time.Hour)
+	return response
 }
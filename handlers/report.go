@@ -0,0 +1,338 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 域名聚合报告处理程序 - 并行执行WHOIS、RDAP、DNS和截图查询
+ */
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"whosee/services"
+	"whosee/types"
+	"whosee/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// 报告分节名称
+const (
+	ReportSectionWhois      = "whois"
+	ReportSectionRDAP       = "rdap"
+	ReportSectionDNS        = "dns"
+	ReportSectionScreenshot = "screenshot"
+)
+
+// reportSectionOrder 报告分节的默认顺序
+var reportSectionOrder = []string{ReportSectionWhois, ReportSectionRDAP, ReportSectionDNS, ReportSectionScreenshot}
+
+// reportSectionTimeouts 各分节超时时间，与对应单独接口的超时保持一致
+var reportSectionTimeouts = map[string]time.Duration{
+	ReportSectionWhois:      15 * time.Second,
+	ReportSectionRDAP:       15 * time.Second,
+	ReportSectionDNS:        10 * time.Second,
+	ReportSectionScreenshot: 60 * time.Second,
+}
+
+// reportSectionFunc 分节查询函数，返回数据及是否来自缓存
+type reportSectionFunc func(ctx context.Context, domain string) (interface{}, bool, error)
+
+// ReportHandler 域名聚合报告处理器
+type ReportHandler struct {
+	screenshotService *services.ScreenshotService
+	chromeManager     *services.ChromeManager
+	renderer          *services.ReportRenderer
+}
+
+// NewReportHandler 创建聚合报告处理器
+func NewReportHandler(screenshotService *services.ScreenshotService, chromeManager *services.ChromeManager, renderer *services.ReportRenderer) *ReportHandler {
+	return &ReportHandler{
+		screenshotService: screenshotService,
+		chromeManager:     chromeManager,
+		renderer:          renderer,
+	}
+}
+
+// GetReport 生成域名聚合报告，sections参数以逗号分隔选择分节，默认包含全部分节
+// format=html|pdf 时渲染为报告文件，download=true 时直接下载文件
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	domain, _ := c.Get("domain")
+	domainStr := domain.(string)
+	reqCtx, _ := c.Get("requestContext")
+	workerPool, _ := c.Get("workerPool")
+	requestContext := reqCtx.(context.Context)
+	pool := workerPool.(*services.WorkerPool)
+
+	sections, err := parseReportSections(c.Query("sections"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, "INVALID_SECTIONS", err.Error())
+		return
+	}
+	format, err := services.ParseReportFormat(c.Query("format"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, "INVALID_FORMAT", err.Error())
+		return
+	}
+
+	startTime := time.Now()
+
+	// 文件格式优先使用缓存，命中时无需重新查询各分节
+	if format != "" && h.renderer != nil {
+		if cached := h.renderer.GetCached(requestContext, domainStr, sections, format); cached != nil {
+			log.Printf("[REPORT] 使用缓存的 %s 报告: %s", format, domainStr)
+			h.respondFile(c, cached, startTime)
+			return
+		}
+	}
+
+	log.Printf("[REPORT] 生成域名 %s 的报告，分节: %s", domainStr, strings.Join(sections, ","))
+
+	report := h.buildReport(requestContext, pool, h.sectionFuncs(c), domainStr, sections)
+
+	log.Printf("[REPORT] 域名 %s 报告完成，成功 %d/%d，处理时间: %dms",
+		domainStr, report.Summary.Succeeded, report.Summary.Requested, time.Since(startTime).Milliseconds())
+
+	if format == "" {
+		utils.SuccessResponse(c, report, &utils.MetaInfo{
+			Timestamp:  time.Now().Format(time.RFC3339),
+			Processing: time.Since(startTime).Milliseconds(),
+		})
+		return
+	}
+
+	if h.renderer == nil {
+		utils.ErrorResponse(c, 503, "SERVICE_UNAVAILABLE", "Report rendering is not available")
+		return
+	}
+	if report.Summary.Succeeded == 0 {
+		utils.ErrorResponse(c, 502, "REPORT_EMPTY", "All report sections failed")
+		return
+	}
+
+	file, err := h.renderer.Render(requestContext, report, sections, format)
+	if err != nil {
+		log.Printf("[REPORT] 渲染域名 %s 的 %s 报告失败: %v", domainStr, format, err)
+		utils.ErrorResponse(c, 500, "RENDER_ERROR", err.Error())
+		return
+	}
+	h.respondFile(c, file, startTime)
+}
+
+// respondFile 返回报告文件信息，或在download=true时直接下载文件
+func (h *ReportHandler) respondFile(c *gin.Context, file *services.ReportFile, startTime time.Time) {
+	if c.Query("download") == "true" {
+		content, err := h.renderer.ReadFile(c.Request.Context(), file)
+		if err != nil {
+			log.Printf("[REPORT] 读取报告文件失败: %v", err)
+			utils.ErrorResponse(c, 500, "FILE_ERROR", "Failed to read report file")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-report.%s", file.Domain, file.Format)))
+		c.Data(200, file.Format.ContentType(), content)
+		return
+	}
+
+	// 本地存储的报告不公开，返回需要同样认证的下载地址
+	if file.FileURL == "" {
+		query := c.Request.URL.Query()
+		query.Set("download", "true")
+		file.FileURL = c.Request.URL.Path + "?" + query.Encode()
+	}
+
+	utils.SuccessResponse(c, file, &utils.MetaInfo{
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Cached:     file.FromCache,
+		CachedAt:   cachedAt(file),
+		Processing: time.Since(startTime).Milliseconds(),
+	})
+}
+
+func cachedAt(file *services.ReportFile) string {
+	if file.FromCache {
+		return file.GeneratedAt
+	}
+	return ""
+}
+
+// buildReport 将各分节提交到工作池并行执行，单个分节失败或超时不影响其他分节
+func (h *ReportHandler) buildReport(ctx context.Context, pool *services.WorkerPool, funcs map[string]reportSectionFunc, domain string, sections []string) *types.DomainReport {
+	type sectionResult struct {
+		name    string
+		section *types.ReportSection
+	}
+	results := make(chan sectionResult, len(sections))
+
+	for _, name := range sections {
+		go func(name string) {
+			results <- sectionResult{name: name, section: runReportSection(ctx, pool, name, funcs[name], domain)}
+		}(name)
+	}
+
+	report := &types.DomainReport{
+		Domain:      domain,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Sections:    make(map[string]*types.ReportSection, len(sections)),
+		Summary:     types.ReportSummary{Requested: len(sections)},
+	}
+	for range sections {
+		r := <-results
+		report.Sections[r.name] = r.section
+		if r.section.Status == types.ReportStatusSuccess {
+			report.Summary.Succeeded++
+		} else {
+			report.Summary.Failed++
+		}
+	}
+	return report
+}
+
+// runReportSection 在工作池中执行单个分节，并在分节超时后立即返回
+func runReportSection(ctx context.Context, pool *services.WorkerPool, name string, fn reportSectionFunc, domain string) *types.ReportSection {
+	sectionCtx, cancel := context.WithTimeout(ctx, reportSectionTimeouts[name])
+	defer cancel()
+
+	type outcome struct {
+		data   interface{}
+		cached bool
+		err    error
+	}
+	// 带缓冲，超时返回后工作池中的任务仍可写入而不阻塞
+	done := make(chan outcome, 1)
+	startTime := time.Now()
+
+	submitted := pool.SubmitWithContext(sectionCtx, func() {
+		if sectionCtx.Err() != nil {
+			done <- outcome{err: sectionCtx.Err()}
+			return
+		}
+		data, cached, err := fn(sectionCtx, domain)
+		done <- outcome{data: data, cached: cached, err: err}
+	})
+	if !submitted {
+		log.Printf("[REPORT] 分节 %s 提交失败: 工作池忙碌", name)
+		return &types.ReportSection{
+			Status: types.ReportStatusBusy,
+			Error:  "Service is busy, please try again later",
+		}
+	}
+
+	select {
+	case o := <-done:
+		section := &types.ReportSection{Latency: time.Since(startTime).Milliseconds(), Cached: o.cached}
+		if o.err != nil {
+			log.Printf("[REPORT] 分节 %s 查询域名 %s 失败: %v", name, domain, o.err)
+			section.Status = types.ReportStatusError
+			section.Error = o.err.Error()
+			if sectionCtx.Err() == context.DeadlineExceeded {
+				section.Status = types.ReportStatusTimeout
+			}
+			return section
+		}
+		section.Status = types.ReportStatusSuccess
+		section.Data = o.data
+		return section
+	case <-sectionCtx.Done():
+		log.Printf("[REPORT] 分节 %s 查询域名 %s 超时", name, domain)
+		return &types.ReportSection{
+			Status:  types.ReportStatusTimeout,
+			Error:   fmt.Sprintf("section timed out after %v", reportSectionTimeouts[name]),
+			Latency: time.Since(startTime).Milliseconds(),
+		}
+	}
+}
+
+// sectionFuncs 构建各分节的查询函数，依赖的服务从请求上下文中获取
+func (h *ReportHandler) sectionFuncs(c *gin.Context) map[string]reportSectionFunc {
+	var manager *services.WhoisManager
+	if value, exists := c.Get("whoisManager"); exists {
+		manager, _ = value.(*services.WhoisManager)
+	}
+	var rdb *redis.Client
+	if value, exists := c.Get("redis"); exists {
+		rdb, _ = value.(*redis.Client)
+	}
+
+	return map[string]reportSectionFunc{
+		ReportSectionWhois: func(ctx context.Context, domain string) (interface{}, bool, error) {
+			if manager == nil {
+				return nil, false, fmt.Errorf("WHOIS service not available")
+			}
+			response, err, fromCache := manager.Query(domain)
+			return response, fromCache, err
+		},
+		ReportSectionRDAP: func(ctx context.Context, domain string) (interface{}, bool, error) {
+			if manager == nil {
+				return nil, false, fmt.Errorf("RDAP service not available")
+			}
+			response, err, fromCache := manager.QueryWithProvider(domain, "IANA-RDAP")
+			return response, fromCache, err
+		},
+		ReportSectionDNS: func(ctx context.Context, domain string) (interface{}, bool, error) {
+			response := lookupDNSRecords(ctx, rdb, domain)
+			return response, response.IsCached, nil
+		},
+		ReportSectionScreenshot: h.screenshotSection,
+	}
+}
+
+// screenshotSection 使用统一截图服务获取基础截图，并记录熔断器统计
+func (h *ReportHandler) screenshotSection(ctx context.Context, domain string) (interface{}, bool, error) {
+	if h.screenshotService == nil {
+		return nil, false, fmt.Errorf("screenshot service not available")
+	}
+
+	startTime := time.Now()
+	response, err := h.screenshotService.TakeScreenshot(ctx, &services.ScreenshotRequest{
+		Type:   services.TypeBasic,
+		Domain: domain,
+		Format: services.FormatFile,
+	})
+	if err != nil {
+		h.chromeManager.OnFailure(time.Since(startTime))
+		return nil, false, err
+	}
+	if !response.Success {
+		h.chromeManager.OnFailure(time.Since(startTime))
+		return nil, false, fmt.Errorf("%s: %s", response.Error, response.Message)
+	}
+	if !response.FromCache {
+		h.chromeManager.OnSuccess(time.Since(startTime))
+	}
+	return response, response.FromCache, nil
+}
+
+// parseReportSections 解析sections参数，未指定时返回全部分节
+func parseReportSections(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return reportSectionOrder, nil
+	}
+
+	requested := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if _, ok := reportSectionTimeouts[name]; !ok {
+			return nil, fmt.Errorf("unknown section: %s (available: %s)", name, strings.Join(reportSectionOrder, ","))
+		}
+		requested[name] = true
+	}
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("no valid section specified")
+	}
+
+	// 按默认顺序输出，去除重复项
+	sections := make([]string, 0, len(requested))
+	for _, name := range reportSectionOrder {
+		if requested[name] {
+			sections = append(sections, name)
+		}
+	}
+	return sections, nil
+}
@@ -128,24 +128,24 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 
 	// 执行截图
 	err := sb.ItdogBreaker.Execute(func() error {
-		// 获取全局Chrome工具
-		chromeUtil := utils.GetGlobalChromeUtil()
-		if chromeUtil == nil {
-			return fmt.Errorf("Chrome工具未初始化")
+		// 获取全局Chrome管理器
+		chromeManager := services.GetGlobalChromeManager()
+		if chromeManager == nil {
+			return fmt.Errorf("Chrome管理器未初始化")
 		}
 
 		// 增加重试机制
 		maxRetries := 2
 		for retry := 0; retry <= maxRetries; retry++ {
 			if retry > 0 {
-				log.Printf("[CHROME-UTIL] %s重试第 %d 次", config.Description, retry)
+				log.Printf("[CHROME-MANAGER] %s重试第 %d 次", config.Description, retry)
 				time.Sleep(time.Duration(retry) * 2 * time.Second) // 递增等待时间
 			}
 
-			// 从Chrome工具获取上下文，设置120秒超时（增加超时时间）
-			ctx, cancel, chromeErr := chromeUtil.GetContext(120 * time.Second)
+			// 从Chrome管理器获取上下文，设置120秒超时（增加超时时间）
+			ctx, cancel, chromeErr := chromeManager.GetContext(120 * time.Second)
 			if chromeErr != nil {
-				log.Printf("[CHROME-UTIL] 获取Chrome上下文失败 (重试 %d/%d): %v", retry, maxRetries, chromeErr)
+				log.Printf("[CHROME-MANAGER] 获取Chrome上下文失败 (重试 %d/%d): %v", retry, maxRetries, chromeErr)
 				if retry == maxRetries {
 					return fmt.Errorf("获取Chrome上下文失败: %v", chromeErr)
 				}
@@ -156,7 +156,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 			select {
 			case <-ctx.Done():
 				cancel()
-				log.Printf("[CHROME-UTIL] 上下文在使用前已被取消 (重试 %d/%d)", retry, maxRetries)
+				log.Printf("[CHROME-MANAGER] 上下文在使用前已被取消 (重试 %d/%d)", retry, maxRetries)
 				if retry == maxRetries {
 					return fmt.Errorf("上下文在使用前已被取消")
 				}
@@ -164,7 +164,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 			default:
 			}
 
-			log.Printf("[CHROME-UTIL] 开始执行%s操作，域名: %s (重试 %d/%d)", config.Description, config.Domain, retry, maxRetries)
+			log.Printf("[CHROME-MANAGER] 开始执行%s操作，域名: %s (重试 %d/%d)", config.Description, config.Domain, retry, maxRetries)
 
 			// 截图数据
 			var buf []byte
@@ -179,7 +179,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤1: 导航到ITDog页面: %s", fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain))
+					log.Printf("[CHROME-MANAGER] 步骤1: 导航到ITDog页面: %s", fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain))
 					return chromedp.Navigate(fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain)).Do(ctx)
 				}),
 
@@ -190,7 +190,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤2: 等待页面加载完成")
+					log.Printf("[CHROME-MANAGER] 步骤2: 等待页面加载完成")
 					return chromedp.Sleep(2 * time.Second).Do(ctx)
 				}),
 
@@ -201,7 +201,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤3: 等待单次测试按钮出现")
+					log.Printf("[CHROME-MANAGER] 步骤3: 等待单次测试按钮出现")
 					return chromedp.WaitVisible(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery).Do(ctx)
 				}),
 
@@ -212,7 +212,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤4: 点击单次测试按钮")
+					log.Printf("[CHROME-MANAGER] 步骤4: 点击单次测试按钮")
 					return chromedp.Click(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery).Do(ctx)
 				}),
 
@@ -223,7 +223,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤5: 等待测试开始")
+					log.Printf("[CHROME-MANAGER] 步骤5: 等待测试开始")
 					return chromedp.Sleep(3 * time.Second).Do(ctx)
 				}),
 
@@ -234,13 +234,13 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						var attempts int
 						maxAttempts := 60 // 增加最大尝试次数
 
-						log.Printf("[CHROME-UTIL] 步骤6: 开始检查%s测试进度", config.Description)
+						log.Printf("[CHROME-MANAGER] 步骤6: 开始检查%s测试进度", config.Description)
 
 						for attempts < maxAttempts {
 							// 检查上下文是否已取消
 							select {
 							case <-ctx.Done():
-								log.Printf("[CHROME-UTIL] %s上下文已取消，停止等待", config.Description)
+								log.Printf("[CHROME-MANAGER] %s上下文已取消，停止等待", config.Description)
 								return ctx.Err()
 							default:
 							}
@@ -260,18 +260,18 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 							})()`, &isDone).Do(ctx)
 
 							if err != nil {
-								log.Printf("[CHROME-UTIL] %s进度检查出错: %v", config.Description, err)
+								log.Printf("[CHROME-MANAGER] %s进度检查出错: %v", config.Description, err)
 								return err
 							}
 
 							if isDone {
-								log.Printf("[CHROME-UTIL] %s测试完成，进度: %d/%d", config.Description, attempts, maxAttempts)
+								log.Printf("[CHROME-MANAGER] %s测试完成，进度: %d/%d", config.Description, attempts, maxAttempts)
 								return nil // 测试完成，退出循环
 							}
 
 							// 每5次尝试打印一次进度
 							if attempts%5 == 0 && attempts > 0 {
-								log.Printf("[CHROME-UTIL] %s等待测试完成，已等待 %d 秒", config.Description, attempts)
+								log.Printf("[CHROME-MANAGER] %s等待测试完成，已等待 %d 秒", config.Description, attempts)
 							}
 
 							// 等待1秒后再次检查
@@ -283,7 +283,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 							}
 						}
 
-						log.Printf("[CHROME-UTIL] %s等待超时，已尝试 %d 次", config.Description, attempts)
+						log.Printf("[CHROME-MANAGER] %s等待超时，已尝试 %d 次", config.Description, attempts)
 						return nil // 达到最大尝试次数，继续执行
 					})
 				}(),
@@ -295,7 +295,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤7: 等待页面元素更新")
+					log.Printf("[CHROME-MANAGER] 步骤7: 等待页面元素更新")
 					return chromedp.Sleep(5 * time.Second).Do(ctx)
 				}),
 
@@ -307,14 +307,14 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 							return ctx.Err()
 						default:
 						}
-						log.Printf("[CHROME-UTIL] 步骤8: 开始截图")
+						log.Printf("[CHROME-MANAGER] 步骤8: 开始截图")
 						if strings.HasPrefix(config.Selector, "//") {
 							// 使用XPath选择器
-							log.Printf("[CHROME-UTIL] 使用XPath选择器截图: %s", config.Selector)
+							log.Printf("[CHROME-MANAGER] 使用XPath选择器截图: %s", config.Selector)
 							return chromedp.Screenshot(config.Selector, &buf, chromedp.NodeVisible, chromedp.BySearch).Do(ctx)
 						} else {
 							// 使用CSS选择器
-							log.Printf("[CHROME-UTIL] 使用CSS选择器截图: %s", config.Selector)
+							log.Printf("[CHROME-MANAGER] 使用CSS选择器截图: %s", config.Selector)
 							return chromedp.Screenshot(config.Selector, &buf, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
 						}
 					})
@@ -325,7 +325,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 			cancel()
 
 			if err != nil {
-				log.Printf("[CHROME-UTIL] %s失败 (重试 %d/%d): %v", config.Description, retry, maxRetries, err)
+				log.Printf("[CHROME-MANAGER] %s失败 (重试 %d/%d): %v", config.Description, retry, maxRetries, err)
 				if strings.Contains(err.Error(), "context canceled") && retry < maxRetries {
 					continue // 重试
 				}
@@ -335,7 +335,7 @@ func performITDogScreenshot(config ITDogScreenshotConfig, rdb *redis.Client) (*S
 				continue
 			}
 
-			log.Printf("[CHROME-UTIL] %s截图成功，大小: %d bytes", config.Description, len(buf))
+			log.Printf("[CHROME-MANAGER] %s截图成功，大小: %d bytes", config.Description, len(buf))
 
 			// 保存截图
 			if err := writeFile(config.FilePath, buf); err != nil {
@@ -471,29 +471,25 @@ func Screenshot(c *gin.Context, rdb *redis.Client) {
 
 	var err error
 	err = sb.ScreenshotBreaker.Execute(func() error {
-		// 临时绕过Chrome工具，直接使用chromedp进行测试
-		log.Printf("[DEBUG] 绕过Chrome工具，直接使用chromedp进行截图")
-
-		// 创建上下文
-		tempCtx, tempCancel := chromedp.NewContext(context.Background())
+		// 使用全局Chrome管理器（已包含超时）
+		tempCtx, tempCancel, err := services.GetGlobalChromeManager().GetContext(90 * time.Second)
+		if err != nil {
+			return fmt.Errorf("获取Chrome上下文失败: %v", err)
+		}
 		defer tempCancel()
 
-		// 设置超时
-		tempCtx, timeoutCancel := context.WithTimeout(tempCtx, 90*time.Second)
-		defer timeoutCancel()
-
 		// 截图数据
 		var buf []byte
 
 		// 执行截图
-		err := chromedp.Run(tempCtx,
+		err = chromedp.Run(tempCtx,
 			chromedp.Navigate(fmt.Sprintf("https://%s", domain)),
 			chromedp.Sleep(8*time.Second),
 			chromedp.CaptureScreenshot(&buf),
 		)
 
 		if err != nil {
-			log.Printf("[DEBUG] 直接使用chromedp截图失败: %v", err)
+			log.Printf("[CHROME-MANAGER] 截图失败: %v", err)
 			return err
 		}
 
@@ -503,7 +499,7 @@ func Screenshot(c *gin.Context, rdb *redis.Client) {
 			return err
 		}
 
-		log.Printf("[DEBUG] 直接使用chromedp截图成功，大小: %d bytes", len(buf))
+		log.Printf("[CHROME-MANAGER] 截图成功，大小: %d bytes", len(buf))
 		return nil
 	})
 
@@ -586,43 +582,23 @@ func ScreenshotBase64(c *gin.Context, rdb *redis.Client) {
 	// 完整URL
 	url := fmt.Sprintf("https://%s", domain)
 
-	// 优先使用全局Chrome工具
-	chromeUtil := utils.GetGlobalChromeUtil()
-	var ctx context.Context
-	var cancel context.CancelFunc
-	var timeoutCancel context.CancelFunc
-
-	if chromeUtil != nil {
-		// 使用全局Chrome工具（已包含超时）
-		globalCtx, globalCancel, err := chromeUtil.GetContext(30 * time.Second)
-		if err != nil {
-			log.Printf("获取全局Chrome上下文失败: %v，回退到chromedp.NewContext", err)
-			// 回退到chromedp.NewContext
-			ctx, cancel = chromedp.NewContext(context.Background())
-			// 为回退方案设置超时
-			ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-			defer timeoutCancel()
-		} else {
-			ctx = globalCtx
-			cancel = globalCancel
-		}
-	} else {
-		// 回退到chromedp.NewContext
-		ctx, cancel = chromedp.NewContext(context.Background())
-		// 为回退方案设置超时
-		ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-		defer timeoutCancel()
-	}
-
-	if cancel != nil {
-		defer cancel()
+	// 使用全局Chrome管理器（已包含超时）
+	ctx, cancel, err := services.GetGlobalChromeManager().GetContext(30 * time.Second)
+	if err != nil {
+		log.Printf("获取Chrome上下文失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, ScreenshotResponse{
+			Success: false,
+			Error:   "截图服务暂不可用",
+			Message: fmt.Sprintf("获取Chrome上下文失败: %v", err),
+		})
+		return
 	}
+	defer cancel()
 
 	// 截图数据
 	var buf []byte
 
 	// 执行截图
-	var err error
 	err = chromedp.Run(ctx,
 		chromedp.Navigate(url),
 		chromedp.Sleep(5*time.Second),
@@ -722,43 +698,18 @@ func ElementScreenshot(c *gin.Context, rdb *redis.Client) {
 	// 使用chromedp获取元素截图
 	log.Printf("开始获取元素截图: %s, 选择器: %s", req.URL, req.Selector)
 
-	// 优先使用全局Chrome工具
-	chromeUtil := utils.GetGlobalChromeUtil()
-	var ctx context.Context
-	var cancel context.CancelFunc
-	var timeoutCancel context.CancelFunc
-
-	if chromeUtil != nil {
-		// 使用全局Chrome工具（已包含超时）
-		globalCtx, globalCancel, err := chromeUtil.GetContext(30 * time.Second)
-		if err != nil {
-			log.Printf("获取全局Chrome上下文失败: %v，回退到chromedp.NewContext", err)
-			// 回退到chromedp.NewContext
-			ctx, cancel = chromedp.NewContext(
-				context.Background(),
-				chromedp.WithLogf(log.Printf),
-			)
-			// 为回退方案设置超时
-			ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-			defer timeoutCancel()
-		} else {
-			ctx = globalCtx
-			cancel = globalCancel
-		}
-	} else {
-		// 回退到chromedp.NewContext
-		ctx, cancel = chromedp.NewContext(
-			context.Background(),
-			chromedp.WithLogf(log.Printf),
-		)
-		// 为回退方案设置超时
-		ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-		defer timeoutCancel()
-	}
-
-	if cancel != nil {
-		defer cancel()
+	// 使用全局Chrome管理器（已包含超时）
+	ctx, cancel, err := services.GetGlobalChromeManager().GetContext(30 * time.Second)
+	if err != nil {
+		log.Printf("获取Chrome上下文失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, ScreenshotResponse{
+			Success: false,
+			Error:   "截图服务暂不可用",
+			Message: fmt.Sprintf("获取Chrome上下文失败: %v", err),
+		})
+		return
 	}
+	defer cancel()
 
 	// 截图数据
 	var buf []byte
@@ -770,7 +721,6 @@ func ElementScreenshot(c *gin.Context, rdb *redis.Client) {
 	}
 
 	// 执行截图
-	var err error
 	err = chromedp.Run(ctx,
 		chromedp.Navigate(req.URL),
 		chromedp.Sleep(waitTime), // 等待页面加载完成
@@ -878,43 +828,23 @@ func ElementScreenshotBase64(c *gin.Context, rdb *redis.Client) {
 		waitTime = time.Duration(req.Wait) * time.Second
 	}
 
-	// 优先使用全局Chrome工具
-	chromeUtil := utils.GetGlobalChromeUtil()
-	var ctx context.Context
-	var cancel context.CancelFunc
-	var timeoutCancel context.CancelFunc
-
-	if chromeUtil != nil {
-		// 使用全局Chrome工具（已包含超时）
-		globalCtx, globalCancel, err := chromeUtil.GetContext(30 * time.Second)
-		if err != nil {
-			log.Printf("获取全局Chrome上下文失败: %v，回退到chromedp.NewContext", err)
-			// 回退到chromedp.NewContext
-			ctx, cancel = chromedp.NewContext(context.Background())
-			// 为回退方案设置超时
-			ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-			defer timeoutCancel()
-		} else {
-			ctx = globalCtx
-			cancel = globalCancel
-		}
-	} else {
-		// 回退到chromedp.NewContext
-		ctx, cancel = chromedp.NewContext(context.Background())
-		// 为回退方案设置超时
-		ctx, timeoutCancel = context.WithTimeout(ctx, 30*time.Second)
-		defer timeoutCancel()
-	}
-
-	if cancel != nil {
-		defer cancel()
+	// 使用全局Chrome管理器（已包含超时）
+	ctx, cancel, err := services.GetGlobalChromeManager().GetContext(30 * time.Second)
+	if err != nil {
+		log.Printf("获取Chrome上下文失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, ScreenshotResponse{
+			Success: false,
+			Error:   "截图服务暂不可用",
+			Message: fmt.Sprintf("获取Chrome上下文失败: %v", err),
+		})
+		return
 	}
+	defer cancel()
 
 	// 截图数据
 	var buf []byte
 
 	// 执行截图
-	var err error
 	err = chromedp.Run(ctx,
 		chromedp.Navigate(req.URL),
 		chromedp.Sleep(waitTime), // 等待页面加载完成
@@ -1472,24 +1402,24 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 
 	// 执行截图
 	err := sb.ItdogBreaker.Execute(func() error {
-		// 获取全局Chrome工具
-		chromeUtil := utils.GetGlobalChromeUtil()
-		if chromeUtil == nil {
-			return fmt.Errorf("Chrome工具未初始化")
+		// 获取全局Chrome管理器
+		chromeManager := services.GetGlobalChromeManager()
+		if chromeManager == nil {
+			return fmt.Errorf("Chrome管理器未初始化")
 		}
 
 		// 增加重试机制
 		maxRetries := 2
 		for retry := 0; retry <= maxRetries; retry++ {
 			if retry > 0 {
-				log.Printf("[CHROME-UTIL] %s重试第 %d 次", config.Description, retry)
+				log.Printf("[CHROME-MANAGER] %s重试第 %d 次", config.Description, retry)
 				time.Sleep(time.Duration(retry) * 2 * time.Second) // 递增等待时间
 			}
 
-			// 从Chrome工具获取上下文，设置120秒超时（增加超时时间）
-			ctx, cancel, chromeErr := chromeUtil.GetContext(120 * time.Second)
+			// 从Chrome管理器获取上下文，设置120秒超时（增加超时时间）
+			ctx, cancel, chromeErr := chromeManager.GetContext(120 * time.Second)
 			if chromeErr != nil {
-				log.Printf("[CHROME-UTIL] 获取Chrome上下文失败 (重试 %d/%d): %v", retry, maxRetries, chromeErr)
+				log.Printf("[CHROME-MANAGER] 获取Chrome上下文失败 (重试 %d/%d): %v", retry, maxRetries, chromeErr)
 				if retry == maxRetries {
 					return fmt.Errorf("获取Chrome上下文失败: %v", chromeErr)
 				}
@@ -1500,7 +1430,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 			select {
 			case <-ctx.Done():
 				cancel()
-				log.Printf("[CHROME-UTIL] 上下文在使用前已被取消 (重试 %d/%d)", retry, maxRetries)
+				log.Printf("[CHROME-MANAGER] 上下文在使用前已被取消 (重试 %d/%d)", retry, maxRetries)
 				if retry == maxRetries {
 					return fmt.Errorf("上下文在使用前已被取消")
 				}
@@ -1508,7 +1438,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 			default:
 			}
 
-			log.Printf("[CHROME-UTIL] 开始执行%s操作，域名: %s (重试 %d/%d)", config.Description, config.Domain, retry, maxRetries)
+			log.Printf("[CHROME-MANAGER] 开始执行%s操作，域名: %s (重试 %d/%d)", config.Description, config.Domain, retry, maxRetries)
 
 			// 截图数据
 			var buf []byte
@@ -1523,7 +1453,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤1: 导航到ITDog页面: %s", fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain))
+					log.Printf("[CHROME-MANAGER] 步骤1: 导航到ITDog页面: %s", fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain))
 					return chromedp.Navigate(fmt.Sprintf("https://www.itdog.cn/ping/%s", config.Domain)).Do(ctx)
 				}),
 
@@ -1534,7 +1464,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤2: 等待页面加载完成")
+					log.Printf("[CHROME-MANAGER] 步骤2: 等待页面加载完成")
 					return chromedp.Sleep(2 * time.Second).Do(ctx)
 				}),
 
@@ -1545,7 +1475,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤3: 等待单次测试按钮出现")
+					log.Printf("[CHROME-MANAGER] 步骤3: 等待单次测试按钮出现")
 					return chromedp.WaitVisible(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery).Do(ctx)
 				}),
 
@@ -1556,7 +1486,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤4: 点击单次测试按钮")
+					log.Printf("[CHROME-MANAGER] 步骤4: 点击单次测试按钮")
 					return chromedp.Click(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery).Do(ctx)
 				}),
 
@@ -1567,7 +1497,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤5: 等待测试开始")
+					log.Printf("[CHROME-MANAGER] 步骤5: 等待测试开始")
 					return chromedp.Sleep(3 * time.Second).Do(ctx)
 				}),
 
@@ -1578,13 +1508,13 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						var attempts int
 						maxAttempts := 60 // 增加最大尝试次数
 
-						log.Printf("[CHROME-UTIL] 步骤6: 开始检查%s测试进度", config.Description)
+						log.Printf("[CHROME-MANAGER] 步骤6: 开始检查%s测试进度", config.Description)
 
 						for attempts < maxAttempts {
 							// 检查上下文是否已取消
 							select {
 							case <-ctx.Done():
-								log.Printf("[CHROME-UTIL] %s上下文已取消，停止等待", config.Description)
+								log.Printf("[CHROME-MANAGER] %s上下文已取消，停止等待", config.Description)
 								return ctx.Err()
 							default:
 							}
@@ -1604,18 +1534,18 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						})()`, &isDone).Do(ctx)
 
 							if err != nil {
-								log.Printf("[CHROME-UTIL] %s进度检查出错: %v", config.Description, err)
+								log.Printf("[CHROME-MANAGER] %s进度检查出错: %v", config.Description, err)
 								return err
 							}
 
 							if isDone {
-								log.Printf("[CHROME-UTIL] %s测试完成，进度: %d/%d", config.Description, attempts, maxAttempts)
+								log.Printf("[CHROME-MANAGER] %s测试完成，进度: %d/%d", config.Description, attempts, maxAttempts)
 								return nil // 测试完成，退出循环
 							}
 
 							// 每5次尝试打印一次进度
 							if attempts%5 == 0 && attempts > 0 {
-								log.Printf("[CHROME-UTIL] %s等待测试完成，已等待 %d 秒", config.Description, attempts)
+								log.Printf("[CHROME-MANAGER] %s等待测试完成，已等待 %d 秒", config.Description, attempts)
 							}
 
 							// 等待1秒后再次检查
@@ -1627,7 +1557,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 							}
 						}
 
-						log.Printf("[CHROME-UTIL] %s等待超时，已尝试 %d 次", config.Description, attempts)
+						log.Printf("[CHROME-MANAGER] %s等待超时，已尝试 %d 次", config.Description, attempts)
 						return nil // 达到最大尝试次数，继续执行
 					})
 				}(),
@@ -1639,7 +1569,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 						return ctx.Err()
 					default:
 					}
-					log.Printf("[CHROME-UTIL] 步骤7: 等待页面元素更新")
+					log.Printf("[CHROME-MANAGER] 步骤7: 等待页面元素更新")
 					return chromedp.Sleep(5 * time.Second).Do(ctx)
 				}),
 
@@ -1651,14 +1581,14 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 							return ctx.Err()
 						default:
 						}
-						log.Printf("[CHROME-UTIL] 步骤8: 开始截图")
+						log.Printf("[CHROME-MANAGER] 步骤8: 开始截图")
 						if strings.HasPrefix(config.Selector, "//") {
 							// 使用XPath选择器
-							log.Printf("[CHROME-UTIL] 使用XPath选择器截图: %s", config.Selector)
+							log.Printf("[CHROME-MANAGER] 使用XPath选择器截图: %s", config.Selector)
 							return chromedp.Screenshot(config.Selector, &buf, chromedp.NodeVisible, chromedp.BySearch).Do(ctx)
 						} else {
 							// 使用CSS选择器
-							log.Printf("[CHROME-UTIL] 使用CSS选择器截图: %s", config.Selector)
+							log.Printf("[CHROME-MANAGER] 使用CSS选择器截图: %s", config.Selector)
 							return chromedp.Screenshot(config.Selector, &buf, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
 						}
 					})
@@ -1669,7 +1599,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 			cancel()
 
 			if err != nil {
-				log.Printf("[CHROME-UTIL] %s失败 (重试 %d/%d): %v", config.Description, retry, maxRetries, err)
+				log.Printf("[CHROME-MANAGER] %s失败 (重试 %d/%d): %v", config.Description, retry, maxRetries, err)
 				if strings.Contains(err.Error(), "context canceled") && retry < maxRetries {
 					continue // 重试
 				}
@@ -1679,7 +1609,7 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 				continue
 			}
 
-			log.Printf("[CHROME-UTIL] %s截图成功，大小: %d bytes", config.Description, len(buf))
+			log.Printf("[CHROME-MANAGER] %s截图成功，大小: %d bytes", config.Description, len(buf))
 			if err := writeFile(config.FilePath, buf); err != nil {
 				log.Printf("保存%s失败: %v", config.Description, err)
 				return err
@@ -1730,40 +1660,40 @@ func performITDogScreenshotBase64(config ITDogScreenshotConfig, rdb *redis.Clien
 	return response, nil
 }
 
-// ChromeStatus 检查Chrome工具状态的API
+// ChromeStatus 检查Chrome管理器状态的API
 func ChromeStatus(c *gin.Context) {
-	chromeUtil := utils.GetGlobalChromeUtil()
-	if chromeUtil == nil {
+	chromeManager := services.GetGlobalChromeManager()
+	if chromeManager == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Chrome工具未初始化",
+			"error":   "Chrome管理器未初始化",
 		})
 		return
 	}
 
 	// 获取详细状态信息
-	detailedStats := chromeUtil.GetDetailedStats()
+	detailedStats := chromeManager.GetDetailedStats()
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":       true,
 		"chrome_status": detailedStats,
-		"message":       "Chrome工具详细状态检查完成",
+		"message":       "Chrome管理器详细状态检查完成",
 	})
 }
 
 // ChromeDiagnose 执行Chrome诊断的API
 func ChromeDiagnose(c *gin.Context) {
-	chromeUtil := utils.GetGlobalChromeUtil()
-	if chromeUtil == nil {
+	chromeManager := services.GetGlobalChromeManager()
+	if chromeManager == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Chrome工具未初始化",
+			"error":   "Chrome管理器未初始化",
 		})
 		return
 	}
 
 	// 执行诊断
-	diagnosis := chromeUtil.Diagnose()
+	diagnosis := chromeManager.Diagnose()
 
 	// 根据诊断结果返回适当的HTTP状态码
 	severity, _ := diagnosis["severity"].(string)
@@ -1788,17 +1718,17 @@ func ChromeDiagnose(c *gin.Context) {
 
 // ChromeForceReset 强制重置Chrome的API
 func ChromeForceReset(c *gin.Context) {
-	chromeUtil := utils.GetGlobalChromeUtil()
-	if chromeUtil == nil {
+	chromeManager := services.GetGlobalChromeManager()
+	if chromeManager == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Chrome工具未初始化",
+			"error":   "Chrome管理器未初始化",
 		})
 		return
 	}
 
 	// 执行强制重置
-	err := chromeUtil.ForceReset()
+	err := chromeManager.ForceReset()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"whosee/services"
 	"whosee/utils"
 
 	"github.com/chromedp/chromedp"
@@ -91,11 +92,17 @@ func ScreenshotBase64Handler(c *gin.Context) {
 	url := fmt.Sprintf("https://%s", domainStr)
 
 	// 创建上下文
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
-
-	// 设置超时
-	ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+	// 使用全局Chrome管理器（已包含超时）
+	ctx, cancel, err := services.GetGlobalChromeManager().GetContext(30 * time.Second)
+	if err != nil {
+		log.Printf("[SCREENSHOT-BASE64] 域名: %s | 获取Chrome上下文失败: %v", domainStr, err)
+		c.JSON(http.StatusServiceUnavailable, ScreenshotResponse{
+			Success: false,
+			Error:   "截图服务暂不可用",
+			Message: fmt.Sprintf("获取Chrome上下文失败: %v", err),
+		})
+		return
+	}
 	defer cancel()
 
 	// 截图数据
@@ -234,11 +241,17 @@ func ITDogBase64Handler(c *gin.Context) {
 	}
 
 	// 完整URL
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
-
-	// 设置超时
-	ctx, cancel = context.WithTimeout(ctx, 90*time.Second)
+	// 使用全局Chrome管理器（已包含超时）
+	ctx, cancel, err := services.GetGlobalChromeManager().GetContext(90 * time.Second)
+	if err != nil {
+		log.Printf("[ITDOG-BASE64] 域名: %s | 获取Chrome上下文失败: %v", domainStr, err)
+		c.JSON(http.StatusServiceUnavailable, ScreenshotResponse{
+			Success: false,
+			Error:   "截图服务暂不可用",
+			Message: fmt.Sprintf("获取Chrome上下文失败: %v", err),
+		})
+		return
+	}
 	defer cancel()
 
 	// 截图数据
@@ -0,0 +1,122 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图历史与视觉对比处理程序
+ */
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"whosee/services"
+	"whosee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScreenshotHistoryHandler 截图历史处理程序
+type ScreenshotHistoryHandler struct {
+	history *services.ScreenshotHistory
+}
+
+// NewScreenshotHistoryHandler 创建截图历史处理程序
+func NewScreenshotHistoryHandler(history *services.ScreenshotHistory) *ScreenshotHistoryHandler {
+	return &ScreenshotHistoryHandler{history: history}
+}
+
+// GetHistory 按时间倒序返回域名的截图历史，支持CSV/NDJSON导出
+func (h *ScreenshotHistoryHandler) GetHistory(c *gin.Context) {
+	domain, _ := c.Get("domain")
+	domainStr := domain.(string)
+	startTime := time.Now()
+
+	limit := services.DefaultHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	records, err := h.history.List(c.Request.Context(), domainStr, historyType(c), limit)
+	if err != nil {
+		log.Printf("[SCREENSHOT] 查询域名 %s 的截图历史失败: %v", domainStr, err)
+		utils.ErrorResponse(c, 500, "HISTORY_ERROR", err.Error())
+		return
+	}
+
+	switch utils.NegotiateExportFormat(c) {
+	case utils.ExportCSV:
+		stream, err := utils.NewCSVStream(c, fmt.Sprintf("screenshot-history-%s.csv", domainStr), services.ScreenshotHistoryCSVHeader)
+		if err != nil {
+			log.Printf("[SCREENSHOT] 写出CSV表头失败: %v", err)
+			return
+		}
+		for _, record := range records {
+			if err := stream.WriteRow(record.CSVRecord()); err != nil {
+				log.Printf("[SCREENSHOT] 写出截图历史失败: %v", err)
+				return
+			}
+		}
+	case utils.ExportNDJSON:
+		stream := utils.NewNDJSONStream(c)
+		for _, record := range records {
+			if err := stream.WriteRecord(record); err != nil {
+				log.Printf("[SCREENSHOT] 写出截图历史失败: %v", err)
+				return
+			}
+		}
+	default:
+		utils.SuccessResponse(c, gin.H{
+			"domain":  domainStr,
+			"type":    historyType(c),
+			"records": records,
+		}, &utils.MetaInfo{
+			Timestamp:  time.Now().Format(time.RFC3339),
+			Processing: time.Since(startTime).Milliseconds(),
+		})
+	}
+}
+
+// GetDiff 对比两次截图，from/to为历史记录ID，默认对比最近两次
+func (h *ScreenshotHistoryHandler) GetDiff(c *gin.Context) {
+	domain, _ := c.Get("domain")
+	domainStr := domain.(string)
+	startTime := time.Now()
+
+	diff, err := h.history.Diff(c.Request.Context(), domainStr, historyType(c), c.Query("from"), c.Query("to"))
+	if err != nil {
+		log.Printf("[SCREENSHOT] 对比域名 %s 的截图失败: %v", domainStr, err)
+		if errors.Is(err, services.ErrHistoryNotFound) {
+			utils.ErrorResponse(c, 404, "HISTORY_NOT_FOUND", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrDiffNotComparable) {
+			utils.ErrorResponse(c, 400, "DIFF_NOT_COMPARABLE", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, 500, "DIFF_ERROR", err.Error())
+		return
+	}
+
+	meta := &utils.MetaInfo{
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Cached:     diff.FromCache,
+		Processing: time.Since(startTime).Milliseconds(),
+	}
+	if diff.FromCache {
+		meta.CachedAt = diff.GeneratedAt
+	}
+	utils.SuccessResponse(c, diff, meta)
+}
+
+// historyType 截图类型参数，默认基础截图
+func historyType(c *gin.Context) services.ScreenshotType {
+	if t := c.Query("type"); t != "" {
+		return services.ScreenshotType(t)
+	}
+	return services.TypeBasic
+}
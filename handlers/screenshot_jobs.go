@@ -0,0 +1,106 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 定时截图任务管理处理程序
+ */
+package handlers
+
+import (
+	"time"
+
+	"whosee/services"
+	"whosee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScreenshotJobsHandler 定时截图任务处理程序
+type ScreenshotJobsHandler struct {
+	scheduler *services.ScreenshotScheduler
+}
+
+// NewScreenshotJobsHandler 创建定时截图任务处理程序，调度器未启用时接口返回503
+func NewScreenshotJobsHandler(scheduler *services.ScreenshotScheduler) *ScreenshotJobsHandler {
+	return &ScreenshotJobsHandler{scheduler: scheduler}
+}
+
+// ListJobs 列出所有任务及其最近执行情况
+func (h *ScreenshotJobsHandler) ListJobs(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	jobs, err := h.scheduler.ListJobs()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "JOB_LIST_FAILED", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, gin.H{
+		"total": len(jobs),
+		"jobs":  jobs,
+	}, &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+// GetJob 获取单个任务
+func (h *ScreenshotJobsHandler) GetJob(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	job, err := h.scheduler.GetJob(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "JOB_GET_FAILED", err.Error())
+		return
+	}
+	if job == nil {
+		utils.ErrorResponse(c, 404, "JOB_NOT_FOUND", "Screenshot job not found")
+		return
+	}
+	utils.SuccessResponse(c, job, &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+// CreateJob 创建任务
+func (h *ScreenshotJobsHandler) CreateJob(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	var job services.ScreenshotJob
+	if err := c.ShouldBindJSON(&job); err != nil {
+		utils.ErrorResponse(c, 400, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	created, err := h.scheduler.AddJob(&job)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "INVALID_JOB", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, created, &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+// DeleteJob 删除任务
+func (h *ScreenshotJobsHandler) DeleteJob(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	deleted, err := h.scheduler.DeleteJob(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "JOB_DELETE_FAILED", err.Error())
+		return
+	}
+	if !deleted {
+		utils.ErrorResponse(c, 404, "JOB_NOT_FOUND", "Screenshot job not found")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"id": c.Param("id"), "deleted": true}, &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+func (h *ScreenshotJobsHandler) available(c *gin.Context) bool {
+	if h.scheduler == nil {
+		utils.ErrorResponse(c, 503, "SERVICE_UNAVAILABLE", "Screenshot scheduler is disabled")
+		return false
+	}
+	return true
+}
@@ -7,6 +7,7 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -74,6 +75,11 @@ func (h *NewScreenshotHandler) TakeScreenshot(c *gin.Context) {
 		h.chromeManager.OnSuccess(duration)
 		log.Printf("[SCREENSHOT] %s截图成功, 耗时: %v, 缓存: %v",
 			req.Type, duration, response.FromCache)
+	} else if response.Error == "CONSOLE_ERRORS" {
+		// 浏览器工作正常，只是页面检查未通过，不计入熔断
+		h.chromeManager.OnSuccess(duration)
+		log.Printf("[SCREENSHOT] %s页面控制台错误超限: %s, 耗时: %v",
+			req.Type, response.Message, duration)
 	} else {
 		h.chromeManager.OnFailure(duration)
 		log.Printf("[SCREENSHOT] %s截图失败: %s, 耗时: %v",
@@ -133,6 +139,94 @@ func (h *NewScreenshotHandler) parseRequest(c *gin.Context) (*services.Screensho
 		}
 	}
 
+	// 解析视口与设备模拟参数
+	if widthStr := c.Query("width"); widthStr != "" {
+		if width, err := strconv.Atoi(widthStr); err == nil && width > 0 {
+			req.Width = width
+		}
+	}
+	if heightStr := c.Query("height"); heightStr != "" {
+		if height, err := strconv.Atoi(heightStr); err == nil && height > 0 {
+			req.Height = height
+		}
+	}
+	if scaleStr := c.Query("device_scale_factor"); scaleStr != "" {
+		if scale, err := strconv.ParseFloat(scaleStr, 64); err == nil && scale > 0 {
+			req.DeviceScaleFactor = scale
+		}
+	}
+	req.FullPage = c.Query("full_page") == "true"
+	req.Mobile = c.Query("mobile") == "true"
+	req.Device = c.Query("device")
+	req.UserAgent = c.Query("user_agent")
+
+	// 解析页面等待策略参数
+	req.WaitUntil = c.Query("wait_until")
+	req.WaitSelector = c.Query("wait_selector")
+	req.WaitFunction = c.Query("wait_function")
+	if idleStr := c.Query("network_idle_ms"); idleStr != "" {
+		if idle, err := strconv.Atoi(idleStr); err == nil && idle > 0 {
+			req.NetworkIdleMs = idle
+		}
+	}
+	if maxWaitStr := c.Query("max_wait"); maxWaitStr != "" {
+		if maxWait, err := strconv.Atoi(maxWaitStr); err == nil && maxWait > 0 {
+			req.MaxWait = maxWait
+		}
+	}
+
+	// 解析图片格式与缩略图参数
+	req.ImageFormat = c.Query("image_format")
+	if qualityStr := c.Query("quality"); qualityStr != "" {
+		if quality, err := strconv.Atoi(qualityStr); err == nil {
+			req.Quality = quality
+		}
+	}
+	if thumbWidthStr := c.Query("thumb_width"); thumbWidthStr != "" {
+		if thumbWidth, err := strconv.Atoi(thumbWidthStr); err == nil {
+			req.ThumbWidth = thumbWidth
+		}
+	}
+	if thumbHeightStr := c.Query("thumb_height"); thumbHeightStr != "" {
+		if thumbHeight, err := strconv.Atoi(thumbHeightStr); err == nil {
+			req.ThumbHeight = thumbHeight
+		}
+	}
+
+	// 解析页面内容提取参数
+	req.Extract = services.ExtractMode(c.Query("extract"))
+	req.HAR = c.Query("har") == "true"
+	if failOnStr := c.Query("fail_on_console_errors"); failOnStr != "" {
+		if failOn, err := strconv.Atoi(failOnStr); err == nil {
+			req.FailOnConsoleErrors = failOn
+		}
+	}
+
+	// 解析页面净化参数，hide_selectors可重复传入
+	req.HideSelectors = c.QueryArray("hide_selectors")
+	req.BlockAds = c.Query("block_ads") == "true"
+	req.ShowConsent = c.Query("show_consent") == "true"
+	req.PersistSession = c.Query("persist_session")
+
+	// 解析出口代理与地区模拟参数，geolocation格式为 纬度,经度[,精度]
+	req.Proxy = c.Query("proxy")
+	req.Locale = c.Query("locale")
+	req.Timezone = c.Query("timezone")
+	if geoStr := c.Query("geolocation"); geoStr != "" {
+		geo, err := services.ParseGeolocation(geoStr)
+		if err != nil {
+			return nil, err
+		}
+		req.Geolocation = geo
+	}
+
+	// 交互步骤通过查询参数传入时为JSON数组
+	if actionsStr := c.Query("actions"); actionsStr != "" {
+		if err := json.Unmarshal([]byte(actionsStr), &req.Actions); err != nil {
+			return nil, fmt.Errorf("actions必须是JSON数组: %v", err)
+		}
+	}
+
 	// 对于POST请求，尝试从请求体解析JSON
 	if c.Request.Method == "POST" {
 		var bodyReq services.ScreenshotRequest
@@ -162,6 +256,90 @@ func (h *NewScreenshotHandler) parseRequest(c *gin.Context) (*services.Screensho
 			if bodyReq.CacheExpire > 0 {
 				req.CacheExpire = bodyReq.CacheExpire
 			}
+			if bodyReq.Width > 0 {
+				req.Width = bodyReq.Width
+			}
+			if bodyReq.Height > 0 {
+				req.Height = bodyReq.Height
+			}
+			if bodyReq.DeviceScaleFactor > 0 {
+				req.DeviceScaleFactor = bodyReq.DeviceScaleFactor
+			}
+			if bodyReq.FullPage {
+				req.FullPage = true
+			}
+			if bodyReq.Mobile {
+				req.Mobile = true
+			}
+			if bodyReq.Device != "" {
+				req.Device = bodyReq.Device
+			}
+			if bodyReq.UserAgent != "" {
+				req.UserAgent = bodyReq.UserAgent
+			}
+			if bodyReq.WaitUntil != "" {
+				req.WaitUntil = bodyReq.WaitUntil
+			}
+			if bodyReq.WaitSelector != "" {
+				req.WaitSelector = bodyReq.WaitSelector
+			}
+			if bodyReq.WaitFunction != "" {
+				req.WaitFunction = bodyReq.WaitFunction
+			}
+			if bodyReq.NetworkIdleMs > 0 {
+				req.NetworkIdleMs = bodyReq.NetworkIdleMs
+			}
+			if bodyReq.MaxWait > 0 {
+				req.MaxWait = bodyReq.MaxWait
+			}
+			if bodyReq.ImageFormat != "" {
+				req.ImageFormat = bodyReq.ImageFormat
+			}
+			if bodyReq.Quality != 0 {
+				req.Quality = bodyReq.Quality
+			}
+			if bodyReq.ThumbWidth != 0 {
+				req.ThumbWidth = bodyReq.ThumbWidth
+			}
+			if bodyReq.ThumbHeight != 0 {
+				req.ThumbHeight = bodyReq.ThumbHeight
+			}
+			if bodyReq.Extract != "" {
+				req.Extract = bodyReq.Extract
+			}
+			if bodyReq.HAR {
+				req.HAR = true
+			}
+			if bodyReq.FailOnConsoleErrors != 0 {
+				req.FailOnConsoleErrors = bodyReq.FailOnConsoleErrors
+			}
+			if len(bodyReq.Actions) > 0 {
+				req.Actions = bodyReq.Actions
+			}
+			if len(bodyReq.HideSelectors) > 0 {
+				req.HideSelectors = bodyReq.HideSelectors
+			}
+			if bodyReq.BlockAds {
+				req.BlockAds = true
+			}
+			if bodyReq.ShowConsent {
+				req.ShowConsent = true
+			}
+			if bodyReq.PersistSession != "" {
+				req.PersistSession = bodyReq.PersistSession
+			}
+			if bodyReq.Proxy != "" {
+				req.Proxy = bodyReq.Proxy
+			}
+			if bodyReq.Locale != "" {
+				req.Locale = bodyReq.Locale
+			}
+			if bodyReq.Timezone != "" {
+				req.Timezone = bodyReq.Timezone
+			}
+			if bodyReq.Geolocation != nil {
+				req.Geolocation = bodyReq.Geolocation
+			}
 		}
 	}
 
@@ -512,18 +690,18 @@ func NewChromeRestart(c *gin.Context) {
 
 // 新实现处理器映射
 var NewScreenshotHandlerMapping = map[string]func(*gin.Context, *redis.Client){
-	"NewScreenshot":                    NewScreenshot,
-	"NewScreenshotBase64":              NewScreenshotBase64,
-	"NewElementScreenshot":             NewElementScreenshot,
-	"NewElementScreenshotBase64":       NewElementScreenshotBase64,
-	"NewItdogScreenshot":               NewItdogScreenshot,
-	"NewItdogScreenshotBase64":         NewItdogScreenshotBase64,
-	"NewItdogTableScreenshot":          NewItdogTableScreenshot,
-	"NewItdogTableScreenshotBase64":    NewItdogTableScreenshotBase64,
-	"NewItdogIPScreenshot":             NewItdogIPScreenshot,
-	"NewItdogIPBase64Screenshot":       NewItdogIPBase64Screenshot,
-	"NewItdogResolveScreenshot":        NewItdogResolveScreenshot,
-	"NewItdogResolveScreenshotBase64":  NewItdogResolveScreenshotBase64,
+	"NewScreenshot":                   NewScreenshot,
+	"NewScreenshotBase64":             NewScreenshotBase64,
+	"NewElementScreenshot":            NewElementScreenshot,
+	"NewElementScreenshotBase64":      NewElementScreenshotBase64,
+	"NewItdogScreenshot":              NewItdogScreenshot,
+	"NewItdogScreenshotBase64":        NewItdogScreenshotBase64,
+	"NewItdogTableScreenshot":         NewItdogTableScreenshot,
+	"NewItdogTableScreenshotBase64":   NewItdogTableScreenshotBase64,
+	"NewItdogIPScreenshot":            NewItdogIPScreenshot,
+	"NewItdogIPBase64Screenshot":      NewItdogIPBase64Screenshot,
+	"NewItdogResolveScreenshot":       NewItdogResolveScreenshot,
+	"NewItdogResolveScreenshotBase64": NewItdogResolveScreenshotBase64,
 }
 
 // 新版便捷函数用于在routes中调用
@@ -585,4 +763,4 @@ func NewITDogResolveHandler(c *gin.Context) {
 func NewITDogResolveBase64Handler(c *gin.Context) {
 	rdb := c.MustGet("redis").(*redis.Client)
 	NewItdogResolveScreenshotBase64(c, rdb)
-}
\ No newline at end of file
+}
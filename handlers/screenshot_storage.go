@@ -0,0 +1,60 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图文件清理统计与试运行处理程序
+ */
+package handlers
+
+import (
+	"time"
+
+	"whosee/services"
+	"whosee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScreenshotStorageHandler 截图文件清理处理程序
+type ScreenshotStorageHandler struct {
+	janitor *services.ScreenshotJanitor
+}
+
+// NewScreenshotStorageHandler 创建截图文件清理处理程序，清理服务未启用时接口返回503
+func NewScreenshotStorageHandler(janitor *services.ScreenshotJanitor) *ScreenshotStorageHandler {
+	return &ScreenshotStorageHandler{janitor: janitor}
+}
+
+// GetStats 返回清理统计，包括累计回收的字节数和最近一次清理结果
+func (h *ScreenshotStorageHandler) GetStats(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	utils.SuccessResponse(c, h.janitor.Stats(), &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+// Cleanup 立即试运行一次清理，只返回将被删除的文件
+// API客户端不能触发实际删除，文件只由后台清理任务按配置删除
+func (h *ScreenshotStorageHandler) Cleanup(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	if c.DefaultQuery("dry_run", "true") != "true" {
+		utils.ErrorResponse(c, 403, "CLEANUP_FORBIDDEN", "Only dry-run cleanup is allowed; files are removed by the background janitor")
+		return
+	}
+
+	report, err := h.janitor.Sweep(c.Request.Context(), true)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "CLEANUP_FAILED", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, report, &utils.MetaInfo{Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+func (h *ScreenshotStorageHandler) available(c *gin.Context) bool {
+	if h.janitor == nil {
+		utils.ErrorResponse(c, 503, "SERVICE_UNAVAILABLE", "Screenshot storage janitor is disabled")
+		return false
+	}
+	return true
+}
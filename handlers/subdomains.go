@@ -0,0 +1,173 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 子域名发现处理程序
+ */
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"whosee/services"
+	"whosee/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// subdomainResolveLimit 单次请求最多解析的子域名数量
+	subdomainResolveLimit = 200
+	// subdomainResolveWorkers 并发解析数
+	subdomainResolveWorkers = 10
+	// subdomainResolvedCacheTTL 解析结果变化较快，缓存时间短于CT结果
+	subdomainResolvedCacheTTL = time.Hour
+)
+
+// SubdomainsHandler 基于证书透明日志的子域名发现处理程序
+func SubdomainsHandler(c *gin.Context) {
+	domain, _ := c.Get("domain")
+	domainStr := domain.(string)
+	resultChan, _ := c.Get("resultChan")
+	errorChan, _ := c.Get("errorChan")
+	reqCtx, _ := c.Get("requestContext")
+	workerPool, _ := c.Get("workerPool")
+	discoveryService, _ := c.Get("subdomainDiscovery")
+
+	results := resultChan.(chan interface{})
+	errors := errorChan.(chan error)
+	requestContext := reqCtx.(context.Context)
+	pool := workerPool.(*services.WorkerPool)
+	discovery, ok := discoveryService.(*services.SubdomainDiscovery)
+	if !ok || discovery == nil {
+		utils.ErrorResponse(c, 503, "SERVICE_UNAVAILABLE", "Subdomain discovery service is not available")
+		return
+	}
+
+	var rdb *redis.Client
+	if client, exists := c.Get("redis"); exists {
+		rdb, _ = client.(*redis.Client)
+	}
+	resolve := c.Query("resolve") == "true"
+
+	submitted := pool.SubmitWithContext(requestContext, func() {
+		startTime := time.Now()
+		log.Printf("[SUBDOMAINS] 查询域名: %s, 解析: %v", domainStr, resolve)
+
+		var result *services.SubdomainDiscoveryResult
+		resolvedKey := utils.BuildCacheKey("cache", "subdomains", utils.SanitizeDomain(domainStr), "resolved")
+		if resolve {
+			result, _ = getResolvedSubdomainCache(requestContext, rdb, resolvedKey)
+		}
+
+		if result == nil {
+			discovered, err := discovery.Discover(requestContext, domainStr)
+			if err != nil {
+				log.Printf("[SUBDOMAINS] 查询域名 %s 失败: %v", domainStr, err)
+				errors <- err
+				return
+			}
+			result = discovered
+
+			if resolve {
+				resolveSubdomains(requestContext, result)
+				setResolvedSubdomainCache(requestContext, rdb, resolvedKey, result)
+			}
+		}
+
+		processingTime := time.Since(startTime).Milliseconds()
+		meta := &utils.MetaInfo{
+			Timestamp:  time.Now().Format(time.RFC3339),
+			Cached:     result.IsCached,
+			Processing: processingTime,
+		}
+		if result.IsCached {
+			meta.CachedAt = result.QueryTime
+		}
+
+		log.Printf("[SUBDOMAINS] 查询域名 %s 完成，子域名 %d 个，处理时间: %dms", domainStr, result.Total, processingTime)
+		results <- gin.H{
+			"data": result,
+			"meta": meta,
+		}
+	})
+
+	if !submitted {
+		log.Printf("[SUBDOMAINS] 查询域名 %s 失败: 工作池忙碌", domainStr)
+		utils.ErrorResponse(c, 503, "SERVICE_BUSY", "Service is busy, please try again later")
+		return
+	}
+
+	select {
+	case result := <-results:
+		data := result.(gin.H)
+		utils.SuccessResponse(c, data["data"], data["meta"].(*utils.MetaInfo))
+	case err := <-errors:
+		utils.ErrorResponse(c, 502, "UPSTREAM_ERROR", err.Error())
+	case <-requestContext.Done():
+		log.Printf("[SUBDOMAINS] 查询域名 %s 超时", domainStr)
+		utils.ErrorResponse(c, 504, "TIMEOUT", "Request timed out")
+	}
+}
+
+// resolveSubdomains 复用DNS查询解析子域名的A/AAAA记录，数量过多时只解析前 subdomainResolveLimit 个
+func resolveSubdomains(ctx context.Context, result *services.SubdomainDiscoveryResult) {
+	count := len(result.Subdomains)
+	if count > subdomainResolveLimit {
+		log.Printf("[SUBDOMAINS] %s 子域名数量 %d 超过解析上限，仅解析前 %d 个", result.Domain, count, subdomainResolveLimit)
+		count = subdomainResolveLimit
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < subdomainResolveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := &result.Subdomains[i]
+				for _, record := range queryAAndAAAA(entry.Name) {
+					entry.Addresses = append(entry.Addresses, record.Value)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func getResolvedSubdomainCache(ctx context.Context, rdb *redis.Client, key string) (*services.SubdomainDiscoveryResult, bool) {
+	if rdb == nil {
+		return nil, false
+	}
+	data, err := rdb.Get(ctx, key).Result()
+	if err != nil {
+		return nil, false
+	}
+	var result services.SubdomainDiscoveryResult
+	if json.Unmarshal([]byte(data), &result) != nil || result.Domain == "" {
+		return nil, false
+	}
+	result.IsCached = true
+	return &result, true
+}
+
+func setResolvedSubdomainCache(ctx context.Context, rdb *redis.Client, key string, result *services.SubdomainDiscoveryResult) {
+	if rdb == nil || result == nil {
+		return
+	}
+	if data, err := json.Marshal(result); err == nil {
+		_ = rdb.Set(ctx, key, data, subdomainResolvedCacheTTL).Err()
+	}
+}
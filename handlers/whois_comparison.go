@@ -12,7 +12,7 @@ import (
 	"whosee/utils"
 	"fmt"
 	"log"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -49,7 +49,11 @@ type ComparisonSummary struct {
 	MethodDescription string `json:"methodDescription,omitempty"`
 }
 
+// whoisComparisonCSVHeader 比较结果CSV表头：提供商查询状态列 + 展平的WHOIS字段
+var whoisComparisonCSVHeader = append([]string{"provider", "success", "error", "responseTimeMs", "cached"}, types.WhoisCSVHeader...)
+
 // WhoisComparisonHandler 处理WHOIS提供商比较请求
+// 支持 Accept: text/csv 和 application/x-ndjson，此时每个提供商完成后立即输出一条记录
 func WhoisComparisonHandler(c *gin.Context) {
 	startTime := time.Now()
 
@@ -61,7 +65,8 @@ func WhoisComparisonHandler(c *gin.Context) {
 		return
 	}
 
-	domainStr := domain.(string)
+	// 按公共后缀列表归约为可注册域名，子域名交给各提供商查询没有意义
+	domainStr := utils.RegistrableDomain(domain.(string))
 	log.Printf("开始WHOIS提供商比较查询: %s", domainStr)
 
 	// 创建所有提供商实例
@@ -74,23 +79,53 @@ func WhoisComparisonHandler(c *gin.Context) {
 
 	// 并发查询所有提供商
 	results := make(map[string]*WhoisProviderResult)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	resultChan := make(chan *WhoisProviderResult, len(providerInstances))
 
 	// 使用带超时的上下文
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	for providerName, provider := range providerInstances {
-		wg.Add(1)
 		go func(name string, p types.WhoisProvider) {
-			defer wg.Done()
-			queryProvider(ctx, name, p, domainStr, &mu, results)
+			resultChan <- queryProvider(ctx, name, p, domainStr)
 		}(providerName, provider)
 	}
 
+	// 流式格式在每个提供商完成时立即输出
+	format := utils.NegotiateExportFormat(c)
+	var csvStream *utils.CSVStream
+	var ndjsonStream *utils.NDJSONStream
+	switch format {
+	case utils.ExportCSV:
+		stream, err := utils.NewCSVStream(c, fmt.Sprintf("whois-compare-%s.csv", domainStr), whoisComparisonCSVHeader)
+		if err != nil {
+			log.Printf("WhoisComparison: 写出CSV表头失败: %v", err)
+			return
+		}
+		csvStream = stream
+	case utils.ExportNDJSON:
+		ndjsonStream = utils.NewNDJSONStream(c)
+	}
+
 	// 等待所有查询完成
-	wg.Wait()
+	for range providerInstances {
+		result := <-resultChan
+		results[result.Provider] = result
+
+		var err error
+		if csvStream != nil {
+			err = csvStream.WriteRow(result.csvRecord())
+		} else if ndjsonStream != nil {
+			err = ndjsonStream.WriteRecord(result)
+		}
+		if err != nil {
+			log.Printf("WhoisComparison: 写出提供商 %s 的结果失败: %v", result.Provider, err)
+		}
+	}
+	if format != utils.ExportJSON {
+		log.Printf("WHOIS提供商比较完成: %s, 格式: %s, 处理时间: %dms", domainStr, format, time.Since(startTime).Milliseconds())
+		return
+	}
 
 	// 生成比较摘要
 	summary := generateComparisonSummary(results)
@@ -115,7 +150,7 @@ func WhoisComparisonHandler(c *gin.Context) {
 }
 
 // queryProvider 查询单个提供商
-func queryProvider(ctx context.Context, name string, provider types.WhoisProvider, domain string, mu *sync.Mutex, results map[string]*WhoisProviderResult) {
+func queryProvider(ctx context.Context, name string, provider types.WhoisProvider, domain string) *WhoisProviderResult {
 	startTime := time.Now()
 	result := &WhoisProviderResult{
 		Provider: name,
@@ -153,11 +188,18 @@ func queryProvider(ctx context.Context, name string, provider types.WhoisProvide
 	}
 
 	result.ResponseTime = time.Since(startTime).Milliseconds()
+	return result
+}
 
-	// 线程安全地存储结果
-	mu.Lock()
-	results[name] = result
-	mu.Unlock()
+// csvRecord 将提供商结果展平为一行CSV
+func (r *WhoisProviderResult) csvRecord() []string {
+	return append([]string{
+		r.Provider,
+		strconv.FormatBool(r.Success),
+		r.Error,
+		strconv.FormatInt(r.ResponseTime, 10),
+		strconv.FormatBool(r.Cached),
+	}, r.Data.CSVRecord()...)
 }
 
 // generateComparisonSummary 生成比较摘要
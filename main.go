@@ -216,6 +216,11 @@ func main() {
 		}
 	}
 
+	// chrome子命令：管理Chrome二进制，不初始化日志和安全配置，也不启动HTTP服务
+	if len(os.Args) > 1 && os.Args[1] == "chrome" {
+		os.Exit(runChromeCommand(os.Args[2:]))
+	}
+
 	// 初始化日志系统
 	setupLogger()
 
@@ -238,14 +243,26 @@ func main() {
 	)
 
 	// 首先确保Chrome可用 - 在所有其他服务之前
-	log.Infof("=== 开始Chrome预检查和下载 ===")
-	chromeDownloader := utils.NewChromeDownloader()
-	if chromeExecPath, err := chromeDownloader.EnsureChrome(); err != nil {
-		log.Warnf("Chrome下载失败: %v，将继续使用系统Chrome", err)
+	// 使用远程浏览器后端时不需要本地Chrome
+	if services.GetGlobalChromeManager().IsRemote() {
+		log.Infof("使用远程Chrome后端，跳过Chrome预检查和下载")
 	} else {
-		log.Infof("Chrome已准备就绪: %s", chromeExecPath)
+		log.Infof("=== 开始Chrome预检查和下载 ===")
+		chromeDownloader := utils.NewChromeDownloader()
+		if chromeExecPath, err := chromeDownloader.EnsureChrome(); err != nil {
+			log.Warnf("Chrome下载失败: %v，将继续使用系统Chrome", err)
+		} else {
+			log.Infof("Chrome已准备就绪: %s", chromeExecPath)
+		}
+		log.Infof("=== Chrome预检查完成 ===")
+	}
+
+	// 公共后缀列表默认使用内置快照，配置刷新间隔后定期从远端更新
+	if interval, err := time.ParseDuration(os.Getenv("PSL_REFRESH_INTERVAL")); err == nil && interval > 0 {
+		log.Infof("启用公共后缀列表定期刷新，间隔: %v", interval)
+		stopPSLRefresher := utils.StartPublicSuffixListRefresher(os.Getenv("PSL_REFRESH_URL"), interval)
+		defer stopPSLRefresher()
 	}
-	log.Infof("=== Chrome预检查完成 ===")
 
 	// 初始化Redis客户端
 	redisAddr := os.Getenv("REDIS_ADDR")
@@ -267,6 +284,11 @@ func main() {
 		MaxConnAge:   30 * time.Minute, // 连接最大存活时间
 	})
 
+	// 初始化截图文件存储，SCREENSHOT_STORAGE配置无效时拒绝启动，避免各副本静默写入本地磁盘
+	if err := services.InitStorageFromEnv(); err != nil {
+		stdlog.Fatalf("截图文件存储配置无效: %v", err)
+	}
+
 	// 初始化服务容器
 	numCPU := runtime.NumCPU()
 	serviceContainer := services.NewServiceContainer(rdb, numCPU*2)
@@ -282,36 +304,46 @@ func main() {
 	serviceContainer.WhoisManager.AddProvider(ianaRDAPProvider)
 	serviceContainer.WhoisManager.AddProvider(ianaWhoisProvider)
 
+	// 初始化证书透明日志数据源，CT_DUMP_FILE指向本地转储文件时启用离线数据源
+	if os.Getenv("CT_DISABLE_CRTSH") != "true" {
+		serviceContainer.SubdomainDiscovery.AddSource(providers.NewCrtShSource())
+	}
+	if dumpFile := os.Getenv("CT_DUMP_FILE"); dumpFile != "" {
+		serviceContainer.SubdomainDiscovery.AddSource(providers.NewCTDumpSource(dumpFile))
+	}
+
 	// 初始化健康检查器
 	serviceContainer.InitializeHealthChecker()
 
-	// 异步初始化Chrome工具（完全非阻塞）
-	log.Infof("正在后台异步初始化Chrome工具...")
+	// 初始化定时截图调度器
+	if os.Getenv("SCREENSHOT_SCHEDULER_DISABLED") != "true" {
+		serviceContainer.InitializeScreenshotScheduler()
+	}
+
+	// 初始化截图文件清理
+	if os.Getenv("SCREENSHOT_JANITOR_DISABLED") != "true" {
+		serviceContainer.InitializeScreenshotJanitor()
+	}
+
+	// 异步初始化Chrome（完全非阻塞），预热模式下预先启动，其余模式在首次截图时按需启动
+	chromeManager := services.GetGlobalChromeManager()
 	port := getPort("8080") // 获取端口，以便在Chrome初始化失败时使用
 	go func() {
-		time.Sleep(3 * time.Second) // 延迟3秒启动，避免与主服务启动冲突
+		// 在所有启动检查结束后提示对外URL与监听端口
+		defer printReadyBanner(buildPublicURL(port), port)
 
-		log.Infof("[CHROME] 开始后台初始化Chrome工具...")
-		if err := utils.InitGlobalChromeUtil(); err != nil {
-			log.Infof("[CHROME] Chrome工具初始化失败: %v，截图功能不可用", err)
-			// 在所有启动检查结束后提示对外URL与监听端口
-			publicURL := buildPublicURL(port)
-			printReadyBanner(publicURL, port)
+		if !chromeManager.Prewarm() {
+			log.Infof("[CHROME] Chrome将在首次截图时按需启动")
 			return
 		}
 
-		log.Infof("[CHROME] Chrome工具初始化成功")
-
-		// 启动Chrome健康检查
-		chromeUtil := utils.GetGlobalChromeUtil()
-		if chromeUtil != nil {
-			log.Infof("[CHROME] Chrome工具已就绪，启动健康监控")
-			chromeUtil.StartHealthMonitor()
+		time.Sleep(3 * time.Second) // 延迟3秒启动，避免与主服务启动冲突
+		log.Infof("[CHROME] 预热模式，开始后台启动Chrome...")
+		if err := chromeManager.Start(); err != nil {
+			log.Infof("[CHROME] Chrome启动失败: %v，将在首次截图时重试", err)
+			return
 		}
-
-		// 在所有启动检查结束后提示对外URL与监听端口
-		publicURL := buildPublicURL(port)
-		printReadyBanner(publicURL, port)
+		log.Infof("[CHROME] Chrome已就绪")
 	}()
 
 	// 创建Gin引擎
@@ -323,13 +355,15 @@ func main() {
 	// 添加结构化HTTP日志中间件（替代gin.Default()的默认日志）
 	r.Use(middleware.HTTPLogger())
 
-	// 添加静态文件服务
+	// 添加静态文件服务，报告文件不公开，通过报告接口认证后下载或使用存储的预签名URL
 	r.Static("/static/screenshots", "./static/screenshots")
 	r.Static("/static/itdog", "./static/itdog")
+	r.Static("/static/diffs", "./static/diffs")
 
 	// 确保静态资源目录存在
 	os.MkdirAll("./static/screenshots", 0755)
 	os.MkdirAll("./static/itdog", 0755)
+	os.MkdirAll("./static/diffs", 0755)
 
 	// 启用CORS中间件
 	corsConfig := getCorsConfig()
@@ -362,14 +396,9 @@ func main() {
 		// 关闭服务容器
 		serviceContainer.Shutdown()
 
-		// 停止Chrome工具（如果已初始化）
-		if chromeUtil := utils.GetGlobalChromeUtil(); chromeUtil != nil {
-			log.Infof("[CHROME] 正在停止Chrome工具...")
-			chromeUtil.Stop()
-			log.Infof("[CHROME] Chrome工具已停止")
-		} else {
-			log.Infof("[CHROME] Chrome工具未初始化，无需停止")
-		}
+		// 停止Chrome
+		log.Infof("[CHROME] 正在停止Chrome...")
+		chromeManager.Stop()
 
 		// 设置关闭超时上下文
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -36,6 +36,11 @@ func ServiceMiddleware(container *services.ServiceContainer) gin.HandlerFunc {
 				c.Set("itdogChecker", container.ITDogChecker)
 			}
 
+			// 注入子域名发现服务
+			if container.SubdomainDiscovery != nil {
+				c.Set("subdomainDiscovery", container.SubdomainDiscovery)
+			}
+
 			// 注入健康检查器
 			if container.HealthChecker != nil {
 				c.Set("healthChecker", container.HealthChecker)
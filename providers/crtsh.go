@@ -0,0 +1,142 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: crt.sh 证书透明日志数据源 - 兼容crt.sh JSON接口
+ */
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"whosee/types"
+)
+
+// crt.sh 单个域名的响应可能非常大，限制读取体积
+const maxCrtShResponseSize = 64 * 1024 * 1024
+
+// CrtShRecord crt.sh JSON接口返回的单条记录，本地CT转储文件也使用同样的结构
+type CrtShRecord struct {
+	ID             int64  `json:"id"`
+	IssuerName     string `json:"issuer_name"`
+	CommonName     string `json:"common_name"`
+	NameValue      string `json:"name_value"` // 多个主机名以换行分隔
+	EntryTimestamp string `json:"entry_timestamp"`
+	NotBefore      string `json:"not_before"`
+	NotAfter       string `json:"not_after"`
+}
+
+// toEntry 转换为通用的证书透明日志记录
+func (r *CrtShRecord) toEntry() types.CTLogEntry {
+	names := strings.Split(r.NameValue, "\n")
+	if r.CommonName != "" {
+		names = append(names, r.CommonName)
+	}
+	return types.CTLogEntry{
+		Names:     names,
+		Issuer:    r.IssuerName,
+		NotBefore: parseCrtShTime(r.NotBefore),
+		NotAfter:  parseCrtShTime(r.NotAfter),
+	}
+}
+
+// parseCrtShTime 解析crt.sh的时间格式（不带时区，按UTC处理）
+func parseCrtShTime(value string) time.Time {
+	formats := []string{
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04:05.999",
+		time.RFC3339,
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+type CrtShSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCrtShSource 创建crt.sh数据源，CT_CRTSH_URL可指向兼容的自建实例
+func NewCrtShSource() *CrtShSource {
+	baseURL := strings.TrimRight(os.Getenv("CT_CRTSH_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://crt.sh"
+	}
+
+	return &CrtShSource{
+		baseURL: baseURL,
+		client: &http.Client{
+			// crt.sh 查询大域名时响应较慢
+			Timeout: 45 * time.Second,
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 40 * time.Second,
+				MaxIdleConns:          10,
+				MaxIdleConnsPerHost:   2,
+				IdleConnTimeout:       60 * time.Second,
+			},
+		},
+	}
+}
+
+func (s *CrtShSource) Name() string {
+	return "crt.sh"
+}
+
+func (s *CrtShSource) Search(ctx context.Context, domain string) ([]types.CTLogEntry, error) {
+	// %.example.com 匹配所有子域名证书，exclude=expired不加，保留历史子域名
+	query := url.Values{}
+	query.Set("q", "%."+domain)
+	query.Set("output", "json")
+	apiURL := fmt.Sprintf("%s/?%s", s.baseURL, query.Encode())
+
+	log.Printf("使用 crt.sh 查询子域名: %s", domain)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Whosee-CT/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("crt.sh请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("crt.sh返回错误: HTTP %d, %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var records []CrtShRecord
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCrtShResponseSize)).Decode(&records); err != nil {
+		return nil, fmt.Errorf("解析crt.sh响应失败: %v", err)
+	}
+
+	entries := make([]types.CTLogEntry, 0, len(records))
+	for i := range records {
+		entries = append(entries, records[i].toEntry())
+	}
+
+	log.Printf("crt.sh 查询完成: %s, 证书记录数: %d", domain, len(entries))
+	return entries, nil
+}
@@ -0,0 +1,166 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 本地证书透明日志转储文件数据源 - 供离线环境使用
+ */
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"whosee/types"
+)
+
+// CTDumpSource 读取本地CT转储文件，支持三种格式：
+//   - crt.sh JSON数组（直接保存的接口响应）
+//   - 每行一个crt.sh JSON对象（NDJSON）
+//   - 每行一个主机名的纯文本
+type CTDumpSource struct {
+	path string
+}
+
+func NewCTDumpSource(path string) *CTDumpSource {
+	return &CTDumpSource{path: path}
+}
+
+func (s *CTDumpSource) Name() string {
+	return "ct-dump"
+}
+
+func (s *CTDumpSource) Search(ctx context.Context, domain string) ([]types.CTLogEntry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("打开CT转储文件失败: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+
+	// 根据第一个非空白字符判断格式
+	var first byte
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取CT转储文件失败: %v", err)
+		}
+		if !isJSONSpace(b) {
+			first = b
+			reader.UnreadByte()
+			break
+		}
+	}
+
+	// 只保留与查询域名相关的记录，避免大文件占用内存
+	suffix := "." + domain
+	matches := func(name string) bool {
+		name = strings.ToLower(strings.TrimSpace(name))
+		return name == domain || strings.HasSuffix(name, suffix)
+	}
+
+	var entries []types.CTLogEntry
+	if first == '[' {
+		entries, err = s.readJSONArray(ctx, reader, matches)
+	} else {
+		entries, err = s.readLines(ctx, reader, matches)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("CT转储文件查询完成: %s, 匹配记录数: %d", domain, len(entries))
+	return entries, nil
+}
+
+// readJSONArray 流式解析crt.sh JSON数组
+func (s *CTDumpSource) readJSONArray(ctx context.Context, r io.Reader, matches func(string) bool) ([]types.CTLogEntry, error) {
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("解析CT转储文件失败: %v", err)
+	}
+
+	var entries []types.CTLogEntry
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var record CrtShRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("解析CT转储文件失败: %v", err)
+		}
+		if entry, ok := filterEntry(record.toEntry(), matches); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// readLines 逐行解析NDJSON或纯文本主机名
+func (s *CTDumpSource) readLines(ctx context.Context, r io.Reader, matches func(string) bool) ([]types.CTLogEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var entries []types.CTLogEntry
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if lineNo%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		var entry types.CTLogEntry
+		if line[0] == '{' {
+			var record CrtShRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				log.Printf("CT转储文件第 %d 行解析失败: %v", lineNo, err)
+				continue
+			}
+			entry = record.toEntry()
+		} else {
+			entry = types.CTLogEntry{Names: []string{string(line)}}
+		}
+
+		if filtered, ok := filterEntry(entry, matches); ok {
+			entries = append(entries, filtered)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取CT转储文件失败: %v", err)
+	}
+	return entries, nil
+}
+
+// filterEntry 过滤掉与查询域名无关的主机名，没有剩余主机名时丢弃整条记录
+func filterEntry(entry types.CTLogEntry, matches func(string) bool) (types.CTLogEntry, bool) {
+	names := entry.Names[:0]
+	for _, name := range entry.Names {
+		if matches(name) {
+			names = append(names, name)
+		}
+	}
+	entry.Names = names
+	return entry, len(names) > 0
+}
+
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
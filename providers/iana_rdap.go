@@ -130,6 +130,7 @@ func NewIANARDAPProvider() *IANARDAPProvider {
 				DialContext: (&net.Dialer{
 					Timeout:   5 * time.Second,
 					KeepAlive: 30 * time.Second,
+					Control:   utils.GetNetworkPolicy().Control, // RDAP地址来自引导数据和重定向，拒绝内网地址
 				}).DialContext,
 				TLSHandshakeTimeout:   5 * time.Second,
 				ResponseHeaderTimeout: 10 * time.Second,
@@ -43,6 +43,9 @@ func (p *IANAWhoisProvider) Query(domain string) (*types.WhoisResponse, error, b
 		}, fmt.Errorf("无效的域名格式: %s", domain), false
 	}
 
+	// 按公共后缀列表归约为可注册域名，避免把子域名发给权威WHOIS服务器
+	domain = utils.RegistrableDomain(domain)
+
 	// 提取顶级域名
 	tld := p.extractTLD(domain)
 	if tld == "" {
@@ -104,18 +107,21 @@ func (p *IANAWhoisProvider) Query(domain string) (*types.WhoisResponse, error, b
 	return response, nil, false
 }
 
+// extractTLD 提取用于查询IANA的顶级域名
+// IANA只登记顶级域名的WHOIS服务器，因此取有效顶级域名（如co.uk）最右侧的标签
 func (p *IANAWhoisProvider) extractTLD(domain string) string {
-	parts := strings.Split(domain, ".")
-	if len(parts) < 2 {
+	parts, err := utils.SplitDomain(domain)
+	if err != nil || parts.RegistrableDomain == "" {
 		return ""
 	}
-	return parts[len(parts)-1]
+	labels := strings.Split(parts.EffectiveTLD, ".")
+	return labels[len(labels)-1]
 }
 
 func (p *IANAWhoisProvider) queryIANAForTLD(tld string) (string, error) {
 	log.Printf("查询IANA获取 %s 的WHOIS服务器", tld)
 
-	conn, err := net.DialTimeout("tcp", "whois.iana.org:43", p.timeout)
+	conn, err := p.dial("whois.iana.org:43")
 	if err != nil {
 		return "", fmt.Errorf("连接IANA失败: %v", err)
 	}
@@ -166,10 +172,16 @@ func (p *IANAWhoisProvider) extractWhoisServer(ianaResponse string) string {
 	return ""
 }
 
+// dial 连接WHOIS服务器，服务器地址来自IANA响应，连接前按出站策略拒绝内网地址
+func (p *IANAWhoisProvider) dial(address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: p.timeout, Control: utils.GetNetworkPolicy().Control}
+	return dialer.Dial("tcp", address)
+}
+
 func (p *IANAWhoisProvider) queryWhoisServer(server, domain string) (string, error) {
 	log.Printf("查询WHOIS服务器: %s，域名: %s", server, domain)
 
-	conn, err := net.DialTimeout("tcp", server+":43", p.timeout)
+	conn, err := p.dial(server + ":43")
 	if err != nil {
 		return "", fmt.Errorf("连接WHOIS服务器失败: %v", err)
 	}
@@ -54,6 +54,7 @@ func NewWhoisFreaksProvider() *WhoisFreaksProvider {
 				DialContext: (&net.Dialer{
 					Timeout:   10 * time.Second, // 增加到10秒
 					KeepAlive: 60 * time.Second,
+					Control:   utils.GetNetworkPolicy().Control,
 				}).DialContext,
 				DisableKeepAlives: false,
 				ForceAttemptHTTP2: true, // 启用HTTP/2
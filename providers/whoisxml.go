@@ -79,6 +79,7 @@ func NewWhoisXMLProvider() *WhoisXMLProvider {
 				DialContext: (&net.Dialer{
 					Timeout:   10 * time.Second,
 					KeepAlive: 60 * time.Second,
+					Control:   utils.GetNetworkPolicy().Control,
 				}).DialContext,
 				DisableKeepAlives: false,
 				ForceAttemptHTTP2: true,
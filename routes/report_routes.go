@@ -0,0 +1,32 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 域名聚合报告路由配置
+ */
+package routes
+
+import (
+	"time"
+
+	"whosee/handlers"
+	"whosee/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterReportRoutes 注册域名聚合报告路由
+func RegisterReportRoutes(apiv1 *gin.RouterGroup, serviceContainer *services.ServiceContainer) {
+	chromeManager := services.GetGlobalChromeManager()
+	reportRenderer := services.NewReportRenderer(chromeManager, serviceContainer.RedisClient, nil)
+	reportHandler := handlers.NewReportHandler(serviceContainer.ScreenshotService(), chromeManager, reportRenderer)
+
+	// 总超时需覆盖最慢的截图分节
+	reportGroup := apiv1.Group("/report")
+	reportGroup.Use(domainValidationMiddleware())
+	reportGroup.Use(rateLimitMiddleware(serviceContainer.Limiter))
+	reportGroup.Use(asyncWorkerMiddleware(serviceContainer.WorkerPool, 90*time.Second))
+	{
+		reportGroup.GET("", reportHandler.GetReport)
+		reportGroup.GET("/:domain", reportHandler.GetReport)
+	}
+}
@@ -272,8 +272,19 @@ func RegisterAPIRoutes(r *gin.Engine, serviceContainer *services.ServiceContaine
 	dnsGroup.GET("", handlers.DNSHandler)
 	dnsGroup.GET("/:domain", handlers.DNSHandler)
 
+	// 子域名发现路由（证书透明日志）
+	subdomainGroup := apiv1.Group("/subdomains")
+	subdomainGroup.Use(domainValidationMiddleware())
+	subdomainGroup.Use(rateLimitMiddleware(apiLimiter))
+	subdomainGroup.Use(asyncWorkerMiddleware(serviceContainer.WorkerPool, 60*time.Second))
+	subdomainGroup.GET("", handlers.SubdomainsHandler)
+	subdomainGroup.GET("/:domain", handlers.SubdomainsHandler)
+
 	// 🔧 P2-3修复：启用统一截图架构
 	// 注册重构后的截图服务路由，包含新的统一API和向后兼容的legacy路由
 	// 这将替换下面所有手动定义的截图路由，启用Chrome管理器、熔断器和并发控制
 	RegisterScreenshotRoutes(apiv1, serviceContainer)
+
+	// 域名聚合报告路由
+	RegisterReportRoutes(apiv1, serviceContainer)
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"whosee/handlers"
+	"whosee/middleware"
 	"whosee/services"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +18,10 @@ import (
 // RegisterScreenshotRoutes 注册截图服务路由
 // 🔧 P2-3修复：接受已配置认证的router group，确保截图路由继承安全中间件
 func RegisterScreenshotRoutes(apiv1 *gin.RouterGroup, serviceContainer *services.ServiceContainer) {
-	// 创建截图服务实例
+	// 使用容器中共享的截图服务实例
 	chromeManager := services.GetGlobalChromeManager()
-	screenshotService := services.NewScreenshotService(chromeManager, serviceContainer.RedisClient, nil)
-	screenshotHandler := handlers.NewUnifiedScreenshotHandler(screenshotService, chromeManager)
+	screenshotHandler := handlers.NewUnifiedScreenshotHandler(serviceContainer.ScreenshotService(), chromeManager)
+	historyHandler := handlers.NewScreenshotHistoryHandler(services.NewScreenshotHistory(serviceContainer.RedisClient, nil))
 
 	// 🔧 P2-3关键修复：先应用中间件，再注册路由
 	// Gin的Use()只影响之后注册的路由，必须在创建group后立即应用中间件
@@ -36,11 +37,37 @@ func RegisterScreenshotRoutes(apiv1 *gin.RouterGroup, serviceContainer *services
 		screenshotGroup.POST("/", screenshotHandler.TakeScreenshot)
 		screenshotGroup.GET("/", screenshotHandler.TakeScreenshot)
 
+		// 截图历史与视觉对比
+		screenshotGroup.GET("/history", historyHandler.GetHistory)
+		screenshotGroup.GET("/diff", historyHandler.GetDiff)
+
 		// Chrome管理接口
 		screenshotGroup.GET("/chrome/status", handlers.NewChromeStatus)
 		screenshotGroup.POST("/chrome/restart", handlers.NewChromeRestart)
 	}
 
+	// 定时截图任务管理，不针对单个域名，不使用域名校验中间件
+	jobsHandler := handlers.NewScreenshotJobsHandler(serviceContainer.ScreenshotJobs)
+	jobsGroup := apiv1.Group("/screenshot/jobs")
+	jobsGroup.Use(rateLimitMiddleware(serviceContainer.Limiter))
+	{
+		jobsGroup.GET("", jobsHandler.ListJobs)
+		jobsGroup.POST("", jobsHandler.CreateJob)
+		jobsGroup.GET("/:id", jobsHandler.GetJob)
+		jobsGroup.DELETE("/:id", jobsHandler.DeleteJob)
+	}
+
+	// 截图文件清理统计与试运行，会列出存储中的文件，只允许可信IP或持有API Key的管理方访问
+	// 不受DISABLE_API_SECURITY影响
+	storageHandler := handlers.NewScreenshotStorageHandler(serviceContainer.ScreenshotJanitor)
+	storageGroup := apiv1.Group("/screenshot/storage")
+	storageGroup.Use(middleware.IPWhitelistMiddleware())
+	storageGroup.Use(rateLimitMiddleware(serviceContainer.Limiter))
+	{
+		storageGroup.GET("", storageHandler.GetStats)
+		storageGroup.POST("/cleanup", storageHandler.Cleanup)
+	}
+
 	// 兼容旧版API路由 (保持向后兼容)
 	compatGroup := apiv1.Group("/")
 	// 应用中间件（必须在路由注册之前）
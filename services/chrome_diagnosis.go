@@ -0,0 +1,338 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome故障诊断 - 健康检查失败模式分析、诊断报告、恢复建议和强制重置
+ */
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthMonitor 健康检查失败统计，连续失败达到阈值时强制重置
+type healthMonitor struct {
+	mu                  sync.Mutex
+	checks              int
+	failures            int
+	consecutiveFailures int
+	reasons             map[string]int // 失败模式 -> 次数
+}
+
+// checkHealth 执行一次健康检查，连续失败达到阈值时进行详细诊断并强制重置
+func (cm *ChromeManager) checkHealth() {
+	h := &cm.health
+	h.mu.Lock()
+	h.checks++
+	if cm.isHealthy() {
+		if h.consecutiveFailures > 0 {
+			log.Printf("[CHROME-MANAGER] 健康检查恢复正常 (之前连续失败%d次)", h.consecutiveFailures)
+			h.consecutiveFailures = 0
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	h.consecutiveFailures++
+	h.failures++
+	reason := cm.analyzeFailurePattern(h.consecutiveFailures)
+	if h.reasons == nil {
+		h.reasons = make(map[string]int)
+	}
+	h.reasons[reason]++
+	consecutive := h.consecutiveFailures
+	threshold := calculateResetThreshold(h.consecutiveFailures, h.failures, h.checks)
+	log.Printf("[CHROME-MANAGER] 健康检查失败: %s (连续:%d, 总计:%d/%d)", reason, consecutive, h.failures, h.checks)
+	h.mu.Unlock()
+
+	if consecutive < threshold {
+		return
+	}
+
+	log.Printf("[CHROME-MANAGER] 连续失败达到阈值(%d)，开始详细诊断...", threshold)
+	cm.performDetailedDiagnosis()
+	if err := cm.ForceReset(); err != nil {
+		log.Printf("[CHROME-MANAGER] 强制重置失败: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.consecutiveFailures = 0
+	h.mu.Unlock()
+}
+
+// analyzeFailurePattern 分析失败模式
+func (cm *ChromeManager) analyzeFailurePattern(consecutiveFailures int) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if atomic.LoadInt32(&cm.isRunning) == 0 {
+		return "chrome_not_running"
+	}
+	if len(cm.browsers) == 0 {
+		return "context_missing"
+	}
+
+	// 浏览器都已退出或断开，远程后端通常是连接问题
+	alive := 0
+	for _, browser := range cm.browsers {
+		if browser.alive() {
+			alive++
+		}
+	}
+	if alive == 0 {
+		if cm.IsRemote() {
+			return "connection_failed"
+		}
+		return "context_canceled"
+	}
+
+	// 根据连续失败次数判断
+	switch {
+	case consecutiveFailures == 1:
+		return "transient_failure"
+	case consecutiveFailures <= 3:
+		return "intermittent_failure"
+	default:
+		return "persistent_failure"
+	}
+}
+
+// calculateResetThreshold 动态计算重置阈值
+func calculateResetThreshold(consecutiveFailures, totalFailures, totalChecks int) int {
+	baseThreshold := 5
+
+	// 如果总体失败率很高，降低阈值
+	if totalChecks > 10 {
+		failureRate := float64(totalFailures) / float64(totalChecks)
+		if failureRate > 0.5 { // 失败率超过50%
+			return 3
+		} else if failureRate > 0.3 { // 失败率超过30%
+			return 4
+		}
+	}
+
+	// 如果已经连续失败很多次，保持较高的阈值避免过度重置
+	if consecutiveFailures > 10 {
+		return 8
+	}
+
+	return baseThreshold
+}
+
+// getRecoveryRecommendations 根据失败模式提供恢复建议
+func getRecoveryRecommendations(failureReasons map[string]int, consecutiveFailures int) []string {
+	recommendations := []string{}
+
+	for reason, count := range failureReasons {
+		switch reason {
+		case "context_canceled":
+			recommendations = append(recommendations,
+				fmt.Sprintf("上下文取消问题(%d次): 检查Chrome进程是否异常退出，考虑增加资源限制", count))
+		case "connection_failed":
+			recommendations = append(recommendations,
+				fmt.Sprintf("连接失败(%d次): 检查网络状态和Chrome WebSocket连接", count))
+		case "chrome_not_running", "context_missing":
+			recommendations = append(recommendations,
+				fmt.Sprintf("Chrome未运行(%d次): 检查Chrome二进制文件和启动参数", count))
+		case "persistent_failure":
+			recommendations = append(recommendations,
+				fmt.Sprintf("持续失败(%d次): 考虑系统资源不足或Chrome配置问题", count))
+		}
+	}
+
+	// 基于连续失败次数提供建议
+	if consecutiveFailures >= 10 {
+		recommendations = append(recommendations, "长期失败: 建议重启整个服务或检查系统环境")
+	} else if consecutiveFailures >= 7 {
+		recommendations = append(recommendations, "频繁失败: 建议检查系统内存和CPU使用率")
+	} else if consecutiveFailures >= 5 {
+		recommendations = append(recommendations, "多次失败: 建议检查Chrome启动参数和权限设置")
+	}
+
+	if len(recommendations) == 0 {
+		recommendations = append(recommendations, "常规恢复: 等待自动重置或手动重启Chrome实例")
+	}
+
+	return recommendations
+}
+
+// Diagnose 诊断Chrome实例状态，severity为none/medium/high/critical
+func (cm *ChromeManager) Diagnose() map[string]interface{} {
+	cm.stats.mu.RLock()
+	restarts, lastRestart := cm.stats.restarts, cm.stats.lastRestart
+	cm.stats.mu.RUnlock()
+
+	cm.mu.RLock()
+	browsers := append([]*browserInstance(nil), cm.browsers...)
+	cm.mu.RUnlock()
+
+	healthy := 0
+	var probe *browserInstance
+	for _, browser := range browsers {
+		if browser.available() {
+			healthy++
+			if probe == nil {
+				probe = browser
+			}
+		}
+	}
+
+	diagnosis := map[string]interface{}{
+		"timestamp":          time.Now().Format(time.RFC3339),
+		"mode":               cm.config.Mode.String(),
+		"is_running":         atomic.LoadInt32(&cm.isRunning) == 1,
+		"browsers":           len(browsers),
+		"healthy_browsers":   healthy,
+		"restarts":           restarts,
+		"last_restart":       lastRestart.Format(time.RFC3339),
+		"uptime_seconds":     time.Since(cm.startTime).Seconds(),
+		"max_concurrent":     cm.maxConcurrent,
+		"current_concurrent": len(cm.semaphore),
+		"available_permits":  cm.maxConcurrent - len(cm.semaphore),
+	}
+
+	// 基础状态检查
+	if atomic.LoadInt32(&cm.isRunning) == 0 {
+		diagnosis["issue"] = "Chrome实例未运行"
+		diagnosis["severity"] = "critical"
+		// 按需启动的模式下未运行是正常状态
+		if cm.config.Mode != ChromeModeWarm {
+			diagnosis["severity"] = "none"
+		}
+		return diagnosis
+	}
+
+	if probe == nil {
+		diagnosis["issue"] = "没有可用的浏览器，上下文已取消或连接已断开"
+		diagnosis["severity"] = "critical"
+		return diagnosis
+	}
+
+	// 在可用浏览器中执行简单脚本
+	if err := probe.validate(5 * time.Second); err != nil {
+		diagnosis["issue"] = "JavaScript执行失败"
+		diagnosis["js_error"] = err.Error()
+		diagnosis["severity"] = "high"
+	} else {
+		diagnosis["js_execution"] = "success"
+		diagnosis["severity"] = "none"
+	}
+
+	// 并发状态评估
+	if len(cm.semaphore) >= cm.maxConcurrent {
+		diagnosis["concurrency_warning"] = "所有并发许可已用完"
+		if diagnosis["severity"] == "none" {
+			diagnosis["severity"] = "medium"
+		}
+	}
+
+	return diagnosis
+}
+
+// GetDetailedStats 获取统计、诊断和建议
+func (cm *ChromeManager) GetDetailedStats() map[string]interface{} {
+	diagnosis := cm.Diagnose()
+
+	cm.health.mu.Lock()
+	health := map[string]interface{}{
+		"checks":               cm.health.checks,
+		"failures":             cm.health.failures,
+		"consecutive_failures": cm.health.consecutiveFailures,
+		"failure_reasons":      cm.health.reasons,
+	}
+	recovery := getRecoveryRecommendations(cm.health.reasons, cm.health.consecutiveFailures)
+	cm.health.mu.Unlock()
+
+	return map[string]interface{}{
+		"basic_stats":              cm.GetStats(),
+		"diagnosis":                diagnosis,
+		"health":                   health,
+		"mode_description":         cm.config.Mode.Description(),
+		"recommendations":          getRecommendations(diagnosis),
+		"recovery_recommendations": recovery,
+	}
+}
+
+// getRecommendations 根据诊断结果提供建议
+func getRecommendations(diagnosis map[string]interface{}) []string {
+	var recommendations []string
+
+	severity, _ := diagnosis["severity"].(string)
+
+	switch severity {
+	case "critical":
+		recommendations = append(recommendations, "立即重启Chrome实例")
+		recommendations = append(recommendations, "检查系统资源使用情况")
+	case "high":
+		recommendations = append(recommendations, "考虑重启Chrome实例")
+		recommendations = append(recommendations, "检查JavaScript执行环境")
+	case "medium":
+		recommendations = append(recommendations, "监控并发使用情况")
+		recommendations = append(recommendations, "考虑增加并发限制")
+	default:
+		recommendations = append(recommendations, "Chrome运行正常")
+	}
+
+	uptime, ok := diagnosis["uptime_seconds"].(float64)
+	if ok && uptime > 3600 && diagnosis["is_running"] == true { // 运行超过1小时
+		recommendations = append(recommendations, "Chrome已运行较长时间，建议定期重启")
+	}
+
+	return recommendations
+}
+
+// ForceReset 强制重置：立即关闭全部浏览器（进行中的任务会失败）后重新启动
+// 用于滚动重启无法恢复的情况，如持续的context canceled
+func (cm *ChromeManager) ForceReset() error {
+	log.Printf("[CHROME-MANAGER] 执行强制重置...")
+	cm.Stop()
+
+	if err := cm.Start(); err != nil {
+		log.Printf("[CHROME-MANAGER] 强制重置失败: %v", err)
+		return err
+	}
+
+	log.Printf("[CHROME-MANAGER] 强制重置成功")
+	return nil
+}
+
+// performDetailedDiagnosis 执行详细诊断（仅在出问题时调用）
+func (cm *ChromeManager) performDetailedDiagnosis() {
+	log.Printf("[CHROME-MANAGER] === 详细诊断开始 ===")
+
+	// 检查Chrome可执行文件
+	if !cm.IsRemote() {
+		if execPath := cm.execPath(); execPath == "" {
+			log.Printf("[CHROME-MANAGER] 未找到Chrome可执行文件")
+		} else if _, err := os.Stat(execPath); err != nil {
+			log.Printf("[CHROME-MANAGER] Chrome可执行文件异常: %v", err)
+		} else {
+			log.Printf("[CHROME-MANAGER] Chrome可执行文件正常: %s", execPath)
+		}
+	}
+
+	// 检查各浏览器状态
+	cm.mu.RLock()
+	for _, browser := range cm.browsers {
+		select {
+		case <-browser.ctx.Done():
+			logBrowser(browser, "上下文已取消: %v", browser.ctx.Err())
+		default:
+			logBrowser(browser, "上下文状态正常，进行中任务: %d", atomic.LoadInt32(&browser.active))
+		}
+	}
+	cm.mu.RUnlock()
+
+	// 系统资源检查
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	log.Printf("[CHROME-MANAGER] 内存使用: %dMB, Goroutine: %d", m.Alloc/1024/1024, runtime.NumGoroutine())
+
+	log.Printf("[CHROME-MANAGER] === 详细诊断结束 ===")
+}
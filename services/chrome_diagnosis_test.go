@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestCalculateResetThreshold 测试根据失败率调整重置阈值
+func TestCalculateResetThreshold(t *testing.T) {
+	cases := []struct {
+		consecutive, failures, checks, expected int
+	}{
+		{1, 1, 5, 5},     // 检查次数不足，使用基础阈值
+		{2, 12, 20, 3},   // 失败率60%
+		{2, 7, 20, 4},    // 失败率35%
+		{11, 11, 100, 8}, // 长期连续失败，避免过度重置
+	}
+	for _, c := range cases {
+		if got := calculateResetThreshold(c.consecutive, c.failures, c.checks); got != c.expected {
+			t.Errorf("连续%d 失败%d/%d: 期望 %d, 实际 %d", c.consecutive, c.failures, c.checks, c.expected, got)
+		}
+	}
+}
+
+// TestAnalyzeFailurePattern 测试失败模式分析
+func TestAnalyzeFailurePattern(t *testing.T) {
+	cm := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 1})
+	if reason := cm.analyzeFailurePattern(1); reason != "chrome_not_running" {
+		t.Errorf("未运行: %s", reason)
+	}
+
+	cm.isRunning = 1
+	b := &browserInstance{id: 1}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	cm.browsers = []*browserInstance{b}
+	if reason := cm.analyzeFailurePattern(4); reason != "persistent_failure" {
+		t.Errorf("浏览器存活时按连续次数判断: %s", reason)
+	}
+
+	b.cancel()
+	if reason := cm.analyzeFailurePattern(1); reason != "context_canceled" {
+		t.Errorf("本地浏览器全部退出: %s", reason)
+	}
+	cm.config.RemoteEndpoints = []string{"ws://chrome:9222"}
+	if reason := cm.analyzeFailurePattern(1); reason != "connection_failed" {
+		t.Errorf("远程浏览器全部断开: %s", reason)
+	}
+}
+
+// TestDiagnoseNotRunning 测试按需启动模式下未运行不视为故障
+func TestDiagnoseNotRunning(t *testing.T) {
+	cm := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 1})
+	if severity := cm.Diagnose()["severity"]; severity != "none" {
+		t.Errorf("自动模式未运行应为none: %v", severity)
+	}
+
+	cm = NewChromeManager(&ChromeManagerConfig{Mode: ChromeModeWarm, MaxConcurrent: 1})
+	diagnosis := cm.Diagnose()
+	if diagnosis["severity"] != "critical" {
+		t.Errorf("预热模式未运行应为critical: %v", diagnosis["severity"])
+	}
+	recommendations := strings.Join(getRecommendations(diagnosis), ";")
+	if !strings.Contains(recommendations, "立即重启Chrome实例") {
+		t.Errorf("建议不正确: %s", recommendations)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,10 +24,15 @@ import (
 // ChromeManager Chrome管理器
 type ChromeManager struct {
 	mu               sync.RWMutex
-	ctx              context.Context
-	cancel           context.CancelFunc
-	allocCtx         context.Context
-	allocCancel      context.CancelFunc
+	poolMu           sync.Mutex          // 串行化进程池补充，避免并发启动过多浏览器
+	browsers         []*browserInstance  // 浏览器进程池
+	nextBrowserID    int32
+	stopCh           chan struct{}
+	cursor           uint32              // 远程后端轮询游标
+	sessionMu        sync.Mutex          // 串行化持久会话创建
+	sessions         map[string]*browserSession // persist_session持久会话
+	usage            usageWindow         // 最近的任务数，自动模式据此调整空闲回收时间
+	health           healthMonitor       // 健康检查失败统计
 	isRunning        int32               // 原子操作，避免锁竞争
 	maxConcurrent    int                 // 最大并发数
 	currentTasks     int32               // 当前任务数
@@ -32,18 +40,27 @@ type ChromeManager struct {
 	config           *ChromeManagerConfig
 	stats            *ChromeStats
 	circuitBreaker   *CircuitBreaker     // 使用现有的熔断器
+	guardProxies     *guardProxySet      // 浏览器上下文使用的出站代理
 	lastUsed         time.Time
 	startTime        time.Time
 }
 
 // ChromeManagerConfig Chrome管理器配置
 type ChromeManagerConfig struct {
+	Mode                ChromeMode    // 运行模式（auto/cold/warm）
 	MaxConcurrent       int           // 最大并发数
 	TaskTimeout         time.Duration // 任务超时时间
 	IdleTimeout         time.Duration // 空闲超时时间
 	StartupTimeout      time.Duration // 启动超时时间
 	HealthCheckInterval time.Duration // 健康检查间隔
 	EnableCircuitBreaker bool         // 是否启用熔断器
+	PoolSize            int           // 浏览器进程数，不超过最大并发数
+	MaxTasksPerBrowser  int           // 单个浏览器处理该数量任务后回收，0表示不限制
+	MaxBrowserMemoryMB  int           // 单个浏览器进程树内存超过该值后回收，0表示不限制
+	DrainTimeout        time.Duration // 回收前等待进行中任务结束的最长时间
+	RemoteEndpoints     []string      // 远程DevTools地址，非空时连接远程浏览器而不启动本地进程
+	GuardProxyListen    string        // 出站代理的监听地址，为空时只监听127.0.0.1
+	GuardProxyAdvertise string        // 远程浏览器访问出站代理使用的主机名，远程模式下为空时不使用出站代理
 	ChromeOptions       []chromedp.ExecAllocatorOption // Chrome选项
 }
 
@@ -56,16 +73,21 @@ type ChromeStats struct {
 	totalDuration time.Duration // 总执行时间
 	restarts      int64         // 重启次数
 	lastRestart   time.Time     // 最后重启时间
+	recycles      int64         // 浏览器回收次数
 }
 
 // DefaultChromeManagerConfig 默认Chrome管理器配置
 var DefaultChromeManagerConfig = &ChromeManagerConfig{
 	MaxConcurrent:        3,
 	TaskTimeout:          60 * time.Second,
-	IdleTimeout:          5 * time.Minute,
+	IdleTimeout:          3 * time.Minute,
 	StartupTimeout:       30 * time.Second,
 	HealthCheckInterval:  60 * time.Second,
 	EnableCircuitBreaker: true,
+	PoolSize:             2,
+	MaxTasksPerBrowser:   200,
+	MaxBrowserMemoryMB:   1536,
+	DrainTimeout:         60 * time.Second,
 	ChromeOptions: []chromedp.ExecAllocatorOption{
 		chromedp.NoFirstRun,
 		chromedp.NoDefaultBrowserCheck,
@@ -89,7 +111,7 @@ var (
 // GetGlobalChromeManager 获取全局Chrome管理器
 func GetGlobalChromeManager() *ChromeManager {
 	chromeManagerOnce.Do(func() {
-		globalChromeManager = NewChromeManager(DefaultChromeManagerConfig)
+		globalChromeManager = NewChromeManager(chromeManagerConfigFromEnv())
 	})
 	return globalChromeManager
 }
@@ -105,6 +127,8 @@ func NewChromeManager(config *ChromeManagerConfig) *ChromeManager {
 		semaphore:      make(chan struct{}, config.MaxConcurrent),
 		config:         config,
 		stats:          &ChromeStats{},
+		sessions:       make(map[string]*browserSession),
+		guardProxies:   newGuardProxySet(utils.GetNetworkPolicy(), config.GuardProxyListen, config.GuardProxyAdvertise),
 		lastUsed:       time.Now(),
 	}
 
@@ -113,11 +137,78 @@ func NewChromeManager(config *ChromeManagerConfig) *ChromeManager {
 		manager.circuitBreaker = NewCircuitBreaker(5, 30*time.Second)
 	}
 
-	log.Printf("[CHROME-MANAGER] 创建Chrome管理器，最大并发: %d", config.MaxConcurrent)
+	if manager.IsRemote() {
+		log.Printf("[CHROME-MANAGER] 创建Chrome管理器，最大并发: %d，远程浏览器: %d个", config.MaxConcurrent, len(config.RemoteEndpoints))
+		if config.GuardProxyAdvertise == "" {
+			log.Printf("[CHROME-MANAGER] 未配置CHROME_GUARD_PROXY_ADVERTISE，远程浏览器不经过出站代理，只通过Fetch拦截检查页面请求")
+		}
+	} else {
+		log.Printf("[CHROME-MANAGER] 创建Chrome管理器，模式: %s，最大并发: %d，浏览器进程数: %d", config.Mode.Description(), config.MaxConcurrent, manager.poolSize())
+	}
 	return manager
 }
 
-// Start 启动Chrome实例
+// chromeManagerConfigFromEnv 在默认配置基础上应用环境变量中的进程池配置
+func chromeManagerConfigFromEnv() *ChromeManagerConfig {
+	config := *DefaultChromeManagerConfig
+	envInt := func(key string, target *int) {
+		if value := os.Getenv(key); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			} else {
+				log.Printf("[CHROME-MANAGER] 忽略无效的%s: %s", key, value)
+			}
+		}
+	}
+	if mode := os.Getenv("CHROME_MODE"); mode != "" {
+		config.Mode = ParseChromeMode(mode)
+	}
+	envInt("CHROME_POOL_SIZE", &config.PoolSize)
+	envInt("CHROME_MAX_TASKS_PER_BROWSER", &config.MaxTasksPerBrowser)
+	envInt("CHROME_MAX_BROWSER_MEMORY_MB", &config.MaxBrowserMemoryMB)
+	config.GuardProxyListen = strings.TrimSpace(os.Getenv("CHROME_GUARD_PROXY_LISTEN"))
+	config.GuardProxyAdvertise = strings.TrimSpace(os.Getenv("CHROME_GUARD_PROXY_ADVERTISE"))
+	if value := os.Getenv("CHROME_REMOTE_URLS"); value != "" {
+		config.RemoteEndpoints = nil
+		for _, endpoint := range strings.Split(value, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				config.RemoteEndpoints = append(config.RemoteEndpoints, endpoint)
+			}
+		}
+	}
+	return &config
+}
+
+// IsRemote 是否使用远程浏览器后端
+func (cm *ChromeManager) IsRemote() bool {
+	return len(cm.config.RemoteEndpoints) > 0
+}
+
+// poolSize 实际的浏览器数：远程后端每个地址一个连接，本地进程数不超过最大并发数
+func (cm *ChromeManager) poolSize() int {
+	if cm.IsRemote() {
+		return len(cm.config.RemoteEndpoints)
+	}
+	return max(1, min(cm.config.PoolSize, cm.maxConcurrent))
+}
+
+// slots 进程池中每个位置对应的远程地址，本地进程为空字符串
+func (cm *ChromeManager) slots() []string {
+	if cm.IsRemote() {
+		return cm.config.RemoteEndpoints
+	}
+	return make([]string, cm.poolSize())
+}
+
+// pick 选择接收任务的浏览器：远程后端在各地址间轮询，本地进程选择负载最低的
+func (cm *ChromeManager) pick() *browserInstance {
+	if cm.IsRemote() {
+		return pickRoundRobin(cm.browsers, &cm.cursor)
+	}
+	return pickLeastLoaded(cm.browsers)
+}
+
+// Start 启动Chrome进程池
 func (cm *ChromeManager) Start() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -127,39 +218,29 @@ func (cm *ChromeManager) Start() error {
 		return nil
 	}
 
-	log.Printf("[CHROME-MANAGER] 启动Chrome实例...")
+	log.Printf("[CHROME-MANAGER] 启动Chrome进程池，进程数: %d...", cm.poolSize())
 	startTime := time.Now()
 
-	// 获取Chrome可执行文件路径
-	chromeDownloader := utils.NewChromeDownloader()
-	execPath := chromeDownloader.GetChromeExecutablePath()
-
-	// 构建Chrome选项
-	opts := make([]chromedp.ExecAllocatorOption, len(cm.config.ChromeOptions))
-	copy(opts, cm.config.ChromeOptions)
-
-	// 如果有自定义Chrome路径，添加到选项中
-	if execPath != "" {
-		log.Printf("[CHROME-MANAGER] 使用Chrome路径: %s", execPath)
-		opts = append(opts, chromedp.ExecPath(execPath))
+	// 至少一个浏览器启动成功即可提供服务，其余由监控补齐
+	var lastErr error
+	for _, endpoint := range cm.slots() {
+		browser, err := cm.launch(endpoint)
+		if err != nil {
+			log.Printf("[CHROME-MANAGER] %v", err)
+			lastErr = err
+			continue
+		}
+		cm.browsers = append(cm.browsers, browser)
 	}
-
-	// 创建分配器上下文
-	cm.allocCtx, cm.allocCancel = chromedp.NewExecAllocator(context.Background(), opts...)
-
-	// 创建Chrome上下文
-	cm.ctx, cm.cancel = chromedp.NewContext(cm.allocCtx)
-
-	// 验证Chrome实例
-	if err := cm.validateChrome(); err != nil {
-		cm.cleanup()
-		return fmt.Errorf("Chrome实例验证失败: %v", err)
+	if len(cm.browsers) == 0 {
+		return lastErr
 	}
 
 	// 更新状态
 	atomic.StoreInt32(&cm.isRunning, 1)
 	cm.startTime = time.Now()
 	cm.lastUsed = time.Now()
+	cm.stopCh = make(chan struct{})
 
 	// 更新统计
 	cm.stats.mu.Lock()
@@ -168,76 +249,102 @@ func (cm *ChromeManager) Start() error {
 	cm.stats.mu.Unlock()
 
 	duration := time.Since(startTime)
-	log.Printf("[CHROME-MANAGER] Chrome实例启动成功，耗时: %v", duration)
+	log.Printf("[CHROME-MANAGER] Chrome进程池启动成功，%d个浏览器，耗时: %v", len(cm.browsers), duration)
 
-	// 启动空闲检查
-	go cm.startIdleMonitor()
+	// 启动空闲和回收检查
+	go cm.startIdleMonitor(cm.stopCh)
 
 	return nil
 }
 
-// validateChrome 验证Chrome实例
-func (cm *ChromeManager) validateChrome() error {
-	// 创建独立的验证上下文，避免影响主上下文
-	testCtx, testCancel := chromedp.NewContext(cm.allocCtx)
-	defer testCancel()
+// execPath 获取Chrome可执行文件路径
+func (cm *ChromeManager) execPath() string {
+	return utils.NewChromeDownloader().GetChromeExecutablePath()
+}
 
-	ctx, cancel := context.WithTimeout(testCtx, cm.config.StartupTimeout)
-	defer cancel()
+// allocatorOptions 构建Chrome启动选项
+func (cm *ChromeManager) allocatorOptions() []chromedp.ExecAllocatorOption {
+	// 获取Chrome可执行文件路径
+	execPath := cm.execPath()
 
-	// 简单的验证操作
-	var result string
-	err := chromedp.Run(ctx,
-		chromedp.Evaluate(`navigator.userAgent`, &result),
-	)
+	// 构建Chrome选项
+	opts := make([]chromedp.ExecAllocatorOption, len(cm.config.ChromeOptions))
+	copy(opts, cm.config.ChromeOptions)
 
-	if err != nil {
-		return fmt.Errorf("Chrome验证失败: %v", err)
+	// 如果有自定义Chrome路径，添加到选项中
+	if execPath != "" {
+		log.Printf("[CHROME-MANAGER] 使用Chrome路径: %s", execPath)
+		opts = append(opts, chromedp.ExecPath(execPath))
 	}
+	return opts
+}
 
-	log.Printf("[CHROME-MANAGER] Chrome验证成功")
-	return nil
+// launch 启动一个新的本地浏览器进程（每个进程使用独立的临时用户目录），endpoint不为空时连接远程浏览器
+func (cm *ChromeManager) launch(endpoint string) (*browserInstance, error) {
+	id := int(atomic.AddInt32(&cm.nextBrowserID, 1))
+	if endpoint != "" {
+		browser, err := connectBrowser(id, endpoint, cm.config.StartupTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("浏览器#%d（%s）: %v", id, redactEndpoint(endpoint), err)
+		}
+		logBrowser(browser, "已连接远程浏览器: %s", redactEndpoint(endpoint))
+		return browser, nil
+	}
+
+	browser, err := launchBrowser(id, cm.allocatorOptions(), cm.config.StartupTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("浏览器#%d: %v", id, err)
+	}
+	logBrowser(browser, "启动成功，PID: %d", browser.pid())
+	return browser, nil
 }
 
-// Stop 停止Chrome实例
+// Stop 停止全部Chrome实例
 func (cm *ChromeManager) Stop() {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	cm.stopLocked()
+}
+
+// stopLocked 停止全部Chrome实例，调用方持有mu
+func (cm *ChromeManager) stopLocked() {
 	if atomic.LoadInt32(&cm.isRunning) == 0 {
 		return
 	}
 
 	log.Printf("[CHROME-MANAGER] 停止Chrome实例...")
-	cm.cleanup()
+	close(cm.stopCh)
+	for _, browser := range cm.browsers {
+		browser.close()
+	}
+	cm.browsers = nil
+	cm.sessions = make(map[string]*browserSession)
 	atomic.StoreInt32(&cm.isRunning, 0)
 
 	log.Printf("[CHROME-MANAGER] Chrome实例已停止")
 }
 
-// cleanup 清理资源
-func (cm *ChromeManager) cleanup() {
-	if cm.cancel != nil {
-		cm.cancel()
-		cm.cancel = nil
-	}
-
-	if cm.allocCancel != nil {
-		cm.allocCancel()
-		cm.allocCancel = nil
-	}
-
-	cm.ctx = nil
-	cm.allocCtx = nil
+// GetContext 获取Chrome上下文，每个任务使用新的隔离浏览器上下文
+func (cm *ChromeManager) GetContext(timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	return cm.GetSessionContext(timeout, BrowserContextOptions{})
 }
 
-// GetContext 获取Chrome上下文
-func (cm *ChromeManager) GetContext(timeout time.Duration) (context.Context, context.CancelFunc, error) {
+// GetSessionContext 获取Chrome上下文：Session为空时使用新的隔离浏览器上下文，任务结束后销毁，
+// Cookie、localStorage和缓存不会在任务间泄漏；不为空时同一Session的任务共享浏览器上下文，保留登录状态。
+// 所有调用方的浏览器上下文都经出站代理连接（Proxy为其上游），返回前启用请求拦截，页面请求按出站策略检查
+func (cm *ChromeManager) GetSessionContext(timeout time.Duration, opts BrowserContextOptions) (context.Context, context.CancelFunc, error) {
 	// 检查并启动Chrome
 	if err := cm.ensureRunning(); err != nil {
 		return nil, nil, fmt.Errorf("确保Chrome运行失败: %v", err)
 	}
 
+	proxyServer, proxyAuth, err := cm.contextProxy(opts.Proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.proxyServer = proxyServer
+
 	// 检查熔断器
 	if !cm.AllowRequest() {
 		return nil, nil, fmt.Errorf("熔断器开启，拒绝请求")
@@ -251,31 +358,103 @@ func (cm *ChromeManager) GetContext(timeout time.Duration) (context.Context, con
 		return nil, nil, fmt.Errorf("获取并发许可超时")
 	}
 
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	if atomic.LoadInt32(&cm.isRunning) == 0 || cm.ctx == nil {
+	browser, persisted, served, err := cm.acquireBrowser(opts)
+	if err != nil {
 		<-cm.semaphore // 释放许可
-		return nil, nil, fmt.Errorf("Chrome实例未运行")
+		return nil, nil, err
 	}
+	cm.usage.add(time.Now())
 
-	// 创建任务上下文
-	taskCtx, cancel := context.WithTimeout(cm.ctx, timeout)
+	// 每个任务使用独立标签页，避免并发任务共享页面状态和事件监听
+	// 新建的浏览器上下文在标签页关闭时由chromedp销毁
+	contextOption := chromedp.WithNewBrowserContext(withContextProxy(opts.proxyServer))
+	if persisted != nil {
+		contextOption = chromedp.WithExistingBrowserContext(persisted.id)
+	}
+	tabCtx, tabCancel := chromedp.NewContext(browser.ctx, contextOption)
+	taskCtx, timeoutCancel := context.WithTimeout(tabCtx, timeout)
+	cancel := func() {
+		timeoutCancel()
+		tabCancel()
+	}
 
 	// 增加当前任务计数
 	atomic.AddInt32(&cm.currentTasks, 1)
-	cm.lastUsed = time.Now()
 
 	// 包装cancel函数
 	wrappedCancel := func() {
 		cancel()
-		atomic.AddInt32(&cm.currentTasks, -1)
+		if persisted != nil {
+			cm.releaseSession(persisted)
+		}
+		atomic.AddInt32(&browser.active, -1)
+		remaining := atomic.AddInt32(&cm.currentTasks, -1)
 		<-cm.semaphore // 释放许可
+		if remaining == 0 && cm.config.Mode == ChromeModeCold {
+			go cm.stopIfIdle()
+		}
+	}
+
+	// 达到任务上限的浏览器排空后回收，当前任务不受影响
+	if limit := cm.config.MaxTasksPerBrowser; limit > 0 && served >= int64(limit) && !browser.remote() {
+		go cm.recycle(browser, fmt.Sprintf("已处理%d个任务", served))
+	}
+
+	// 请求拦截对所有调用方生效，在调用方导航之前启用
+	interceptor := opts.Interceptor
+	if interceptor == nil {
+		interceptor = &requestInterceptor{guard: newNavigationGuard(cm.guardProxies.policy)}
+	}
+	interceptor.proxy = proxyAuth
+	if err := chromedp.Run(taskCtx, interceptor.action()); err != nil {
+		wrappedCancel()
+		return nil, nil, fmt.Errorf("启用请求拦截失败: %v", err)
 	}
 
 	return taskCtx, wrappedCancel, nil
 }
 
+// contextProxy 浏览器上下文使用的代理服务器，以及浏览器连接它时需要的凭据（不需要认证时为nil）
+// 出站代理监听在本进程中，远程浏览器的回环地址访问不到；远程模式下未配置可访问的主机名时浏览器直接连接上游代理，
+// 出站检查只由Fetch拦截完成，WebSocket等Fetch看不到的连接不受出站代理保护
+func (cm *ChromeManager) contextProxy(upstream *ScreenshotProxy) (string, *ScreenshotProxy, error) {
+	if cm.IsRemote() && cm.guardProxies.advertise == "" {
+		if upstream == nil {
+			return "", nil, nil
+		}
+		if upstream.Scheme == "socks5" && upstream.needsAuth() {
+			return "", nil, fmt.Errorf("远程浏览器不支持SOCKS5代理认证，请配置CHROME_GUARD_PROXY_ADVERTISE")
+		}
+		if !upstream.needsAuth() {
+			return upstream.Server, nil, nil
+		}
+		return upstream.Server, upstream, nil
+	}
+
+	proxy, err := cm.guardProxies.proxyFor(upstream)
+	if err != nil {
+		return "", nil, err
+	}
+	return proxy.URL(), proxy.credentials(), nil
+}
+
+// serverIPLookup 返回HAR记录服务器IP使用的查询函数，与contextProxy选择的代理一致：
+// 经出站代理时查询代理实际连接的IP；远程浏览器直接连接上游代理时无法得知目标IP，始终返回空；不经代理时返回nil，使用Chrome报告的地址
+func (cm *ChromeManager) serverIPLookup(upstream *ScreenshotProxy) func(address string) string {
+	if cm.IsRemote() && cm.guardProxies.advertise == "" {
+		if upstream == nil {
+			return nil
+		}
+		return func(string) string { return "" }
+	}
+
+	proxy, err := cm.guardProxies.proxyFor(upstream)
+	if err != nil {
+		return func(string) string { return "" }
+	}
+	return proxy.dialedIP
+}
+
 // ensureRunning 确保Chrome正在运行
 func (cm *ChromeManager) ensureRunning() error {
 	if atomic.LoadInt32(&cm.isRunning) == 1 && cm.isHealthy() {
@@ -291,21 +470,135 @@ func (cm *ChromeManager) ensureRunning() error {
 	return cm.Start()
 }
 
-// isHealthy 检查Chrome健康状态
+// isHealthy 检查Chrome健康状态，至少有一个浏览器可以接收任务
 func (cm *ChromeManager) isHealthy() bool {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	if cm.ctx == nil {
-		return false
+	for _, browser := range cm.browsers {
+		if browser.available() {
+			return true
+		}
+	}
+	return false
+}
+
+// recycle 排空并回收浏览器：先启动替换进程，再等待进行中的任务结束后关闭
+func (cm *ChromeManager) recycle(browser *browserInstance, reason string) {
+	if !cm.retire(browser, reason) {
+		return
 	}
+	if err := cm.replenish(); err != nil {
+		log.Printf("[CHROME-MANAGER] 补充浏览器失败: %v", err)
+	}
+	cm.drainAndClose(browser)
+}
 
-	// 检查上下文是否被取消
-	select {
-	case <-cm.ctx.Done():
+// retire 标记浏览器不再接收新任务，返回false表示已在回收中
+func (cm *ChromeManager) retire(browser *browserInstance, reason string) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if !browser.startDraining() {
 		return false
-	default:
-		return true
+	}
+	logBrowser(browser, "%s，开始排空回收", reason)
+	return true
+}
+
+// replenish 补齐可用浏览器到进程池大小，远程后端重新连接断开的地址
+func (cm *ChromeManager) replenish() error {
+	cm.poolMu.Lock()
+	defer cm.poolMu.Unlock()
+
+	if atomic.LoadInt32(&cm.isRunning) == 0 {
+		return nil
+	}
+
+	// 按地址统计可用浏览器，本地进程的地址都为空
+	cm.mu.RLock()
+	available := make(map[string]int)
+	for _, browser := range cm.browsers {
+		if browser.available() {
+			available[browser.endpoint]++
+		}
+	}
+	cm.mu.RUnlock()
+
+	var lastErr error
+	for _, endpoint := range cm.slots() {
+		if available[endpoint] > 0 {
+			available[endpoint]--
+			continue
+		}
+		browser, err := cm.launch(endpoint)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		cm.mu.Lock()
+		if atomic.LoadInt32(&cm.isRunning) == 0 {
+			cm.mu.Unlock()
+			browser.close()
+			return nil
+		}
+		cm.browsers = append(cm.browsers, browser)
+		cm.mu.Unlock()
+	}
+	return lastErr
+}
+
+// drainAndClose 等待浏览器上的任务结束后关闭并移出进程池
+func (cm *ChromeManager) drainAndClose(browser *browserInstance) {
+	if !browser.waitIdle(cm.config.DrainTimeout) {
+		logBrowser(browser, "排空超时，强制关闭，未完成任务: %d", atomic.LoadInt32(&browser.active))
+	}
+	browser.close()
+
+	cm.mu.Lock()
+	cm.dropSessions(browser)
+	for i, b := range cm.browsers {
+		if b == browser {
+			cm.browsers = append(cm.browsers[:i], cm.browsers[i+1:]...)
+			break
+		}
+	}
+	cm.mu.Unlock()
+
+	cm.stats.mu.Lock()
+	cm.stats.recycles++
+	cm.stats.mu.Unlock()
+	logBrowser(browser, "已回收，共处理任务: %d", atomic.LoadInt64(&browser.served))
+}
+
+// checkBrowsers 检查已退出、内存超限和远程健康检查失败的浏览器并回收
+func (cm *ChromeManager) checkBrowsers() {
+	cm.mu.RLock()
+	browsers := append([]*browserInstance(nil), cm.browsers...)
+	cm.mu.RUnlock()
+
+	limit := int64(cm.config.MaxBrowserMemoryMB) * 1024 * 1024
+	for _, browser := range browsers {
+		if atomic.LoadInt32(&browser.draining) == 1 {
+			continue
+		}
+		if !browser.alive() {
+			go cm.recycle(browser, "进程已退出或连接已断开")
+			continue
+		}
+		if browser.remote() {
+			// 远程浏览器无法采样内存，通过打开标签页执行脚本检查连接可用
+			go func(browser *browserInstance) {
+				if err := browser.validate(cm.config.StartupTimeout); err != nil {
+					cm.recycle(browser, fmt.Sprintf("健康检查失败: %v", err))
+				}
+			}(browser)
+			continue
+		}
+		if memory := browser.sampleMemory(); limit > 0 && memory > limit {
+			go cm.recycle(browser, fmt.Sprintf("内存%dMB超过上限", memory/(1024*1024)))
+		}
 	}
 }
 
@@ -360,6 +653,7 @@ func (cm *ChromeManager) GetStats() map[string]interface{} {
 	}
 
 	stats := map[string]interface{}{
+		"mode":             cm.config.Mode.String(),
 		"is_running":       atomic.LoadInt32(&cm.isRunning) == 1,
 		"is_healthy":       cm.isHealthy(),
 		"current_tasks":    atomic.LoadInt32(&cm.currentTasks),
@@ -371,32 +665,58 @@ func (cm *ChromeManager) GetStats() map[string]interface{} {
 		"success_rate":     successRate,
 		"avg_duration_ms":  avgDuration.Milliseconds(),
 		"restarts":         cm.stats.restarts,
+		"recycles":         cm.stats.recycles,
 		"last_restart":     cm.stats.lastRestart.Format(time.RFC3339),
 		"uptime_seconds":   time.Since(cm.startTime).Seconds(),
+		"pool_size":        cm.poolSize(),
+		"remote":           cm.IsRemote(),
+		"browsers":         cm.browserStats(),
+		"sessions":         cm.sessionCount(),
 	}
 
 	return stats
 }
 
-// startIdleMonitor 启动空闲监控
-func (cm *ChromeManager) startIdleMonitor() {
-	if cm.config.IdleTimeout <= 0 {
-		return
+// browserStats 各浏览器的统计信息
+func (cm *ChromeManager) browserStats() []map[string]interface{} {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	stats := make([]map[string]interface{}, 0, len(cm.browsers))
+	for _, browser := range cm.browsers {
+		stats = append(stats, browser.stats())
 	}
+	return stats
+}
 
+// sessionCount 当前保留的持久会话数
+func (cm *ChromeManager) sessionCount() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return len(cm.sessions)
+}
+
+// startIdleMonitor 启动空闲监控，同时回收异常的浏览器并补齐进程池
+func (cm *ChromeManager) startIdleMonitor(stopCh chan struct{}) {
 	ticker := time.NewTicker(30 * time.Second) // 每30秒检查一次
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-stopCh:
+			return // Chrome已停止
 		case <-ticker.C:
-			if atomic.LoadInt32(&cm.isRunning) == 0 {
-				return // Chrome已停止
+			cm.checkBrowsers()
+			cm.expireSessions(PersistSessionTTL)
+			if err := cm.replenish(); err != nil {
+				log.Printf("[CHROME-MANAGER] 补充浏览器失败: %v", err)
 			}
+			cm.checkHealth()
 
 			// 检查是否空闲超时
-			if time.Since(cm.lastUsed) > cm.config.IdleTimeout && atomic.LoadInt32(&cm.currentTasks) == 0 {
-				log.Printf("[CHROME-MANAGER] 空闲超时，自动停止Chrome")
+			if idle := cm.idleTimeout(); idle > 0 && time.Since(cm.lastUsed) > idle && atomic.LoadInt32(&cm.currentTasks) == 0 {
+				log.Printf("[CHROME-MANAGER] 空闲超时(%v)，自动停止Chrome", idle)
 				cm.Stop()
 				return
 			}
@@ -404,12 +724,42 @@ func (cm *ChromeManager) startIdleMonitor() {
 	}
 }
 
-// Restart 重启Chrome实例
+// Restart 滚动重启：先启动新的浏览器，旧浏览器排空进行中的任务后关闭
 func (cm *ChromeManager) Restart() error {
-	log.Printf("[CHROME-MANAGER] 重启Chrome实例...")
-	cm.Stop()
-	time.Sleep(2 * time.Second) // 等待完全关闭
-	return cm.Start()
+	if atomic.LoadInt32(&cm.isRunning) == 0 {
+		return cm.Start()
+	}
+
+	log.Printf("[CHROME-MANAGER] 滚动重启Chrome进程池...")
+	cm.mu.RLock()
+	browsers := append([]*browserInstance(nil), cm.browsers...)
+	cm.mu.RUnlock()
+
+	retired := make([]*browserInstance, 0, len(browsers))
+	for _, browser := range browsers {
+		if cm.retire(browser, "手动重启") {
+			retired = append(retired, browser)
+		}
+	}
+
+	err := cm.replenish()
+	for _, browser := range retired {
+		go cm.drainAndClose(browser)
+	}
+	if err != nil {
+		return fmt.Errorf("启动新的Chrome实例失败: %v", err)
+	}
+
+	cm.stats.mu.Lock()
+	cm.stats.restarts++
+	cm.stats.lastRestart = time.Now()
+	cm.stats.mu.Unlock()
+	return nil
+}
+
+// MaxConcurrent 最大并发任务数
+func (cm *ChromeManager) MaxConcurrent() int {
+	return cm.maxConcurrent
 }
 
 // IsHealthy 对外接口 - 检查Chrome实例是否健康
@@ -0,0 +1,141 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome运行模式 - 冷启动、预热和自动模式下的启动时机与空闲回收策略
+ */
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChromeMode Chrome运行模式
+type ChromeMode int
+
+const (
+	ChromeModeAuto ChromeMode = iota // 自动模式（按需启动，根据使用频率调整空闲回收时间）
+	ChromeModeCold                   // 冷启动模式（按需启动，任务全部结束后立即关闭）
+	ChromeModeWarm                   // 预热模式（服务启动时预先启动，不做空闲回收）
+)
+
+// 自动模式的使用频率判断
+const (
+	usageWindowMinutes = 10 // 统计最近10分钟的任务数
+	frequentUsageTasks = 5  // 超过该任务数视为频繁使用
+)
+
+// ParseChromeMode 解析CHROME_MODE配置，未知值使用自动模式
+func ParseChromeMode(mode string) ChromeMode {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "cold", "冷启动":
+		return ChromeModeCold
+	case "warm", "预热", "热启动":
+		return ChromeModeWarm
+	case "", "auto", "自动":
+		return ChromeModeAuto
+	default:
+		log.Printf("[CHROME-MANAGER] 未知模式 '%s'，使用自动模式", mode)
+		return ChromeModeAuto
+	}
+}
+
+// String 模式名称
+func (m ChromeMode) String() string {
+	switch m {
+	case ChromeModeCold:
+		return "cold"
+	case ChromeModeWarm:
+		return "warm"
+	default:
+		return "auto"
+	}
+}
+
+// Description 模式描述
+func (m ChromeMode) Description() string {
+	switch m {
+	case ChromeModeCold:
+		return "冷启动模式 - 按需启动，节省资源"
+	case ChromeModeWarm:
+		return "预热模式 - 预先启动，快速响应"
+	default:
+		return "自动模式 - 智能管理，平衡性能和资源"
+	}
+}
+
+// usageWindow 按分钟统计最近一段时间的任务数
+type usageWindow struct {
+	mu      sync.Mutex
+	counts  [usageWindowMinutes]int
+	minutes [usageWindowMinutes]int64
+}
+
+func (w *usageWindow) add(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	minute := now.Unix() / 60
+	i := minute % usageWindowMinutes
+	if w.minutes[i] != minute {
+		w.minutes[i] = minute
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+}
+
+func (w *usageWindow) count(now time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	minute := now.Unix() / 60
+	total := 0
+	for i, m := range w.minutes {
+		if minute-m < usageWindowMinutes {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
+
+// Prewarm 是否在服务启动时预先启动Chrome
+func (cm *ChromeManager) Prewarm() bool {
+	return cm.config.Mode == ChromeModeWarm
+}
+
+// idleTimeout 当前的空闲回收时间，0表示不回收
+// 自动模式下频繁使用时延长一倍，偶尔使用时缩短一半
+func (cm *ChromeManager) idleTimeout() time.Duration {
+	switch cm.config.Mode {
+	case ChromeModeWarm:
+		return 0
+	case ChromeModeAuto:
+		if cm.usage.count(time.Now()) > frequentUsageTasks {
+			return cm.config.IdleTimeout * 2
+		}
+		return cm.config.IdleTimeout / 2
+	default:
+		return cm.config.IdleTimeout
+	}
+}
+
+// stopIfIdle 冷启动模式下任务全部结束后关闭Chrome
+// 新任务在持有mu时增加浏览器计数，这里在同一把锁内确认没有进行中的任务
+func (cm *ChromeManager) stopIfIdle() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if atomic.LoadInt32(&cm.isRunning) == 0 {
+		return
+	}
+	for _, browser := range cm.browsers {
+		if atomic.LoadInt32(&browser.active) > 0 {
+			return
+		}
+	}
+	log.Printf("[CHROME-MANAGER] 冷启动模式：任务已全部结束，关闭Chrome")
+	cm.stopLocked()
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestParseChromeMode 测试运行模式解析
+func TestParseChromeMode(t *testing.T) {
+	cases := map[string]ChromeMode{
+		"":      ChromeModeAuto,
+		"auto":  ChromeModeAuto,
+		"COLD":  ChromeModeCold,
+		" warm": ChromeModeWarm,
+		"预热":    ChromeModeWarm,
+		"bogus": ChromeModeAuto,
+	}
+	for input, expected := range cases {
+		if mode := ParseChromeMode(input); mode != expected {
+			t.Errorf("%q: 期望 %s, 实际 %s", input, expected, mode)
+		}
+	}
+}
+
+// TestUsageWindow 测试只统计最近10分钟的任务
+func TestUsageWindow(t *testing.T) {
+	var w usageWindow
+	now := time.Now()
+	w.add(now.Add(-15 * time.Minute))
+	w.add(now.Add(-5 * time.Minute))
+	w.add(now)
+	w.add(now)
+	if count := w.count(now); count != 3 {
+		t.Errorf("最近10分钟任务数应为3: %d", count)
+	}
+	if count := w.count(now.Add(20 * time.Minute)); count != 0 {
+		t.Errorf("20分钟后任务数应为0: %d", count)
+	}
+}
+
+// TestIdleTimeout 测试各模式的空闲回收时间
+func TestIdleTimeout(t *testing.T) {
+	newManager := func(mode ChromeMode) *ChromeManager {
+		return NewChromeManager(&ChromeManagerConfig{Mode: mode, MaxConcurrent: 1, IdleTimeout: 4 * time.Minute})
+	}
+
+	if idle := newManager(ChromeModeWarm).idleTimeout(); idle != 0 {
+		t.Errorf("预热模式不应空闲回收: %v", idle)
+	}
+	if idle := newManager(ChromeModeCold).idleTimeout(); idle != 4*time.Minute {
+		t.Errorf("冷启动模式应使用配置的空闲时间: %v", idle)
+	}
+
+	auto := newManager(ChromeModeAuto)
+	if idle := auto.idleTimeout(); idle != 2*time.Minute {
+		t.Errorf("偶尔使用时空闲时间应缩短一半: %v", idle)
+	}
+	for i := 0; i <= frequentUsageTasks; i++ {
+		auto.usage.add(time.Now())
+	}
+	if idle := auto.idleTimeout(); idle != 8*time.Minute {
+		t.Errorf("频繁使用时空闲时间应延长一倍: %v", idle)
+	}
+}
+
+// TestStopIfIdle 测试冷启动模式只在没有进行中的任务时关闭
+func TestStopIfIdle(t *testing.T) {
+	cm := NewChromeManager(&ChromeManagerConfig{Mode: ChromeModeCold, MaxConcurrent: 1})
+	b := &browserInstance{id: 1, active: 1}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	cm.browsers = []*browserInstance{b}
+	cm.isRunning = 1
+	cm.stopCh = make(chan struct{})
+
+	cm.stopIfIdle()
+	if cm.isRunning != 1 || !b.alive() {
+		t.Fatal("有进行中的任务时不应关闭")
+	}
+
+	b.active = 0
+	cm.stopIfIdle()
+	if cm.isRunning != 0 || b.alive() || len(cm.browsers) != 0 {
+		t.Error("任务全部结束后应关闭Chrome")
+	}
+}
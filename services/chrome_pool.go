@@ -0,0 +1,295 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome浏览器进程池 - 单个浏览器实例的启动或远程连接、任务计数、内存统计和排空回收
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// browserInstance 进程池中的一个浏览器进程
+type browserInstance struct {
+	id          int
+	endpoint    string // 远程DevTools地址，本地进程为空
+	allocCtx    context.Context
+	allocCancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	startedAt   time.Time
+
+	active   int32 // 进行中的任务数
+	served   int64 // 启动以来分配的任务数
+	draining int32 // 排空中，不再接收新任务
+	memory   int64 // 最近一次采样的进程树内存（字节）
+}
+
+// launchBrowser 启动一个本地浏览器进程并验证可用
+func launchBrowser(id int, opts []chromedp.ExecAllocatorOption, startupTimeout time.Duration) (*browserInstance, error) {
+	b := &browserInstance{id: id}
+	b.allocCtx, b.allocCancel = chromedp.NewExecAllocator(context.Background(), opts...)
+	return b, b.start(startupTimeout)
+}
+
+// connectBrowser 连接远程DevTools地址并验证可用
+// 地址可以是 ws://host:9222/devtools/browser/<id>，也可以是 http://host:9222 或 ws://host:9222，后两者通过/json/version解析
+func connectBrowser(id int, endpoint string, startupTimeout time.Duration) (*browserInstance, error) {
+	b := &browserInstance{id: id, endpoint: endpoint}
+	b.allocCtx, b.allocCancel = chromedp.NewRemoteAllocator(context.Background(), endpoint)
+	return b, b.start(startupTimeout)
+}
+
+// start 分配浏览器并验证，失败时释放资源
+func (b *browserInstance) start(timeout time.Duration) error {
+	b.ctx, b.cancel = chromedp.NewContext(b.allocCtx)
+
+	// 立即分配浏览器，后续任务在该浏览器中打开新标签页
+	// 注意不能带超时，首次Run的上下文取消会关闭浏览器，这里单独等待超时
+	done := make(chan error, 1)
+	go func() {
+		done <- chromedp.Run(b.ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			b.close()
+			return fmt.Errorf("Chrome实例启动失败: %v", err)
+		}
+	case <-time.After(timeout):
+		b.close()
+		return fmt.Errorf("Chrome实例启动超时（%v）", timeout)
+	}
+
+	if err := b.validate(timeout); err != nil {
+		b.close()
+		return fmt.Errorf("Chrome实例验证失败: %v", err)
+	}
+
+	b.startedAt = time.Now()
+	return nil
+}
+
+// validate 在新标签页中执行简单脚本，避免影响浏览器主上下文
+func (b *browserInstance) validate(timeout time.Duration) error {
+	testCtx, testCancel := chromedp.NewContext(b.ctx)
+	defer testCancel()
+
+	ctx, cancel := context.WithTimeout(testCtx, timeout)
+	defer cancel()
+
+	var result string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`navigator.userAgent`, &result)); err != nil {
+		return fmt.Errorf("Chrome验证失败: %v", err)
+	}
+	return nil
+}
+
+// close 关闭浏览器进程
+func (b *browserInstance) close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	if b.allocCancel != nil {
+		b.allocCancel()
+	}
+}
+
+// alive 浏览器上下文未被取消（进程未退出）
+func (b *browserInstance) alive() bool {
+	select {
+	case <-b.ctx.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// remote 是否为远程浏览器
+func (b *browserInstance) remote() bool {
+	return b.endpoint != ""
+}
+
+// available 可以接收新任务
+func (b *browserInstance) available() bool {
+	return atomic.LoadInt32(&b.draining) == 0 && b.alive()
+}
+
+// startDraining 标记为排空状态，返回false表示已在排空中
+func (b *browserInstance) startDraining() bool {
+	return atomic.CompareAndSwapInt32(&b.draining, 0, 1)
+}
+
+// waitIdle 等待进行中的任务结束，超时后返回false
+func (b *browserInstance) waitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt32(&b.active) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return true
+}
+
+// pid 浏览器主进程ID，远程浏览器返回0
+func (b *browserInstance) pid() int {
+	c := chromedp.FromContext(b.ctx)
+	if c == nil || c.Browser == nil || c.Browser.Process() == nil {
+		return 0
+	}
+	return c.Browser.Process().Pid
+}
+
+// sampleMemory 采样浏览器进程树的常驻内存，不支持/proc的系统返回0
+func (b *browserInstance) sampleMemory() int64 {
+	memory := processTreeRSS(b.pid())
+	atomic.StoreInt64(&b.memory, memory)
+	return memory
+}
+
+// stats 单个浏览器的统计信息
+func (b *browserInstance) stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"id":             b.id,
+		"pid":            b.pid(),
+		"healthy":        b.alive(),
+		"draining":       atomic.LoadInt32(&b.draining) == 1,
+		"active_tasks":   atomic.LoadInt32(&b.active),
+		"served_tasks":   atomic.LoadInt64(&b.served),
+		"memory_mb":      atomic.LoadInt64(&b.memory) / (1024 * 1024),
+		"uptime_seconds": time.Since(b.startedAt).Seconds(),
+	}
+	if b.remote() {
+		stats["endpoint"] = redactEndpoint(b.endpoint)
+	}
+	return stats
+}
+
+// pickLeastLoaded 选择进行中任务最少的可用浏览器，任务数相同时选择处理过任务较少的
+func pickLeastLoaded(browsers []*browserInstance) *browserInstance {
+	var best *browserInstance
+	for _, b := range browsers {
+		if !b.available() {
+			continue
+		}
+		if best == nil {
+			best = b
+			continue
+		}
+		active, bestActive := atomic.LoadInt32(&b.active), atomic.LoadInt32(&best.active)
+		if active < bestActive || (active == bestActive && atomic.LoadInt64(&b.served) < atomic.LoadInt64(&best.served)) {
+			best = b
+		}
+	}
+	return best
+}
+
+// pickRoundRobin 从游标位置开始轮询选择可用浏览器，用于远程后端在各地址间均匀分配
+func pickRoundRobin(browsers []*browserInstance, cursor *uint32) *browserInstance {
+	n := len(browsers)
+	if n == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(cursor, 1) - 1)
+	for i := 0; i < n; i++ {
+		if b := browsers[(start+i)%n]; b.available() {
+			return b
+		}
+	}
+	return nil
+}
+
+// redactEndpoint 隐藏远程地址中的凭据和查询参数（如token）
+func redactEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "invalid"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// processTreeRSS 统计进程及其全部子进程（渲染、GPU等）的常驻内存
+func processTreeRSS(root int) int64 {
+	if root <= 0 {
+		return 0
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if ppid := parentPID(pid); ppid > 0 {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+
+	pageSize := int64(os.Getpagesize())
+	var total int64
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		total += residentPages(pid) * pageSize
+		queue = append(queue, children[pid]...)
+	}
+	return total
+}
+
+// parentPID 从/proc/<pid>/stat读取父进程ID，进程名可能包含空格和括号，从最后一个右括号之后解析
+func parentPID(pid int) int {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(string(fields[1]))
+	return ppid
+}
+
+// residentPages 从/proc/<pid>/statm读取常驻内存页数
+func residentPages(pid int) int64 {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "statm"))
+	if err != nil {
+		return 0
+	}
+	fields := bytes.Fields(data)
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+	return pages
+}
+
+// logBrowser 统一的浏览器日志前缀
+func logBrowser(b *browserInstance, format string, args ...interface{}) {
+	log.Printf("[CHROME-MANAGER] 浏览器#%d: %s", b.id, fmt.Sprintf(format, args...))
+}
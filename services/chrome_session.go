@@ -0,0 +1,223 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome浏览器上下文隔离 - 每个任务使用独立的浏览器上下文，persist_session会话在任务间共享登录状态
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// 持久会话参数限制
+const (
+	MaxPersistSessions = 50               // 同时保留的会话数，超出时淘汰最久未使用的空闲会话
+	PersistSessionTTL  = 30 * time.Minute // 空闲超过该时间的会话被销毁
+)
+
+// persistSessionPattern 会话键相当于访问凭据，要求足够长以避免被猜测
+var persistSessionPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{16,64}$`)
+
+// browserSession persist_session对应的浏览器上下文，Cookie、localStorage和缓存在同一会话的任务间保留
+type browserSession struct {
+	key       string
+	browser   *browserInstance
+	id        cdp.BrowserContextID
+	proxy     string // 创建上下文时使用的代理服务器，会话内不能更换
+	createdAt time.Time
+	lastUsed  time.Time // 受ChromeManager.mu保护
+	active    int32     // 受ChromeManager.mu保护
+}
+
+// validatePersistSession 校验会话键
+func validatePersistSession(key string) error {
+	if key != "" && !persistSessionPattern.MatchString(key) {
+		return fmt.Errorf("persist_session必须是16-64位的字母、数字、下划线、点或连字符")
+	}
+	return nil
+}
+
+// sessionLabel 日志中只显示会话键前缀
+func sessionLabel(key string) string {
+	if len(key) > 6 {
+		return key[:6] + "..."
+	}
+	return key
+}
+
+// BrowserContextOptions 任务浏览器上下文的选项
+type BrowserContextOptions struct {
+	Session     string              // 持久会话键，为空时使用任务结束后销毁的隔离上下文
+	Proxy       *ScreenshotProxy    // 上游命名代理，为nil时直连
+	Interceptor *requestInterceptor // 请求拦截器，为nil时使用只做出站检查的拦截器
+
+	proxyServer string // 上下文实际使用的代理服务器，由GetSessionContext设置
+}
+
+// acquireBrowser 选择执行任务的浏览器并增加计数：持久会话固定在创建它的浏览器上，其余任务按负载选择
+func (cm *ChromeManager) acquireBrowser(opts BrowserContextOptions) (*browserInstance, *browserSession, int64, error) {
+	key := opts.Session
+	// 串行化会话创建，避免同一会话键并发创建多个浏览器上下文
+	if key != "" {
+		cm.sessionMu.Lock()
+		defer cm.sessionMu.Unlock()
+	}
+
+	// 选择和计数在写锁内完成，避免与排空回收竞争
+	cm.mu.Lock()
+	session := cm.sessions[key]
+	if session != nil && !session.browser.available() {
+		// 会话所在的浏览器正在回收，登录状态随之失效
+		log.Printf("[CHROME-MANAGER] 会话 %s 所在的浏览器#%d已回收，重新创建", sessionLabel(key), session.browser.id)
+		delete(cm.sessions, key)
+		session = nil
+	}
+	if session != nil && session.proxy != opts.proxyServer {
+		// 代理在创建浏览器上下文时确定，同一会话中途更换出口会让登录状态与来源地区不一致
+		cm.mu.Unlock()
+		return nil, nil, 0, fmt.Errorf("persist_session已使用其他代理创建，不能更换代理")
+	}
+	browser := cm.pick()
+	if session != nil {
+		browser = session.browser
+		session.active++
+		session.lastUsed = time.Now()
+	}
+	if atomic.LoadInt32(&cm.isRunning) == 0 || browser == nil {
+		cm.mu.Unlock()
+		return nil, nil, 0, fmt.Errorf("Chrome实例未运行")
+	}
+	atomic.AddInt32(&browser.active, 1)
+	served := atomic.AddInt64(&browser.served, 1)
+	cm.lastUsed = time.Now()
+	cm.mu.Unlock()
+
+	if key == "" || session != nil {
+		return browser, session, served, nil
+	}
+
+	session, err := cm.createSession(key, opts.proxyServer, browser)
+	if err != nil {
+		atomic.AddInt32(&browser.active, -1)
+		return nil, nil, 0, err
+	}
+	return browser, session, served, nil
+}
+
+// releaseSession 任务结束后更新会话的使用时间
+func (cm *ChromeManager) releaseSession(session *browserSession) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session.active--
+	session.lastUsed = time.Now()
+}
+
+// createSession 在浏览器中创建持久会话，数量达到上限时先淘汰最久未使用的空闲会话
+func (cm *ChromeManager) createSession(key, proxy string, browser *browserInstance) (*browserSession, error) {
+	cm.mu.Lock()
+	var evicted *browserSession
+	if len(cm.sessions) >= MaxPersistSessions {
+		for _, session := range cm.sessions {
+			if session.active == 0 && (evicted == nil || session.lastUsed.Before(evicted.lastUsed)) {
+				evicted = session
+			}
+		}
+		if evicted == nil {
+			cm.mu.Unlock()
+			return nil, fmt.Errorf("持久会话数已达上限（%d）", MaxPersistSessions)
+		}
+		delete(cm.sessions, evicted.key)
+	}
+	cm.mu.Unlock()
+
+	if evicted != nil {
+		log.Printf("[CHROME-MANAGER] 会话数已达上限，淘汰最久未使用的会话 %s", sessionLabel(evicted.key))
+		disposeBrowserContext(evicted.browser, evicted.id)
+	}
+
+	id, err := createBrowserContext(browser, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("创建浏览器上下文失败: %v", err)
+	}
+
+	now := time.Now()
+	session := &browserSession{key: key, browser: browser, id: id, proxy: proxy, createdAt: now, lastUsed: now, active: 1}
+	cm.mu.Lock()
+	cm.sessions[key] = session
+	cm.mu.Unlock()
+
+	logBrowser(browser, "创建持久会话 %s", sessionLabel(key))
+	return session, nil
+}
+
+// expireSessions 销毁空闲超时的持久会话
+func (cm *ChromeManager) expireSessions(ttl time.Duration) {
+	cm.mu.Lock()
+	var expired []*browserSession
+	for key, session := range cm.sessions {
+		if session.active == 0 && time.Since(session.lastUsed) > ttl {
+			expired = append(expired, session)
+			delete(cm.sessions, key)
+		}
+	}
+	cm.mu.Unlock()
+
+	for _, session := range expired {
+		log.Printf("[CHROME-MANAGER] 会话 %s 空闲超时，已销毁", sessionLabel(session.key))
+		disposeBrowserContext(session.browser, session.id)
+	}
+}
+
+// dropSessions 移除浏览器上的全部会话，浏览器关闭后上下文随之销毁，调用方持有mu
+func (cm *ChromeManager) dropSessions(browser *browserInstance) {
+	for key, session := range cm.sessions {
+		if session.browser == browser {
+			delete(cm.sessions, key)
+		}
+	}
+}
+
+// createBrowserContext 通过Target.createBrowserContext创建独立的浏览器上下文，proxy不为空时上下文的请求经该代理发出
+func createBrowserContext(b *browserInstance, proxy string) (cdp.BrowserContextID, error) {
+	c := chromedp.FromContext(b.ctx)
+	if c == nil || c.Browser == nil {
+		return "", fmt.Errorf("浏览器未就绪")
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, 10*time.Second)
+	defer cancel()
+	return withContextProxy(proxy)(target.CreateBrowserContext()).Do(cdp.WithExecutor(ctx, c.Browser))
+}
+
+// disposeBrowserContext 销毁浏览器上下文及其中的标签页，浏览器已退出时忽略
+func disposeBrowserContext(b *browserInstance, id cdp.BrowserContextID) {
+	c := chromedp.FromContext(b.ctx)
+	if c == nil || c.Browser == nil || !b.alive() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, 10*time.Second)
+	defer cancel()
+	if err := target.DisposeBrowserContext(id).Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+		logBrowser(b, "销毁浏览器上下文失败: %v", err)
+	}
+}
+
+// withContextProxy 设置浏览器上下文的代理服务器
+// "<-loopback>" 取消Chrome默认对localhost和回环地址的直连，本机地址同样经过代理，页面无法绕过代理访问本机服务
+func withContextProxy(proxy string) chromedp.CreateBrowserContextOption {
+	return func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+		if proxy == "" {
+			return p
+		}
+		return p.WithProxyServer(proxy).WithProxyBypassList("<-loopback>")
+	}
+}
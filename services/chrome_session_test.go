@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestValidatePersistSession 测试会话键格式
+func TestValidatePersistSession(t *testing.T) {
+	for _, key := range []string{"", "customer-42.login_session", strings.Repeat("a", 64)} {
+		if err := validatePersistSession(key); err != nil {
+			t.Errorf("%q 应校验通过: %v", key, err)
+		}
+	}
+	for _, key := range []string{"short", strings.Repeat("a", 65), "has space in the key!", "../../etc/passwd/abcdef"} {
+		if err := validatePersistSession(key); err == nil {
+			t.Errorf("%q 应校验失败", key)
+		}
+	}
+}
+
+// TestAcquireBrowserSession 测试持久会话固定在所属浏览器上，浏览器回收后会话失效
+func TestAcquireBrowserSession(t *testing.T) {
+	cm := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 3, PoolSize: 2})
+	cm.isRunning = 1
+	newBrowser := func(id int, active int32) *browserInstance {
+		b := &browserInstance{id: id, active: active}
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+		t.Cleanup(b.cancel)
+		return b
+	}
+	idle, busy := newBrowser(1, 0), newBrowser(2, 2)
+	cm.browsers = []*browserInstance{idle, busy}
+
+	key := "tenant-a-session-0001"
+	cm.sessions[key] = &browserSession{key: key, browser: busy, id: "ctx-1", lastUsed: time.Now()}
+
+	browser, session, _, err := cm.acquireBrowser(BrowserContextOptions{Session: key})
+	if err != nil || browser != busy || session == nil || session.active != 1 {
+		t.Fatalf("会话任务应分配到会话所在的浏览器: %v, %v", browser, err)
+	}
+	cm.releaseSession(session)
+	if session.active != 0 {
+		t.Errorf("释放后会话任务数应为0: %d", session.active)
+	}
+
+	// 会话的代理在创建时确定，不能更换
+	if _, _, _, err := cm.acquireBrowser(BrowserContextOptions{Session: key, proxyServer: "http://127.0.0.1:3128"}); err == nil {
+		t.Error("会话更换代理时应失败")
+	}
+	if session.active != 0 || busy.active != 3 {
+		t.Errorf("更换代理失败后计数不应变化: %d, %d", session.active, busy.active)
+	}
+
+	browser, session, _, err = cm.acquireBrowser(BrowserContextOptions{})
+	if err != nil || browser != idle || session != nil {
+		t.Errorf("普通任务应分配到负载最低的浏览器: %v, %v", browser, err)
+	}
+
+	// 会话所在浏览器排空后会话被丢弃，重新创建时浏览器不可用导致失败，计数应恢复
+	busy.startDraining()
+	before := idle.active
+	if _, _, _, err := cm.acquireBrowser(BrowserContextOptions{Session: key}); err == nil {
+		t.Error("浏览器未就绪时创建会话应失败")
+	}
+	if _, ok := cm.sessions[key]; ok {
+		t.Error("回收中浏览器上的会话应被移除")
+	}
+	if idle.active != before {
+		t.Errorf("创建会话失败后任务数应恢复: %d != %d", idle.active, before)
+	}
+}
+
+// TestExpireSessions 测试只销毁空闲超时的会话
+func TestExpireSessions(t *testing.T) {
+	cm := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 1})
+	browser := &browserInstance{id: 1, ctx: context.Background()}
+	old := time.Now().Add(-time.Hour)
+	cm.sessions["expired"] = &browserSession{key: "expired", browser: browser, lastUsed: old}
+	cm.sessions["in-use"] = &browserSession{key: "in-use", browser: browser, lastUsed: old, active: 1}
+	cm.sessions["recent"] = &browserSession{key: "recent", browser: browser, lastUsed: time.Now()}
+
+	cm.expireSessions(PersistSessionTTL)
+	if _, ok := cm.sessions["expired"]; ok {
+		t.Error("空闲超时的会话应被销毁")
+	}
+	if len(cm.sessions) != 2 {
+		t.Errorf("使用中和最近使用的会话应保留: %d", len(cm.sessions))
+	}
+}
@@ -7,6 +7,7 @@ package services
 
 import (
 	"log"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -14,14 +15,20 @@ import (
 
 // ServiceContainer 服务容器，管理所有服务组件
 type ServiceContainer struct {
-	RedisClient      *redis.Client
-	WorkerPool       *WorkerPool
-	WhoisManager     *WhoisManager
-	DNSChecker       *DNSChecker
-	ScreenshotChecker *ScreenshotChecker
-	ITDogChecker     *ITDogChecker
-	HealthChecker    *HealthChecker
-	Limiter          *RateLimiter
+	RedisClient        *redis.Client
+	WorkerPool         *WorkerPool
+	WhoisManager       *WhoisManager
+	DNSChecker         *DNSChecker
+	ScreenshotChecker  *ScreenshotChecker
+	ITDogChecker       *ITDogChecker
+	HealthChecker      *HealthChecker
+	Limiter            *RateLimiter
+	SubdomainDiscovery *SubdomainDiscovery
+	ScreenshotJobs     *ScreenshotScheduler
+	ScreenshotJanitor  *ScreenshotJanitor
+
+	screenshotOnce    sync.Once
+	screenshotService *ScreenshotService
 }
 
 // NewServiceContainer 创建新的服务容器
@@ -47,6 +54,9 @@ func NewServiceContainer(redisClient *redis.Client, workerPoolSize int) *Service
 	// 初始化ITDog检查器
 	container.ITDogChecker = NewITDogChecker()
 
+	// 初始化子域名发现服务（数据源在main中注册）
+	container.SubdomainDiscovery = NewSubdomainDiscovery(redisClient)
+
 	return container
 }
 
@@ -59,6 +69,26 @@ func (sc *ServiceContainer) InitializeHealthChecker() {
 	go sc.HealthChecker.ForceRefresh()
 }
 
+// ScreenshotService 返回共享的截图服务实例，路由、调度器和清理服务共用同一存储与配置
+func (sc *ServiceContainer) ScreenshotService() *ScreenshotService {
+	sc.screenshotOnce.Do(func() {
+		sc.screenshotService = NewScreenshotService(GetGlobalChromeManager(), sc.RedisClient, nil)
+	})
+	return sc.screenshotService
+}
+
+// InitializeScreenshotScheduler 初始化定时截图调度器
+func (sc *ServiceContainer) InitializeScreenshotScheduler() {
+	sc.ScreenshotJobs = NewScreenshotScheduler(sc.ScreenshotService(), sc.RedisClient)
+	sc.ScreenshotJobs.Start()
+}
+
+// InitializeScreenshotJanitor 初始化截图文件清理服务
+func (sc *ServiceContainer) InitializeScreenshotJanitor() {
+	sc.ScreenshotJanitor = NewScreenshotJanitor(sc.ScreenshotService(), sc.RedisClient, janitorConfigFromEnv())
+	sc.ScreenshotJanitor.Start()
+}
+
 // InitializeLimiter 初始化限流器
 func (sc *ServiceContainer) InitializeLimiter(key string, rate int, period time.Duration) {
 	sc.Limiter = NewRateLimiter(sc.RedisClient, key, rate, period)
@@ -78,6 +108,18 @@ func (sc *ServiceContainer) Shutdown() {
 		sc.HealthChecker.Stop()
 	}
 
+	// 停止定时截图调度
+	if sc.ScreenshotJobs != nil {
+		log.Println("停止定时截图调度...")
+		sc.ScreenshotJobs.Stop()
+	}
+
+	// 停止截图文件清理
+	if sc.ScreenshotJanitor != nil {
+		log.Println("停止截图文件清理...")
+		sc.ScreenshotJanitor.Stop()
+	}
+
 	// 关闭 Redis 客户端
 	if sc.RedisClient != nil {
 		log.Println("关闭 Redis 客户端...")
@@ -0,0 +1,325 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 域名报告渲染服务 - 使用内置模板生成HTML，并通过Chrome打印为PDF
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"whosee/types"
+	"whosee/utils"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed templates/report.html
+var reportTemplateSource string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateSource))
+
+// ReportFormat 报告文件格式
+type ReportFormat string
+
+const (
+	ReportFormatHTML ReportFormat = "html"
+	ReportFormatPDF  ReportFormat = "pdf"
+)
+
+// ContentType 报告文件的MIME类型
+func (f ReportFormat) ContentType() string {
+	if contentType := mime.TypeByExtension("." + string(f)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// ReportFile 渲染后的报告文件
+type ReportFile struct {
+	Domain      string       `json:"domain"`
+	Format      ReportFormat `json:"format"`
+	FileURL     string       `json:"file_url"` // 预签名URL；本地存储的报告不公开，由接口填入需要认证的下载地址
+	FileKey     string       `json:"file_key"` // 文件存储键，缓存命中时据此重新生成访问URL
+	Size        int64        `json:"size"`
+	GeneratedAt string       `json:"generated_at"`
+	FromCache   bool         `json:"from_cache"`
+}
+
+// reportTemplateData 模板数据，分节按名称展开便于模板引用
+type reportTemplateData struct {
+	*types.DomainReport
+	Whois         *types.ReportSection
+	RDAP          *types.ReportSection
+	DNS           *types.ReportSection
+	Screenshot    *types.ReportSection
+	ScreenshotURI template.URL
+}
+
+// ReportRenderer 报告渲染服务，报告文件保存在截图文件存储的reports目录
+type ReportRenderer struct {
+	chromeManager *ChromeManager
+	redisClient   *redis.Client
+	config        *ScreenshotServiceConfig
+	storage       Storage
+}
+
+// NewReportRenderer 创建报告渲染服务
+func NewReportRenderer(chromeManager *ChromeManager, redisClient *redis.Client, config *ScreenshotServiceConfig) *ReportRenderer {
+	if config == nil {
+		config = DefaultScreenshotServiceConfig
+	}
+
+	return &ReportRenderer{
+		chromeManager: chromeManager,
+		redisClient:   redisClient,
+		config:        config,
+		storage:       storageFor(config),
+	}
+}
+
+// ParseReportFormat 解析报告格式，空值表示返回JSON
+func ParseReportFormat(value string) (ReportFormat, error) {
+	switch strings.ToLower(value) {
+	case "", "json":
+		return "", nil
+	case string(ReportFormatHTML):
+		return ReportFormatHTML, nil
+	case string(ReportFormatPDF):
+		return ReportFormatPDF, nil
+	default:
+		return "", fmt.Errorf("unsupported report format: %s", value)
+	}
+}
+
+// GetCached 获取已缓存的报告文件，缓存存在但文件已被清理时视为未命中
+func (r *ReportRenderer) GetCached(ctx context.Context, domain string, sections []string, format ReportFormat) *ReportFile {
+	if r.redisClient == nil {
+		return nil
+	}
+
+	data, err := r.redisClient.Get(ctx, r.buildCacheKey(domain, sections, format)).Result()
+	if err != nil {
+		return nil
+	}
+
+	var file ReportFile
+	if err := json.Unmarshal([]byte(data), &file); err != nil {
+		return nil
+	}
+	if file.FileKey == "" {
+		file.FileKey = storageKeyFromURL(file.FileURL)
+	}
+	if _, err := r.storage.Stat(ctx, file.FileKey); err != nil {
+		return nil
+	}
+	if err := r.signFile(ctx, &file); err != nil {
+		return nil
+	}
+
+	file.FromCache = true
+	return &file
+}
+
+// ReadFile 从文件存储读取报告内容
+func (r *ReportRenderer) ReadFile(ctx context.Context, file *ReportFile) ([]byte, error) {
+	return r.storage.Get(ctx, file.FileKey)
+}
+
+// Render 渲染报告并写入文件，PDF格式需要Chrome并受熔断器保护
+func (r *ReportRenderer) Render(ctx context.Context, report *types.DomainReport, sections []string, format ReportFormat) (*ReportFile, error) {
+	html, err := r.renderHTML(report)
+	if err != nil {
+		return nil, fmt.Errorf("渲染报告模板失败: %v", err)
+	}
+
+	content := html
+	if format == ReportFormatPDF {
+		content, err = r.printToPDF(ctx, html)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.config.MaxFileSize > 0 && int64(len(content)) > r.config.MaxFileSize {
+		return nil, fmt.Errorf("报告文件大小 %d 超过限制 %d", len(content), r.config.MaxFileSize)
+	}
+
+	fileName := fmt.Sprintf("report_%s_%d.%s", utils.GenerateSecureFilename(report.Domain), time.Now().Unix(), format)
+	file := &ReportFile{
+		Domain:      report.Domain,
+		Format:      format,
+		FileKey:     "reports/" + fileName,
+		Size:        int64(len(content)),
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := r.storage.Put(ctx, file.FileKey, content, format.ContentType()); err != nil {
+		return nil, fmt.Errorf("保存报告文件失败: %v", err)
+	}
+	if err := r.signFile(ctx, file); err != nil {
+		return nil, fmt.Errorf("生成报告访问URL失败: %v", err)
+	}
+
+	// 部分分节失败的报告不缓存，下次请求重新生成
+	if report.Summary.Failed == 0 {
+		r.cacheFile(r.buildCacheKey(report.Domain, sections, format), file)
+	}
+	log.Printf("[REPORT] 生成 %s 报告: %s, 大小: %d", format, report.Domain, file.Size)
+	return file, nil
+}
+
+// renderHTML 使用内置模板渲染报告，截图以data URI内嵌以保证文件可独立查看
+func (r *ReportRenderer) renderHTML(report *types.DomainReport) ([]byte, error) {
+	data := reportTemplateData{
+		DomainReport: report,
+		Whois:        report.Sections["whois"],
+		RDAP:         report.Sections["rdap"],
+		DNS:          report.Sections["dns"],
+		Screenshot:   report.Sections["screenshot"],
+	}
+	if data.Screenshot != nil && data.Screenshot.Status == types.ReportStatusSuccess {
+		if shot, ok := data.Screenshot.Data.(*ScreenshotResponse); ok {
+			data.ScreenshotURI = r.screenshotDataURI(shot)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// screenshotDataURI 将截图转换为data URI，文件格式的截图从文件存储读取
+func (r *ReportRenderer) screenshotDataURI(shot *ScreenshotResponse) template.URL {
+	if strings.HasPrefix(shot.ImageURL, "data:image/") {
+		return template.URL(shot.ImageURL)
+	}
+	if shot.ImageBase64 != "" {
+		return template.URL("data:image/png;base64," + shot.ImageBase64)
+	}
+	key := shot.ImageKey
+	if key == "" && strings.HasPrefix(shot.ImageURL, "/static/") {
+		key = storageKeyFromURL(shot.ImageURL)
+	}
+	if key == "" {
+		return ""
+	}
+
+	content, err := r.storage.Get(context.Background(), key)
+	if err != nil {
+		log.Printf("[REPORT] 读取截图文件失败: %v", err)
+		return ""
+	}
+	mimeType := mime.TypeByExtension(path.Ext(key))
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(content))
+}
+
+// printToPDF 在Chrome中加载HTML并打印为A4 PDF
+func (r *ReportRenderer) printToPDF(ctx context.Context, html []byte) ([]byte, error) {
+	if r.chromeManager == nil || !r.chromeManager.AllowRequest() {
+		return nil, fmt.Errorf("PDF渲染服务暂时不可用，请稍后重试")
+	}
+
+	startTime := time.Now()
+	chromeCtx, cancel, err := r.chromeManager.GetContext(r.config.DefaultTimeout)
+	if err != nil {
+		r.chromeManager.OnFailure(time.Since(startTime))
+		return nil, fmt.Errorf("获取Chrome上下文失败: %v", err)
+	}
+	defer cancel()
+
+	taskCtx, taskCancel := context.WithTimeout(chromeCtx, r.config.DefaultTimeout)
+	defer taskCancel()
+	// 请求取消时同步终止渲染
+	stop := context.AfterFunc(ctx, taskCancel)
+	defer stop()
+
+	var pdf []byte
+	err = chromedp.Run(taskCtx,
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			frameTree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+			return page.SetDocumentContent(frameTree.Frame.ID, string(html)).Do(ctx)
+		}),
+		chromedp.WaitReady("body"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			pdf, _, err = page.PrintToPDF().
+				WithPrintBackground(true).
+				WithPreferCSSPageSize(true).
+				Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		r.chromeManager.OnFailure(time.Since(startTime))
+		return nil, fmt.Errorf("打印PDF失败: %v", err)
+	}
+
+	r.chromeManager.OnSuccess(time.Since(startTime))
+	return pdf, nil
+}
+
+// buildCacheKey 缓存键包含分节组合，不同分节生成不同的报告文件
+func (r *ReportRenderer) buildCacheKey(domain string, sections []string, format ReportFormat) string {
+	return utils.BuildCacheKey("cache", "report", string(format), utils.SanitizeDomain(domain), strings.Join(sections, "+"))
+}
+
+// signFile 生成报告的访问URL
+// 本地存储没有签名能力，报告不通过/static公开，FileURL留空，由接口填入需要认证的下载地址
+func (r *ReportRenderer) signFile(ctx context.Context, file *ReportFile) error {
+	expiry := r.config.SignedURLExpiry
+	if expiry <= 0 {
+		expiry = DefaultSignedURLExpiry
+	}
+	signed, err := r.storage.SignedURL(ctx, file.FileKey, expiry)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(signed, "/static/") {
+		signed = ""
+	}
+	file.FileURL = signed
+	touchStorage(ctx, r.redisClient, file.FileKey)
+	return nil
+}
+
+// cacheFile 缓存报告文件信息，过期时间与截图缓存一致
+func (r *ReportRenderer) cacheFile(key string, file *ReportFile) {
+	if r.redisClient == nil {
+		return
+	}
+
+	expiration := r.config.CacheExpiration
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return
+	}
+	if err := r.redisClient.Set(context.Background(), key, data, expiration).Err(); err != nil {
+		log.Printf("[REPORT] 缓存报告失败: %v", err)
+	}
+}
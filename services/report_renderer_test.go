@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"whosee/types"
+)
+
+// TestReportRendererHTML 测试HTML报告渲染、截图内嵌和写入文件存储
+func TestReportRendererHTML(t *testing.T) {
+	baseDir := t.TempDir()
+	config := *DefaultScreenshotServiceConfig
+	config.BaseDir = baseDir
+	renderer := NewReportRenderer(nil, nil, &config)
+
+	shotDir := filepath.Join(baseDir, "screenshots")
+	if err := os.MkdirAll(shotDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shotDir, "shot.png"), []byte("png-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := &types.DomainReport{
+		Domain:      "example.com",
+		GeneratedAt: "2026-10-18T00:00:00Z",
+		Sections: map[string]*types.ReportSection{
+			"whois": {
+				Status: types.ReportStatusSuccess,
+				Data: &types.WhoisResponse{
+					Registrar:   "Example <Registrar>",
+					NameServers: []string{"ns1.example.com", "ns2.example.com"},
+				},
+			},
+			"dns": {Status: types.ReportStatusTimeout, Error: "section timed out"},
+			"screenshot": {
+				Status: types.ReportStatusSuccess,
+				Data:   &ScreenshotResponse{Success: true, ImageURL: "/static/screenshots/shot.png"},
+			},
+		},
+		Summary: types.ReportSummary{Requested: 3, Succeeded: 2, Failed: 1},
+	}
+
+	file, err := renderer.Render(context.Background(), report, []string{"whois", "dns", "screenshot"}, ReportFormatHTML)
+	if err != nil {
+		t.Fatalf("Render 返回错误: %v", err)
+	}
+	if !strings.HasPrefix(file.FileKey, "reports/") || file.FileURL != "" {
+		t.Fatalf("本地存储的报告不应有公开URL: %s, %s", file.FileKey, file.FileURL)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(file.FileKey))); err != nil {
+		t.Fatalf("报告文件未写入本地存储: %v", err)
+	}
+
+	content, err := renderer.ReadFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("读取报告文件失败: %v", err)
+	}
+	html := string(content)
+	for _, want := range []string{
+		"Example &lt;Registrar&gt;",
+		"ns2.example.com",
+		"section timed out",
+		"data:image/png;base64,cG5nLWJ5dGVz",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("报告内容缺少 %q", want)
+		}
+	}
+
+	config.Storage = NewMemoryStorage()
+	remote := NewReportRenderer(nil, nil, &config)
+	report.Sections = map[string]*types.ReportSection{"whois": report.Sections["whois"]}
+	file, err = remote.Render(context.Background(), report, []string{"whois"}, ReportFormatHTML)
+	if err != nil || file.FileURL != "memory://"+file.FileKey {
+		t.Errorf("对象存储的报告应返回签名URL: %+v, %v", file, err)
+	}
+}
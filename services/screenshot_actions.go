@@ -0,0 +1,330 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图前的页面交互步骤 - 点击、输入、滚动、悬停、等待、按键和设置Cookie，按白名单校验并逐步报告结果
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"whosee/utils"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// ActionType 交互步骤类型
+type ActionType string
+
+const (
+	ActionClick     ActionType = "click"
+	ActionTypeText  ActionType = "type"
+	ActionScroll    ActionType = "scroll"
+	ActionHover     ActionType = "hover"
+	ActionWait      ActionType = "wait"
+	ActionPress     ActionType = "press"
+	ActionSetCookie ActionType = "set_cookie"
+)
+
+// 交互步骤参数限制
+const (
+	MaxActions           = 20
+	DefaultActionTimeout = 5 * time.Second
+	MaxActionTimeout     = 30 * time.Second
+	MaxActionWait        = 10 * time.Second
+	MaxActionTextLength  = 1000
+)
+
+// actionKeys press步骤允许的按键
+var actionKeys = map[string]string{
+	"enter":      kb.Enter,
+	"tab":        kb.Tab,
+	"escape":     kb.Escape,
+	"backspace":  kb.Backspace,
+	"delete":     kb.Delete,
+	"space":      " ",
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+	"home":       kb.Home,
+	"end":        kb.End,
+}
+
+// ScreenshotAction 截图前执行的一个交互步骤
+type ScreenshotAction struct {
+	Action    ActionType    `json:"action"`
+	Selector  string        `json:"selector,omitempty"`   // click/type/hover/scroll/wait的目标元素，// 开头按XPath处理
+	Text      string        `json:"text,omitempty"`       // type输入的文本
+	X         int           `json:"x,omitempty"`          // scroll未指定选择器时的横向坐标
+	Y         int           `json:"y,omitempty"`          // scroll未指定选择器时的纵向坐标
+	Ms        int           `json:"ms,omitempty"`         // wait未指定选择器时的等待毫秒数
+	Key       string        `json:"key,omitempty"`        // press的按键名
+	Cookie    *ActionCookie `json:"cookie,omitempty"`     // set_cookie的Cookie
+	TimeoutMs int           `json:"timeout_ms,omitempty"` // 单步超时（毫秒）
+	Optional  bool          `json:"optional,omitempty"`   // 失败时继续执行后续步骤
+}
+
+// ActionCookie set_cookie步骤的Cookie
+type ActionCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"` // 默认为目标页面的主机
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+}
+
+// ActionResult 单个步骤的执行结果
+type ActionResult struct {
+	Index      int        `json:"index"`
+	Action     ActionType `json:"action"`
+	Success    bool       `json:"success"`
+	Skipped    bool       `json:"skipped,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+// actionError 必需步骤失败，携带已执行步骤的结果
+type actionError struct {
+	result  *ActionResult
+	results []*ActionResult
+}
+
+func (e *actionError) Error() string {
+	return fmt.Sprintf("第%d步 %s 执行失败: %s", e.result.Index+1, e.result.Action, e.result.Error)
+}
+
+// validateActions 按白名单校验交互步骤，targetURL用于校验Cookie域名
+func validateActions(actions []*ScreenshotAction, targetURL string) error {
+	if len(actions) > MaxActions {
+		return fmt.Errorf("交互步骤不能超过%d个", MaxActions)
+	}
+
+	for i, action := range actions {
+		if action == nil {
+			return fmt.Errorf("第%d步为空", i+1)
+		}
+		if err := action.validate(targetURL); err != nil {
+			return fmt.Errorf("第%d步 %s: %v", i+1, action.Action, err)
+		}
+	}
+	return nil
+}
+
+func (a *ScreenshotAction) validate(targetURL string) error {
+	if a.TimeoutMs < 0 || time.Duration(a.TimeoutMs)*time.Millisecond > MaxActionTimeout {
+		return fmt.Errorf("timeout_ms必须在0-%d之间", MaxActionTimeout.Milliseconds())
+	}
+	if a.Selector != "" && (strings.Contains(a.Selector, "javascript:") || strings.Contains(a.Selector, "eval(")) {
+		return fmt.Errorf("选择器包含不安全的内容")
+	}
+
+	switch a.Action {
+	case ActionClick, ActionHover:
+		if a.Selector == "" {
+			return fmt.Errorf("必须提供selector")
+		}
+	case ActionTypeText:
+		if a.Selector == "" {
+			return fmt.Errorf("必须提供selector")
+		}
+		if len(a.Text) > MaxActionTextLength {
+			return fmt.Errorf("text长度不能超过%d", MaxActionTextLength)
+		}
+	case ActionScroll:
+		if a.X < 0 || a.Y < 0 {
+			return fmt.Errorf("滚动坐标不能为负数")
+		}
+	case ActionWait:
+		if a.Selector == "" && a.Ms <= 0 {
+			return fmt.Errorf("必须提供ms或selector")
+		}
+		if time.Duration(a.Ms)*time.Millisecond > MaxActionWait {
+			return fmt.Errorf("ms不能超过%d", MaxActionWait.Milliseconds())
+		}
+	case ActionPress:
+		if _, ok := actionKeys[strings.ToLower(a.Key)]; !ok {
+			return fmt.Errorf("不支持的按键: %s", a.Key)
+		}
+	case ActionSetCookie:
+		return validateActionCookie(a.Cookie, targetURL)
+	default:
+		return fmt.Errorf("不支持的操作类型")
+	}
+	return nil
+}
+
+// validateActionCookie Cookie只能设置在目标主机或其上级域名
+func validateActionCookie(cookie *ActionCookie, targetURL string) error {
+	if cookie == nil || cookie.Name == "" {
+		return fmt.Errorf("必须提供cookie.name")
+	}
+	if cookie.Domain == "" {
+		return nil
+	}
+
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("无效的目标URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return fmt.Errorf("cookie.domain必须是目标主机或其上级域名")
+	}
+	// 不允许设置在公共后缀（如 com、co.uk）上，否则Cookie会发送给该后缀下的所有站点
+	if net.ParseIP(domain) == nil {
+		if parts, err := utils.SplitDomain(domain); err != nil || parts.RegistrableDomain == "" {
+			return fmt.Errorf("cookie.domain不能是公共后缀: %s", cookie.Domain)
+		}
+	}
+	return nil
+}
+
+// timeout 单步超时
+func (a *ScreenshotAction) timeout() time.Duration {
+	if a.TimeoutMs > 0 {
+		return time.Duration(a.TimeoutMs) * time.Millisecond
+	}
+	return DefaultActionTimeout
+}
+
+// setActionCookies 在导航之前设置Cookie，使首个请求即可携带
+func setActionCookies(actions []*ScreenshotAction, targetURL string, results *[]*ActionResult) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for i, action := range actions {
+			if action.Action != ActionSetCookie {
+				continue
+			}
+			if err := runAction(ctx, i, action, targetURL, results); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runActions 按顺序执行除set_cookie以外的步骤
+func runActions(actions []*ScreenshotAction, targetURL string, results *[]*ActionResult) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for i, action := range actions {
+			if action.Action == ActionSetCookie {
+				continue
+			}
+			if err := runAction(ctx, i, action, targetURL, results); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runAction 执行单个步骤并记录结果，可选步骤失败时继续
+func runAction(ctx context.Context, index int, action *ScreenshotAction, targetURL string, results *[]*ActionResult) error {
+	stepCtx, cancel := context.WithTimeout(ctx, action.timeout())
+	defer cancel()
+
+	startTime := time.Now()
+	err := action.do(stepCtx, targetURL)
+	result := &ActionResult{
+		Index:      index,
+		Action:     action.Action,
+		Success:    err == nil,
+		DurationMs: time.Since(startTime).Milliseconds(),
+	}
+	*results = append(*results, result)
+
+	if err == nil {
+		return nil
+	}
+	if errors.Is(stepCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("步骤超时（%v）", action.timeout())
+	}
+	result.Error = err.Error()
+
+	if action.Optional {
+		result.Skipped = true
+		return nil
+	}
+	return &actionError{result: result, results: *results}
+}
+
+func (a *ScreenshotAction) do(ctx context.Context, targetURL string) error {
+	queryOption := selectorQueryOption(a.Selector)
+
+	switch a.Action {
+	case ActionClick:
+		return chromedp.Click(a.Selector, chromedp.NodeVisible, queryOption).Do(ctx)
+	case ActionTypeText:
+		return chromedp.SendKeys(a.Selector, a.Text, chromedp.NodeVisible, queryOption).Do(ctx)
+	case ActionScroll:
+		if a.Selector != "" {
+			return chromedp.ScrollIntoView(a.Selector, queryOption).Do(ctx)
+		}
+		return chromedp.Evaluate(fmt.Sprintf("window.scrollTo(%d, %d)", a.X, a.Y), nil).Do(ctx)
+	case ActionHover:
+		return hoverNode(ctx, a.Selector, queryOption)
+	case ActionWait:
+		if a.Selector != "" {
+			return chromedp.WaitVisible(a.Selector, queryOption).Do(ctx)
+		}
+		return chromedp.Sleep(time.Duration(a.Ms) * time.Millisecond).Do(ctx)
+	case ActionPress:
+		return chromedp.KeyEvent(actionKeys[strings.ToLower(a.Key)]).Do(ctx)
+	case ActionSetCookie:
+		cookie := network.SetCookie(a.Cookie.Name, a.Cookie.Value).
+			WithSecure(a.Cookie.Secure).
+			WithHTTPOnly(a.Cookie.HTTPOnly)
+		if a.Cookie.Domain != "" {
+			cookie = cookie.WithDomain(a.Cookie.Domain).WithPath("/")
+		} else {
+			cookie = cookie.WithURL(targetURL)
+		}
+		if a.Cookie.Path != "" {
+			cookie = cookie.WithPath(a.Cookie.Path)
+		}
+		return cookie.Do(ctx)
+	}
+	return fmt.Errorf("不支持的操作类型: %s", a.Action)
+}
+
+// hoverNode 将鼠标移动到元素中心，触发hover样式和mouseover事件
+func hoverNode(ctx context.Context, selector string, queryOption chromedp.QueryOption) error {
+	var nodes []*cdp.Node
+	if err := chromedp.Nodes(selector, &nodes, chromedp.NodeVisible, queryOption).Do(ctx); err != nil {
+		return err
+	}
+	if err := dom.ScrollIntoViewIfNeeded().WithNodeID(nodes[0].NodeID).Do(ctx); err != nil {
+		return err
+	}
+
+	quads, err := dom.GetContentQuads().WithNodeID(nodes[0].NodeID).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(quads) == 0 || len(quads[0]) < 8 {
+		return fmt.Errorf("无法获取元素位置")
+	}
+
+	var x, y float64
+	for i := 0; i < len(quads[0]); i += 2 {
+		x += quads[0][i]
+		y += quads[0][i+1]
+	}
+	points := float64(len(quads[0]) / 2)
+	return chromedp.MouseEvent(input.MouseMoved, x/points, y/points).Do(ctx)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestValidateActions 测试交互步骤白名单校验
+func TestValidateActions(t *testing.T) {
+	target := "https://www.example.com/login"
+
+	valid := `[
+		{"action": "click", "selector": "#accept", "optional": true},
+		{"action": "type", "selector": "//input[@name='q']", "text": "whosee"},
+		{"action": "scroll", "y": 800},
+		{"action": "scroll", "selector": "#footer"},
+		{"action": "hover", "selector": ".menu"},
+		{"action": "wait", "ms": 500},
+		{"action": "wait", "selector": ".loaded", "timeout_ms": 10000},
+		{"action": "press", "key": "Enter"},
+		{"action": "set_cookie", "cookie": {"name": "consent", "value": "yes"}},
+		{"action": "set_cookie", "cookie": {"name": "lang", "value": "zh", "domain": ".example.com"}}
+	]`
+	var actions []*ScreenshotAction
+	if err := json.Unmarshal([]byte(valid), &actions); err != nil {
+		t.Fatalf("解析步骤失败: %v", err)
+	}
+	if err := validateActions(actions, target); err != nil {
+		t.Errorf("合法步骤校验失败: %v", err)
+	}
+
+	invalid := map[string]*ScreenshotAction{
+		"未知操作":       {Action: "eval", Selector: "body"},
+		"缺少选择器":      {Action: ActionClick},
+		"不安全选择器":     {Action: ActionClick, Selector: "javascript:alert(1)"},
+		"文本过长":       {Action: ActionTypeText, Selector: "#q", Text: strings.Repeat("a", MaxActionTextLength+1)},
+		"等待过长":       {Action: ActionWait, Ms: 60000},
+		"空等待":        {Action: ActionWait},
+		"不支持的按键":     {Action: ActionPress, Key: "F12"},
+		"超时过长":       {Action: ActionClick, Selector: "#a", TimeoutMs: 600000},
+		"负滚动坐标":      {Action: ActionScroll, Y: -1},
+		"缺少Cookie":   {Action: ActionSetCookie},
+		"跨域Cookie":   {Action: ActionSetCookie, Cookie: &ActionCookie{Name: "a", Domain: "evil.com"}},
+		"相似后缀Cookie": {Action: ActionSetCookie, Cookie: &ActionCookie{Name: "a", Domain: "ample.com"}},
+		"公共后缀Cookie": {Action: ActionSetCookie, Cookie: &ActionCookie{Name: "a", Domain: ".com"}},
+	}
+	for name, action := range invalid {
+		if err := validateActions([]*ScreenshotAction{action}, target); err == nil {
+			t.Errorf("%s: 期望校验失败", name)
+		}
+	}
+
+	tooMany := make([]*ScreenshotAction, MaxActions+1)
+	for i := range tooMany {
+		tooMany[i] = &ScreenshotAction{Action: ActionWait, Ms: 10}
+	}
+	if err := validateActions(tooMany, target); err == nil {
+		t.Error("步骤数量超限应校验失败")
+	}
+}
+
+// TestActionErrorMessage 测试步骤错误信息使用从1开始的序号
+func TestActionErrorMessage(t *testing.T) {
+	result := &ActionResult{Index: 2, Action: ActionClick, Error: "步骤超时（5s）"}
+	err := &actionError{result: result, results: []*ActionResult{result}}
+	if err.Error() != "第3步 click 执行失败: 步骤超时（5s）" {
+		t.Errorf("错误信息不正确: %s", err.Error())
+	}
+}
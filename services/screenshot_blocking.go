@@ -0,0 +1,238 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图页面净化 - 隐藏指定元素和常见同意弹窗，通过CDP Fetch拦截请求，拦截广告与跟踪请求、检查出站地址并响应代理认证
+ */
+package services
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+//go:embed blocklists/ad_hosts.txt
+var embeddedAdBlocklist string
+
+// 元素隐藏参数限制
+const (
+	MaxHideSelectors      = 50
+	MaxHideSelectorLength = 200
+)
+
+// consentSelectors 常见同意管理平台（CMP）的弹窗和遮罩
+var consentSelectors = []string{
+	// OneTrust
+	"#onetrust-consent-sdk", "#onetrust-banner-sdk", ".onetrust-pc-dark-filter",
+	// Cookiebot
+	"#CybotCookiebotDialog", "#CybotCookiebotDialogBodyUnderlay",
+	// Quantcast Choice
+	".qc-cmp2-container", "#qc-cmp2-container",
+	// TrustArc
+	"#truste-consent-track", ".truste_box_overlay", ".truste_overlay", "#consent_blackbar",
+	// Didomi
+	"#didomi-host", ".didomi-popup-open",
+	// Usercentrics
+	"#usercentrics-root", "#usercentrics-cmp-ui",
+	// Sourcepoint
+	"[id^='sp_message_container']", ".sp_veil",
+	// Google Funding Choices
+	".fc-consent-root", ".fc-dialog-overlay",
+	// Osano / CookieYes / Complianz / iubenda / Termly / Borlabs / Klaro / Cookie Consent
+	".osano-cm-window", ".cky-consent-container", ".cky-overlay", "#cmplz-cookiebanner-container",
+	"#iubenda-cs-banner", "#termly-code-snippet-support", "#BorlabsCookieBox", ".klaro .cookie-notice",
+	".cc-window", ".cc-banner", "#cookie-law-info-bar", "#cookie-notice", "#gdpr-cookie-message",
+}
+
+// hostBlocklist 按主机及其子域名匹配的拦截列表
+type hostBlocklist struct {
+	hosts map[string]struct{}
+}
+
+// loadAdBlocklist 加载内置拦截列表，path不为空时合并额外的列表文件
+func loadAdBlocklist(path string) *hostBlocklist {
+	list := &hostBlocklist{hosts: make(map[string]struct{})}
+	list.merge(strings.NewReader(embeddedAdBlocklist))
+
+	if path == "" {
+		return list
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("[SCREENSHOT] 读取广告拦截列表失败: %s, 错误: %v", path, err)
+		return list
+	}
+	defer file.Close()
+
+	list.merge(file)
+	log.Printf("[SCREENSHOT] 已加载广告拦截列表: %s, 共 %d 个主机", path, len(list.hosts))
+	return list
+}
+
+// merge 合并列表，#开头为注释，@@开头表示移除
+func (l *hostBlocklist) merge(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			delete(l.hosts, strings.TrimPrefix(line, "@@"))
+			continue
+		}
+		l.hosts[strings.TrimPrefix(line, ".")] = struct{}{}
+	}
+}
+
+// blocks 主机本身或任一上级域名在列表中时拦截
+func (l *hostBlocklist) blocks(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for host != "" {
+		if _, ok := l.hosts[host]; ok {
+			return true
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			return false
+		}
+		host = host[dot+1:]
+	}
+	return false
+}
+
+// requestInterceptor 通过CDP Fetch拦截页面发出的每个请求
+type requestInterceptor struct {
+	guard     *navigationGuard // 出站地址检查，为nil时不检查
+	blocklist *hostBlocklist   // 广告拦截列表，为nil时不拦截广告
+	blocked   *int64           // 被广告列表拦截的请求数
+	proxy     *ScreenshotProxy // 浏览器直接连接的需要认证的代理，由ChromeManager设置，为nil时不处理认证质询
+}
+
+// action 启用Fetch拦截：出站策略拒绝的请求以AddressUnreachable失败，命中广告列表的以BlockedByClient失败，其余放行；
+// 浏览器连接的代理（远程模式下的出站代理或上游代理）需要认证时响应认证质询，同一请求只提供一次凭据，避免凭据错误时反复重试
+func (ri *requestInterceptor) action() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var authMu sync.Mutex
+		authAttempts := make(map[fetch.RequestID]bool)
+
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch e := ev.(type) {
+			case *fetch.EventRequestPaused:
+				// 事件回调中不能同步执行CDP命令，地址检查可能需要解析域名
+				go func() {
+					execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+					var err error
+					if ri.guard != nil && !ri.guard.allow(ctx, e) {
+						err = fetch.FailRequest(e.RequestID, network.ErrorReasonAddressUnreachable).Do(execCtx)
+					} else if u, parseErr := url.Parse(e.Request.URL); parseErr == nil && ri.blocklist != nil && ri.blocklist.blocks(u.Hostname()) {
+						atomic.AddInt64(ri.blocked, 1)
+						err = fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx)
+					} else {
+						err = fetch.ContinueRequest(e.RequestID).Do(execCtx)
+					}
+					if err != nil && ctx.Err() == nil {
+						log.Printf("[SCREENSHOT] 处理拦截请求失败: %v", err)
+					}
+				}()
+			case *fetch.EventAuthRequired:
+				response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+				if e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+					authMu.Lock()
+					retried := authAttempts[e.RequestID]
+					authAttempts[e.RequestID] = true
+					authMu.Unlock()
+					if retried {
+						log.Printf("[SCREENSHOT] 代理 %s 认证失败", ri.proxy.Name)
+						response.Response = fetch.AuthChallengeResponseResponseCancelAuth
+					} else {
+						response.Response = fetch.AuthChallengeResponseResponseProvideCredentials
+						response.Username = ri.proxy.Username
+						response.Password = ri.proxy.Password
+					}
+				}
+				go func() {
+					execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+					if err := fetch.ContinueWithAuth(e.RequestID, response).Do(execCtx); err != nil && ctx.Err() == nil {
+						log.Printf("[SCREENSHOT] 响应认证质询失败: %v", err)
+					}
+				}()
+			}
+		})
+
+		return fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+			{URLPattern: "*", RequestStage: fetch.RequestStageRequest},
+		}).WithHandleAuthRequests(ri.proxy.needsAuth()).Do(ctx)
+	})
+}
+
+// validateHideSelectors 校验隐藏选择器，禁止通过花括号注入额外样式
+func validateHideSelectors(selectors []string) error {
+	if len(selectors) > MaxHideSelectors {
+		return fmt.Errorf("hide_selectors不能超过%d个", MaxHideSelectors)
+	}
+	for _, selector := range selectors {
+		if strings.TrimSpace(selector) == "" {
+			return fmt.Errorf("hide_selectors不能包含空选择器")
+		}
+		if len(selector) > MaxHideSelectorLength {
+			return fmt.Errorf("hide_selectors单个选择器长度不能超过%d", MaxHideSelectorLength)
+		}
+		if strings.ContainsAny(selector, "{}<;") {
+			return fmt.Errorf("hide_selectors包含不安全的字符: %s", selector)
+		}
+	}
+	return nil
+}
+
+// buildHideCSS 生成隐藏样式：用户选择器保留布局只隐藏，同意弹窗直接移除并恢复页面滚动
+func buildHideCSS(selectors []string, hideConsent bool) string {
+	var css strings.Builder
+	if len(selectors) > 0 {
+		css.WriteString(strings.Join(selectors, ",\n"))
+		css.WriteString(" { visibility: hidden !important; }\n")
+	}
+	if hideConsent {
+		css.WriteString(strings.Join(consentSelectors, ",\n"))
+		css.WriteString(" { display: none !important; }\n")
+		css.WriteString("html.didomi-popup-open, body.didomi-popup-open, body.sp-message-open { overflow: auto !important; }\n")
+	}
+	return css.String()
+}
+
+// injectStyles 在每个新文档创建时注入样式，覆盖交互步骤触发的页面跳转
+func injectStyles(css string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		content, err := json.Marshal(css)
+		if err != nil {
+			return err
+		}
+		script := fmt.Sprintf(`(() => {
+	const add = () => {
+		const style = document.createElement('style');
+		style.setAttribute('data-whosee', 'hide');
+		style.textContent = %s;
+		(document.head || document.documentElement).appendChild(style);
+	};
+	if (document.documentElement) add();
+	else document.addEventListener('DOMContentLoaded', add);
+})()`, content)
+		_, err = page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+		return err
+	})
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHostBlocklist 测试内置列表按子域名匹配，以及额外列表的添加和移除
+func TestHostBlocklist(t *testing.T) {
+	list := loadAdBlocklist("")
+	blocked := []string{"doubleclick.net", "stats.g.doubleclick.net", "WWW.GOOGLE-ANALYTICS.COM", "hm.baidu.com."}
+	for _, host := range blocked {
+		if !list.blocks(host) {
+			t.Errorf("%s 应被拦截", host)
+		}
+	}
+	allowed := []string{"example.com", "baidu.com", "notdoubleclick.net", ""}
+	for _, host := range allowed {
+		if list.blocks(host) {
+			t.Errorf("%s 不应被拦截", host)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "extra.txt")
+	content := "# 额外列表\nads.example.org\n.tracker.example.net\n@@hotjar.com\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list = loadAdBlocklist(path)
+	if !list.blocks("cdn.ads.example.org") || !list.blocks("tracker.example.net") {
+		t.Error("额外列表中的主机应被拦截")
+	}
+	if list.blocks("static.hotjar.com") {
+		t.Error("@@移除的主机不应被拦截")
+	}
+	if !list.blocks("doubleclick.net") {
+		t.Error("合并额外列表后应保留内置列表")
+	}
+
+	// 文件不存在时退回内置列表
+	if list := loadAdBlocklist(filepath.Join(t.TempDir(), "missing.txt")); !list.blocks("doubleclick.net") {
+		t.Error("读取失败时应使用内置列表")
+	}
+}
+
+// TestValidateHideSelectors 测试隐藏选择器校验
+func TestValidateHideSelectors(t *testing.T) {
+	if err := validateHideSelectors([]string{"#chat-widget", ".ad, .banner", "div[data-ad='1']"}); err != nil {
+		t.Errorf("合法选择器校验失败: %v", err)
+	}
+
+	invalid := [][]string{
+		{""},
+		{"body } * { display:none"},
+		{"#a; color: red"},
+		{"</style><script>"},
+		{strings.Repeat("a", MaxHideSelectorLength+1)},
+		make([]string, MaxHideSelectors+1),
+	}
+	for _, selectors := range invalid {
+		if err := validateHideSelectors(selectors); err == nil {
+			t.Errorf("期望校验失败: %q", selectors)
+		}
+	}
+}
+
+// TestBuildHideCSS 测试用户选择器和同意弹窗规则的样式
+func TestBuildHideCSS(t *testing.T) {
+	if css := buildHideCSS(nil, false); css != "" {
+		t.Errorf("无选择器时不应生成样式: %q", css)
+	}
+
+	css := buildHideCSS([]string{"#chat", ".ad"}, false)
+	if !strings.Contains(css, "#chat,\n.ad { visibility: hidden !important; }") || strings.Contains(css, "onetrust") {
+		t.Errorf("用户选择器样式不正确: %q", css)
+	}
+
+	css = buildHideCSS(nil, true)
+	if !strings.Contains(css, "#onetrust-consent-sdk") || !strings.Contains(css, "display: none !important") {
+		t.Errorf("同意弹窗规则样式不正确: %q", css)
+	}
+}
@@ -8,6 +8,7 @@ package services
 import (
 	"whosee/utils"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -90,6 +91,13 @@ func (sc *ScreenshotChecker) TestScreenshotHealth(domain ...string) map[string]i
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse // 不跟随重定向
 			},
+			// 目标域名由请求指定，连接前按出站策略拒绝内网地址
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout: 10 * time.Second,
+					Control: utils.GetNetworkPolicy().Control,
+				}).DialContext,
+			},
 		}
 
 		req, err := http.NewRequest("HEAD", url, nil)
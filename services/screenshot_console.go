@@ -0,0 +1,237 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图控制台记录 - 通过CDP Runtime/Log/Network事件收集控制台消息、未捕获异常和资源加载失败
+ */
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// 控制台消息级别
+const (
+	ConsoleLevelError   = "error"
+	ConsoleLevelWarning = "warning"
+	ConsoleLevelInfo    = "info"
+	ConsoleLevelDebug   = "debug"
+)
+
+// 控制台消息来源
+const (
+	ConsoleSourceConsole   = "console"
+	ConsoleSourceException = "exception"
+	ConsoleSourceNetwork   = "network"
+)
+
+// 控制台记录参数限制
+const (
+	MaxConsoleMessages    = 100  // 最多返回的消息数，统计不受限制
+	MaxConsoleTextLength  = 1000 // 单条消息最大长度
+	MaxConsoleErrorsLimit = 1000 // fail_on_console_errors上限
+)
+
+// ConsoleMessage 一条控制台消息
+type ConsoleMessage struct {
+	Level  string `json:"level"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Line   int64  `json:"line,omitempty"`
+	Column int64  `json:"column,omitempty"`
+}
+
+// ConsoleSummary 控制台记录结果，写入响应元数据
+type ConsoleSummary struct {
+	Errors   int               `json:"errors"`
+	Warnings int               `json:"warnings"`
+	Messages []*ConsoleMessage `json:"messages"`
+	Dropped  int               `json:"dropped,omitempty"`
+}
+
+// consoleRecorder 监听单个标签页的控制台、异常和资源加载失败
+type consoleRecorder struct {
+	mu       sync.Mutex
+	requests map[network.RequestID]string
+	summary  ConsoleSummary
+}
+
+// newConsoleRecorder 创建记录器并注册事件监听，需要在导航之前调用
+func newConsoleRecorder(ctx context.Context) *consoleRecorder {
+	r := &consoleRecorder{
+		requests: make(map[network.RequestID]string),
+		summary:  ConsoleSummary{Messages: []*ConsoleMessage{}},
+	}
+	chromedp.ListenTarget(ctx, r.handleEvent)
+	return r
+}
+
+func (r *consoleRecorder) handleEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		msg := &ConsoleMessage{
+			Level:  consoleAPILevel(e.Type),
+			Source: ConsoleSourceConsole,
+			Text:   formatConsoleArgs(e.Args),
+		}
+		setStackLocation(msg, e.StackTrace)
+		r.add(msg)
+	case *runtime.EventExceptionThrown:
+		details := e.ExceptionDetails
+		msg := &ConsoleMessage{
+			Level:  ConsoleLevelError,
+			Source: ConsoleSourceException,
+			Text:   details.Text,
+			URL:    details.URL,
+			Line:   details.LineNumber + 1,
+			Column: details.ColumnNumber + 1,
+		}
+		if details.Exception != nil && details.Exception.Description != "" {
+			msg.Text = details.Exception.Description
+		}
+		r.add(msg)
+	case *cdplog.EventEntryAdded:
+		// 网络类日志由下方Network事件记录，避免重复
+		if e.Entry.Source == cdplog.SourceNetwork {
+			return
+		}
+		r.add(&ConsoleMessage{
+			Level:  logEntryLevel(e.Entry.Level),
+			Source: string(e.Entry.Source),
+			Text:   e.Entry.Text,
+			URL:    e.Entry.URL,
+			Line:   e.Entry.LineNumber,
+		})
+	case *network.EventRequestWillBeSent:
+		r.mu.Lock()
+		r.requests[e.RequestID] = e.Request.URL
+		r.mu.Unlock()
+	case *network.EventResponseReceived:
+		if e.Response.Status >= 400 {
+			r.add(&ConsoleMessage{
+				Level:  ConsoleLevelError,
+				Source: ConsoleSourceNetwork,
+				Text:   fmt.Sprintf("资源加载失败: HTTP %d", e.Response.Status),
+				URL:    e.Response.URL,
+			})
+		}
+	case *network.EventLoadingFailed:
+		r.mu.Lock()
+		url := r.requests[e.RequestID]
+		r.mu.Unlock()
+		// 页面主动取消的请求（如导航跳转）和block_ads拦截的请求不视为错误
+		if e.Canceled || strings.Contains(e.ErrorText, "ERR_BLOCKED_BY_CLIENT") {
+			return
+		}
+		text := e.ErrorText
+		if e.BlockedReason != "" {
+			text = fmt.Sprintf("%s (blocked: %s)", text, e.BlockedReason)
+		}
+		r.add(&ConsoleMessage{
+			Level:  ConsoleLevelError,
+			Source: ConsoleSourceNetwork,
+			Text:   "资源加载失败: " + text,
+			URL:    url,
+		})
+	}
+}
+
+func (r *consoleRecorder) add(msg *ConsoleMessage) {
+	if len(msg.Text) > MaxConsoleTextLength {
+		msg.Text = truncateUTF8(msg.Text, MaxConsoleTextLength) + "..."
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch msg.Level {
+	case ConsoleLevelError:
+		r.summary.Errors++
+	case ConsoleLevelWarning:
+		r.summary.Warnings++
+	}
+	if len(r.summary.Messages) >= MaxConsoleMessages {
+		r.summary.Dropped++
+		return
+	}
+	r.summary.Messages = append(r.summary.Messages, msg)
+}
+
+// result 返回当前记录的副本
+func (r *consoleRecorder) result() *ConsoleSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := r.summary
+	summary.Messages = append([]*ConsoleMessage(nil), r.summary.Messages...)
+	return &summary
+}
+
+func consoleAPILevel(apiType runtime.APIType) string {
+	switch apiType {
+	case runtime.APITypeError, runtime.APITypeAssert:
+		return ConsoleLevelError
+	case runtime.APITypeWarning:
+		return ConsoleLevelWarning
+	case runtime.APITypeDebug:
+		return ConsoleLevelDebug
+	default:
+		return ConsoleLevelInfo
+	}
+}
+
+func logEntryLevel(level cdplog.Level) string {
+	switch level {
+	case cdplog.LevelError:
+		return ConsoleLevelError
+	case cdplog.LevelWarning:
+		return ConsoleLevelWarning
+	case cdplog.LevelVerbose:
+		return ConsoleLevelDebug
+	default:
+		return ConsoleLevelInfo
+	}
+}
+
+// formatConsoleArgs 将console调用参数拼接为文本，原始值优先，对象使用描述
+func formatConsoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case len(arg.Value) > 0:
+			var value interface{}
+			if err := json.Unmarshal(arg.Value, &value); err == nil {
+				parts = append(parts, fmt.Sprint(value))
+			} else {
+				parts = append(parts, string(arg.Value))
+			}
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		default:
+			parts = append(parts, string(arg.Type))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// setStackLocation 使用调用栈顶部作为消息位置
+func setStackLocation(msg *ConsoleMessage, stack *runtime.StackTrace) {
+	if stack == nil || len(stack.CallFrames) == 0 {
+		return
+	}
+	frame := stack.CallFrames[0]
+	msg.URL = frame.URL
+	msg.Line = frame.LineNumber + 1
+	msg.Column = frame.ColumnNumber + 1
+}
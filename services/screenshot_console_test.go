@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func newTestConsoleRecorder() *consoleRecorder {
+	return &consoleRecorder{
+		requests: make(map[network.RequestID]string),
+		summary:  ConsoleSummary{Messages: []*ConsoleMessage{}},
+	}
+}
+
+// TestConsoleRecorder 测试控制台消息、异常和资源加载失败的级别与来源
+func TestConsoleRecorder(t *testing.T) {
+	r := newTestConsoleRecorder()
+
+	r.handleEvent(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeError,
+		Args: []*runtime.RemoteObject{
+			{Type: runtime.TypeString, Value: []byte(`"加载失败"`)},
+			{Type: runtime.TypeNumber, Value: []byte(`42`)},
+			{Type: runtime.TypeObject, Description: "Object"},
+		},
+		StackTrace: &runtime.StackTrace{CallFrames: []*runtime.CallFrame{{URL: "https://example.com/app.js", LineNumber: 9, ColumnNumber: 4}}},
+	})
+	r.handleEvent(&runtime.EventConsoleAPICalled{Type: runtime.APITypeWarning, Args: []*runtime.RemoteObject{{Type: runtime.TypeString, Value: []byte(`"deprecated"`)}}})
+	r.handleEvent(&runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: []*runtime.RemoteObject{{Type: runtime.TypeUndefined}}})
+	r.handleEvent(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{
+		Text:      "Uncaught",
+		Exception: &runtime.RemoteObject{Description: "TypeError: x is undefined"},
+	}})
+	r.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{Source: cdplog.SourceNetwork, Level: cdplog.LevelError, Text: "Failed to load resource"}})
+	r.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{Source: cdplog.SourceIntervention, Level: cdplog.LevelVerbose, Text: "intervention"}})
+	r.handleEvent(&network.EventRequestWillBeSent{RequestID: "1", Request: &network.Request{URL: "https://cdn.example.com/a.css"}})
+	r.handleEvent(&network.EventLoadingFailed{RequestID: "1", ErrorText: "net::ERR_CONNECTION_REFUSED"})
+	r.handleEvent(&network.EventRequestWillBeSent{RequestID: "2", Request: &network.Request{URL: "https://example.com/old"}})
+	r.handleEvent(&network.EventLoadingFailed{RequestID: "2", ErrorText: "net::ERR_ABORTED", Canceled: true})
+	r.handleEvent(&network.EventResponseReceived{RequestID: "3", Response: &network.Response{URL: "https://example.com/missing.png", Status: 404}})
+
+	summary := r.result()
+	if summary.Errors != 4 || summary.Warnings != 1 || len(summary.Messages) != 7 {
+		t.Fatalf("统计不正确: errors=%d warnings=%d messages=%d", summary.Errors, summary.Warnings, len(summary.Messages))
+	}
+
+	first := summary.Messages[0]
+	if first.Text != "加载失败 42 Object" || first.Line != 10 || first.Column != 5 || first.Source != ConsoleSourceConsole {
+		t.Errorf("控制台消息不正确: %+v", first)
+	}
+	if exception := summary.Messages[3]; exception.Source != ConsoleSourceException || exception.Text != "TypeError: x is undefined" {
+		t.Errorf("异常消息不正确: %+v", exception)
+	}
+	if debug := summary.Messages[4]; debug.Level != ConsoleLevelDebug || debug.Source != "intervention" {
+		t.Errorf("日志消息不正确: %+v", debug)
+	}
+	if failed := summary.Messages[5]; failed.URL != "https://cdn.example.com/a.css" || failed.Source != ConsoleSourceNetwork {
+		t.Errorf("资源加载失败消息不正确: %+v", failed)
+	}
+	if notFound := summary.Messages[6]; notFound.Level != ConsoleLevelError || !strings.Contains(notFound.Text, "404") {
+		t.Errorf("HTTP错误消息不正确: %+v", notFound)
+	}
+}
+
+// TestConsoleRecorderLimits 测试消息数量和长度限制
+func TestConsoleRecorderLimits(t *testing.T) {
+	r := newTestConsoleRecorder()
+	long := `"` + strings.Repeat("x", MaxConsoleTextLength+100) + `"`
+	for i := 0; i < MaxConsoleMessages+5; i++ {
+		r.handleEvent(&runtime.EventConsoleAPICalled{Type: runtime.APITypeError, Args: []*runtime.RemoteObject{{Value: []byte(long)}}})
+	}
+
+	summary := r.result()
+	if summary.Errors != MaxConsoleMessages+5 || summary.Dropped != 5 || len(summary.Messages) != MaxConsoleMessages {
+		t.Errorf("限制不正确: errors=%d dropped=%d messages=%d", summary.Errors, summary.Dropped, len(summary.Messages))
+	}
+	if len(summary.Messages[0].Text) != MaxConsoleTextLength+3 {
+		t.Errorf("消息应被截断: %d", len(summary.Messages[0].Text))
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图视觉对比 - 像素差异、感知哈希距离和差异高亮图，纯Go实现
+ */
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+)
+
+// 对比参数
+const (
+	// DiffPixelThreshold 单通道差值超过该值才视为像素变化，过滤抗锯齿和压缩噪点
+	DiffPixelThreshold = 24
+	// diffBlockSize 变化区域按块合并后描边
+	diffBlockSize = 32
+	// MaxDiffPixels 参与对比的图片和对比画布的最大像素数，对比时每像素约占用12字节内存
+	MaxDiffPixels = 25_000_000
+)
+
+var (
+	diffHighlightColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	diffOutlineColor   = color.RGBA{R: 255, G: 140, B: 0, A: 255}
+)
+
+// imageComparison 两张截图的对比结果
+type imageComparison struct {
+	ChangedPixels  int
+	TotalPixels    int
+	DiffPercent    float64
+	HashDistance   int
+	ChangedRegions int
+	Diff           *image.RGBA
+}
+
+// compareImages 逐像素对比两张图片，尺寸不同时以较大的画布对比，超出部分视为变化
+// 差异图以淡化的新图为底，变化像素标红，包含变化的区块描边
+func compareImages(before, after image.Image) *imageComparison {
+	a := toRGBA(before)
+	b := toRGBA(after)
+
+	width := max(a.Bounds().Dx(), b.Bounds().Dx())
+	height := max(a.Bounds().Dy(), b.Bounds().Dy())
+	diff := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	blocksX := (width + diffBlockSize - 1) / diffBlockSize
+	blocksY := (height + diffBlockSize - 1) / diffBlockSize
+	changedBlocks := make([]bool, blocksX*blocksY)
+
+	result := &imageComparison{TotalPixels: width * height}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pa, okA := pixelAt(a, x, y)
+			pb, okB := pixelAt(b, x, y)
+
+			if okA && okB && !pixelChanged(pa, pb) {
+				diff.SetRGBA(x, y, fadePixel(pb))
+				continue
+			}
+
+			result.ChangedPixels++
+			diff.SetRGBA(x, y, diffHighlightColor)
+			changedBlocks[(y/diffBlockSize)*blocksX+x/diffBlockSize] = true
+		}
+	}
+
+	for i, changed := range changedBlocks {
+		if changed {
+			result.ChangedRegions++
+			outlineBlock(diff, (i%blocksX)*diffBlockSize, (i/blocksX)*diffBlockSize)
+		}
+	}
+
+	if result.TotalPixels > 0 {
+		result.DiffPercent = float64(result.ChangedPixels) * 100 / float64(result.TotalPixels)
+	}
+	result.HashDistance = bits.OnesCount64(differenceHash(a) ^ differenceHash(b))
+	result.Diff = diff
+	return result
+}
+
+// differenceHash 计算64位dHash：缩放为9x8灰度图后比较相邻像素亮度
+func differenceHash(img image.Image) uint64 {
+	small := resizeImage(img, 9, 8)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(small.RGBAAt(x, y)) > luminance(small.RGBAAt(x+1, y)) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+func pixelAt(img *image.RGBA, x, y int) (color.RGBA, bool) {
+	if !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+		return color.RGBA{}, false
+	}
+	return img.RGBAAt(x, y), true
+}
+
+func pixelChanged(a, b color.RGBA) bool {
+	return absDiff(a.R, b.R) > DiffPixelThreshold ||
+		absDiff(a.G, b.G) > DiffPixelThreshold ||
+		absDiff(a.B, b.B) > DiffPixelThreshold ||
+		absDiff(a.A, b.A) > DiffPixelThreshold
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// fadePixel 将未变化的像素与白色混合，突出高亮区域
+func fadePixel(c color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8((uint16(c.R) + 3*255) / 4),
+		G: uint8((uint16(c.G) + 3*255) / 4),
+		B: uint8((uint16(c.B) + 3*255) / 4),
+		A: 255,
+	}
+}
+
+func luminance(c color.RGBA) uint32 {
+	return (299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000
+}
+
+// outlineBlock 为包含变化的区块描边
+func outlineBlock(img *image.RGBA, x0, y0 int) {
+	bounds := img.Bounds()
+	x1 := min(x0+diffBlockSize, bounds.Max.X) - 1
+	y1 := min(y0+diffBlockSize, bounds.Max.Y) - 1
+	for x := x0; x <= x1; x++ {
+		img.SetRGBA(x, y0, diffOutlineColor)
+		img.SetRGBA(x, y1, diffOutlineColor)
+	}
+	for y := y0; y <= y1; y++ {
+		img.SetRGBA(x0, y, diffOutlineColor)
+		img.SetRGBA(x1, y, diffOutlineColor)
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func solidImage(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// TestCompareImages 测试像素差异比例、变化区域和差异图尺寸
+func TestCompareImages(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	before := solidImage(64, 64, white)
+
+	same := compareImages(before, solidImage(64, 64, white))
+	if same.ChangedPixels != 0 || same.DiffPercent != 0 || same.HashDistance != 0 || same.ChangedRegions != 0 {
+		t.Errorf("相同图片不应有差异: %+v", same)
+	}
+
+	after := solidImage(64, 64, white)
+	for y := 0; y < 64; y++ {
+		for x := 48; x < 64; x++ {
+			after.SetRGBA(x, y, color.RGBA{A: 255})
+		}
+	}
+	changed := compareImages(before, after)
+	if changed.DiffPercent != 25 || changed.ChangedRegions != 2 {
+		t.Errorf("差异比例 = %.2f%%, 区域 = %d, 期望 25%%, 2", changed.DiffPercent, changed.ChangedRegions)
+	}
+	if changed.HashDistance == 0 {
+		t.Errorf("内容变化后感知哈希距离应大于0")
+	}
+	if got := changed.Diff.RGBAAt(56, 10); got != diffHighlightColor {
+		t.Errorf("变化像素应高亮, 实际 %v", got)
+	}
+
+	// 尺寸不同时超出部分视为变化
+	taller := compareImages(before, solidImage(64, 128, white))
+	if taller.DiffPercent != 50 || taller.Diff.Bounds().Dy() != 128 {
+		t.Errorf("尺寸变化的差异比例 = %.2f%%, 期望 50%%", taller.DiffPercent)
+	}
+}
+
+// TestSelectDiffPair 测试默认选择最近两次截图、指定ID、顺序交换和视口不同的截图
+func TestSelectDiffPair(t *testing.T) {
+	records := []*ScreenshotRecord{{ID: "c"}, {ID: "b"}, {ID: "a"}}
+
+	from, to, err := selectDiffPair(records, "", "")
+	if err != nil || from.ID != "b" || to.ID != "c" {
+		t.Errorf("默认应对比最近两次: %v, %v, %v", from, to, err)
+	}
+
+	from, to, err = selectDiffPair(records, "a", "")
+	if err != nil || from.ID != "a" || to.ID != "c" {
+		t.Errorf("指定from时应与最新一次对比: %v, %v, %v", from, to, err)
+	}
+
+	for _, ids := range [][2]string{{"", "a"}, {"x", ""}} {
+		if _, _, err := selectDiffPair(records, ids[0], ids[1]); !errors.Is(err, ErrHistoryNotFound) {
+			t.Errorf("from=%q to=%q 应返回ErrHistoryNotFound, 实际 %v", ids[0], ids[1], err)
+		}
+	}
+	if _, _, err := selectDiffPair(records[:1], "", ""); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("只有一次截图时应返回ErrHistoryNotFound, 实际 %v", err)
+	}
+
+	from, to, err = selectDiffPair(records, "c", "a")
+	if err != nil || from.ID != "a" || to.ID != "c" {
+		t.Errorf("from晚于to时应交换: %v, %v, %v", from, to, err)
+	}
+	if _, _, err := selectDiffPair(records, "b", "b"); !errors.Is(err, ErrDiffNotComparable) {
+		t.Errorf("与自身对比应返回ErrDiffNotComparable, 实际 %v", err)
+	}
+
+	mixed := []*ScreenshotRecord{
+		{ID: "c", Viewport: "1920x1080@1"},
+		{ID: "b", Viewport: "390x844@3,mobile", Device: "iphone-14"},
+		{ID: "a", Viewport: "1920x1080@1"},
+	}
+	from, to, err = selectDiffPair(mixed, "", "")
+	if err != nil || from.ID != "a" || to.ID != "c" {
+		t.Errorf("默认应跳过视口不同的截图: %v, %v, %v", from, to, err)
+	}
+	if _, _, err := selectDiffPair(mixed, "b", "c"); !errors.Is(err, ErrDiffNotComparable) {
+		t.Errorf("视口或设备不同时应返回ErrDiffNotComparable, 实际 %v", err)
+	}
+	if _, _, err := selectDiffPair(mixed, "", "b"); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("没有相同视口的更早截图时应返回ErrHistoryNotFound, 实际 %v", err)
+	}
+
+	setups := []*ScreenshotRecord{
+		{ID: "d", Format: ImagePNG, Setup: "a1b2"},
+		{ID: "c", Format: ImageJPEG, Setup: "a1b2"},
+		{ID: "b", Format: ImagePNG},
+		{ID: "a", Format: ImagePNG, Setup: "a1b2"},
+	}
+	from, to, err = selectDiffPair(setups, "", "")
+	if err != nil || from.ID != "a" || to.ID != "d" {
+		t.Errorf("默认应跳过格式或截图参数不同的截图: %v, %v, %v", from, to, err)
+	}
+	for _, id := range []string{"b", "c"} {
+		if _, _, err := selectDiffPair(setups, id, "d"); !errors.Is(err, ErrDiffNotComparable) {
+			t.Errorf("%s 与 d 的格式或截图参数不同，应返回ErrDiffNotComparable, 实际 %v", id, err)
+		}
+	}
+}
+
+// TestNewHistoryRecord 测试只有保存了文件且未使用登录会话的截图写入历史
+func TestNewHistoryRecord(t *testing.T) {
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	config := &ScreenshotConfig{
+		Type:     TypeBasic,
+		Domain:   "example.com",
+		FileKey:  "basic/example.com.png",
+		Viewport: &ViewportConfig{Width: 1920, Height: 1080, DeviceScaleFactor: 1},
+		Image:    &ImageOptions{Format: ImagePNG},
+	}
+	response := &ScreenshotResponse{ImageKey: config.FileKey, Metadata: map[string]interface{}{"size": 2048}}
+
+	record := newHistoryRecord(config, response, now)
+	if record == nil || record.Domain != "example.com" || record.ImageKey != config.FileKey || record.Size != 2048 || record.Setup != "" {
+		t.Fatalf("历史记录不正确: %+v", record)
+	}
+
+	// 改变页面内容的参数各自产生不同的签名
+	variants := []func(c *ScreenshotConfig){
+		func(c *ScreenshotConfig) { c.Actions = []*ScreenshotAction{{Action: ActionClick, Selector: "#accept"}} },
+		func(c *ScreenshotConfig) { c.HideCSS = ".banner{display:none!important}" },
+		func(c *ScreenshotConfig) { c.BlockAds = true },
+		func(c *ScreenshotConfig) { c.Region = &RegionConfig{Locale: "de-DE"} },
+		func(c *ScreenshotConfig) { c.Region = &RegionConfig{Proxy: &ScreenshotProxy{Name: "eu"}} },
+	}
+	seen := map[string]bool{}
+	for i, apply := range variants {
+		variant := *config
+		apply(&variant)
+		setup := newHistoryRecord(&variant, response, now).Setup
+		if setup == "" || seen[setup] {
+			t.Errorf("参数 %d 的签名应非空且互不相同: %q", i, setup)
+		}
+		seen[setup] = true
+	}
+	if setup := captureSetupSignature(&ScreenshotConfig{Region: &RegionConfig{}}); setup != "" {
+		t.Errorf("默认地区设置的签名应为空: %q", setup)
+	}
+
+	session := *config
+	session.Session = "account-a"
+	if record := newHistoryRecord(&session, response, now); record != nil {
+		t.Errorf("使用登录会话的截图不应写入共享历史: %+v", record)
+	}
+	base64 := *config
+	base64.FileKey = ""
+	if record := newHistoryRecord(&base64, response, now); record != nil {
+		t.Errorf("Base64输出不应写入历史: %+v", record)
+	}
+}
+
+// pngHeader 只包含文件头和IHDR的PNG，DecodeConfig可读出尺寸而无需生成完整图片
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 6 // 8位RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+// TestLoadImageSizeLimit 测试超过像素上限的截图在解码前被拒绝
+func TestLoadImageSizeLimit(t *testing.T) {
+	storage := NewMemoryStorage()
+	history := &ScreenshotHistory{config: DefaultScreenshotServiceConfig, storage: storage}
+	ctx := context.Background()
+
+	var small bytes.Buffer
+	png.Encode(&small, solidImage(4, 4, color.RGBA{A: 255}))
+	storage.Put(ctx, "screenshots/small.png", small.Bytes(), "image/png")
+	if img, err := history.loadImage(ctx, &ScreenshotRecord{ID: "small", ImageKey: "screenshots/small.png", Format: ImagePNG}); err != nil || img.Bounds().Dx() != 4 {
+		t.Errorf("读取正常截图失败: %v", err)
+	}
+
+	storage.Put(ctx, "screenshots/huge.png", pngHeader(10000, 10000), "image/png")
+	if _, err := history.loadImage(ctx, &ScreenshotRecord{ID: "huge", ImageKey: "screenshots/huge.png", Format: ImagePNG}); !errors.Is(err, ErrDiffNotComparable) {
+		t.Errorf("超过像素上限的截图应返回ErrDiffNotComparable, 实际 %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 页面内容提取 - 在截图的同一页面中提取标题、元标签、外链、技术栈和HTML/文本
+ */
+package services
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chromedp/chromedp"
+)
+
+//go:embed scripts/extract_content.js
+var extractContentScript string
+
+// 内容提取参数限制
+const (
+	MaxExtractLinks    = 500
+	MaxExtractHTMLSize = 2 * 1024 * 1024
+	MaxExtractTextSize = 512 * 1024
+)
+
+// ExtractMode extract参数，JSON中同时接受布尔值和字符串
+type ExtractMode string
+
+// UnmarshalJSON 将true/false转换为对应的字符串
+func (m *ExtractMode) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*m = ExtractMode(strconv.FormatBool(enabled))
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("extract必须是布尔值或字符串")
+	}
+	*m = ExtractMode(value)
+	return nil
+}
+
+// ExtractOptions 内容提取配置
+type ExtractOptions struct {
+	HTML bool `json:"includeHTML"`
+	Text bool `json:"includeText"`
+}
+
+// PageLink 页面中的外部链接
+type PageLink struct {
+	URL  string `json:"url"`
+	Host string `json:"host"`
+	Text string `json:"text,omitempty"`
+}
+
+// PageTechnology 检测到的前端技术
+type PageTechnology struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// PageContent 提取的页面内容
+type PageContent struct {
+	Title        string            `json:"title"`
+	Description  string            `json:"description,omitempty"`
+	Canonical    string            `json:"canonical,omitempty"`
+	Generator    string            `json:"generator,omitempty"`
+	Language     string            `json:"language,omitempty"`
+	OpenGraph    map[string]string `json:"open_graph,omitempty"`
+	Twitter      map[string]string `json:"twitter,omitempty"`
+	Links        []PageLink        `json:"links"`
+	Technologies []PageTechnology  `json:"technologies"`
+	HTML         string            `json:"html,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	Truncated    bool              `json:"truncated,omitempty"`
+}
+
+// parseExtractOptions 解析extract参数：true/meta只提取元信息，html、text可用逗号组合
+func parseExtractOptions(mode ExtractMode) (*ExtractOptions, error) {
+	value := strings.ToLower(strings.TrimSpace(string(mode)))
+	if value == "" || value == "false" {
+		return nil, nil
+	}
+
+	opts := &ExtractOptions{}
+	for _, part := range strings.Split(value, ",") {
+		switch strings.TrimSpace(part) {
+		case "true", "meta":
+		case "html":
+			opts.HTML = true
+		case "text":
+			opts.Text = true
+		default:
+			return nil, fmt.Errorf("不支持的extract选项: %s（可选: true、meta、html、text）", part)
+		}
+	}
+	return opts, nil
+}
+
+// extractContent 在当前页面执行提取脚本
+func extractContent(opts *ExtractOptions, content **PageContent) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		args, err := json.Marshal(struct {
+			*ExtractOptions
+			MaxLinks int `json:"maxLinks"`
+		}{opts, MaxExtractLinks})
+		if err != nil {
+			return err
+		}
+
+		var result PageContent
+		script := fmt.Sprintf("(%s)(%s)", strings.TrimSpace(extractContentScript), args)
+		if err := chromedp.Evaluate(script, &result).Do(ctx); err != nil {
+			return fmt.Errorf("提取页面内容失败: %v", err)
+		}
+
+		if len(result.HTML) > MaxExtractHTMLSize {
+			result.HTML = truncateUTF8(result.HTML, MaxExtractHTMLSize)
+			result.Truncated = true
+		}
+		if len(result.Text) > MaxExtractTextSize {
+			result.Text = truncateUTF8(result.Text, MaxExtractTextSize)
+			result.Truncated = true
+		}
+		*content = &result
+		return nil
+	})
+}
+
+// truncateUTF8 按字节截断且不截断多字节字符
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestParseExtractOptions 测试extract参数解析
+func TestParseExtractOptions(t *testing.T) {
+	for _, mode := range []ExtractMode{"", "false"} {
+		if opts, err := parseExtractOptions(mode); opts != nil || err != nil {
+			t.Errorf("%q 不应启用提取: %+v, %v", mode, opts, err)
+		}
+	}
+
+	opts, err := parseExtractOptions("true")
+	if err != nil || opts == nil || opts.HTML || opts.Text {
+		t.Errorf("true应只提取元信息: %+v, %v", opts, err)
+	}
+
+	opts, err = parseExtractOptions("meta, HTML,text")
+	if err != nil || !opts.HTML || !opts.Text {
+		t.Errorf("应同时提取HTML和文本: %+v, %v", opts, err)
+	}
+
+	if _, err := parseExtractOptions("cookies"); err == nil {
+		t.Error("未知选项应校验失败")
+	}
+}
+
+// TestExtractModeUnmarshal 测试JSON中的布尔值和字符串
+func TestExtractModeUnmarshal(t *testing.T) {
+	cases := map[string]ExtractMode{
+		`{"extract": true}`:   "true",
+		`{"extract": false}`:  "false",
+		`{"extract": "text"}`: "text",
+	}
+	for body, expected := range cases {
+		var req ScreenshotRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil || req.Extract != expected {
+			t.Errorf("%s: 期望 %q, 实际 %q, %v", body, expected, req.Extract, err)
+		}
+	}
+
+	var req ScreenshotRequest
+	if err := json.Unmarshal([]byte(`{"extract": 1}`), &req); err == nil {
+		t.Error("数字应解析失败")
+	}
+}
+
+// TestTruncateUTF8 测试截断不拆分多字节字符
+func TestTruncateUTF8(t *testing.T) {
+	s := strings.Repeat("中", 4)
+	if got := truncateUTF8(s, 7); got != "中中" {
+		t.Errorf("期望截断为两个字符, 实际 %q", got)
+	}
+	if got := truncateUTF8(s, 100); got != s {
+		t.Errorf("未超限时不应截断: %q", got)
+	}
+}
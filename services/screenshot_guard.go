@@ -0,0 +1,113 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图导航防护 - 浏览器发出的每个请求（含跳转和子资源）按出站策略检查目标地址
+ */
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"whosee/utils"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// 导航防护参数
+const (
+	guardLookupTimeout = 5 * time.Second // 单个主机名的解析超时
+	maxDeniedRecorded  = 10              // 元数据中最多记录的被拒绝URL数
+)
+
+// navigationGuard 单次截图任务的请求防护，同一任务内缓存主机的检查结果
+// 这里的检查用于尽早拒绝请求并记录被拒绝的URL；Chrome在连接时会重新解析域名，
+// 实际连接由guardProxy按解析后的地址再次检查，DNS重绑定、WebSocket和子框架、Worker的请求都无法绕过
+type navigationGuard struct {
+	policy *utils.NetworkPolicy
+
+	mu       sync.Mutex
+	hosts    map[string]bool // 主机名 -> 是否允许
+	denied   []string
+	document string // 被拒绝的文档请求，导航失败时用于说明原因
+	count    int64
+}
+
+func newNavigationGuard(policy *utils.NetworkPolicy) *navigationGuard {
+	return &navigationGuard{policy: policy, hosts: make(map[string]bool)}
+}
+
+// allow 检查请求是否允许发出：http(s)/ws(s)检查主机地址，data、blob和about页面不产生网络连接直接放行，其余协议拒绝
+// 主机名解析失败时同样拒绝，无法确认地址的主机不允许访问
+func (g *navigationGuard) allow(ctx context.Context, e *fetch.EventRequestPaused) bool {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return g.deny(e)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "data", "blob", "about":
+		return true
+	case "http", "https", "ws", "wss":
+	default:
+		return g.deny(e)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	g.mu.Lock()
+	allowed, cached := g.hosts[host]
+	g.mu.Unlock()
+	if !cached {
+		lookupCtx, cancel := context.WithTimeout(ctx, guardLookupTimeout)
+		err := g.policy.CheckHost(lookupCtx, host)
+		cancel()
+		allowed = err == nil
+		g.mu.Lock()
+		g.hosts[host] = allowed
+		g.mu.Unlock()
+	}
+	if !allowed {
+		return g.deny(e)
+	}
+	return true
+}
+
+// deny 记录被拒绝的请求
+func (g *navigationGuard) deny(e *fetch.EventRequestPaused) bool {
+	atomic.AddInt64(&g.count, 1)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.denied) < maxDeniedRecorded {
+		g.denied = append(g.denied, e.Request.URL)
+	}
+	if e.ResourceType == network.ResourceTypeDocument && g.document == "" {
+		g.document = e.Request.URL
+	}
+	return false
+}
+
+// blockedDocument 被拒绝的文档请求，没有时为空
+func (g *navigationGuard) blockedDocument() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.document
+}
+
+// metadata 被拒绝的请求数和URL，没有被拒绝的请求时返回nil
+func (g *navigationGuard) metadata() map[string]interface{} {
+	count := atomic.LoadInt64(&g.count)
+	if count == 0 {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return map[string]interface{}{
+		"count": count,
+		"urls":  append([]string(nil), g.denied...),
+	}
+}
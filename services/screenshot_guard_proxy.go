@@ -0,0 +1,370 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图出站代理 - 浏览器上下文的全部连接经本机代理发出，按出站策略检查解析后的地址并固定连接目标
+ */
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"whosee/utils"
+
+	"golang.org/x/net/proxy"
+)
+
+// 出站代理参数
+const (
+	guardDialTimeout = 10 * time.Second // 连接目标或上游代理的超时
+	guardIdleTimeout = 30 * time.Second // 转发HTTP请求时空闲连接的保留时间
+	guardProxyUser   = "whosee"         // 监听非回环地址时浏览器使用的代理用户名
+	guardDialedLimit = 4096             // 记录的目标连接地址上限，超过后清空重新记录
+)
+
+// hopHeaders 转发HTTP请求时需要移除的逐跳头
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// guardProxy 监听在回环地址上的HTTP代理，浏览器上下文的代理服务器指向它
+// Fetch拦截看不到WebSocket握手，也可能漏掉跨进程子框架和Worker的请求，代理在建立连接时检查，所有请求都经过这里；
+// 主机名只解析一次，检查通过后直接连接解析得到的IP（经上游代理时以IP发起CONNECT），DNS重绑定无法在检查后更换地址
+type guardProxy struct {
+	policy    *utils.NetworkPolicy
+	upstream  *ScreenshotProxy // 上游命名代理，为nil时直连
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+	advertise string // 浏览器访问代理使用的主机名，为空时使用监听地址
+	token     string // 代理认证口令，监听非回环地址时生成，防止其他主机借用代理
+
+	dialedMu sync.Mutex
+	dialed   map[string]string // 目标host:port -> 最近一次实际连接的IP，供HAR记录服务器地址
+}
+
+// newGuardProxy 在listenHost（为空时为127.0.0.1）的随机端口上启动出站代理
+func newGuardProxy(policy *utils.NetworkPolicy, upstream *ScreenshotProxy, listenHost, advertise string) (*guardProxy, error) {
+	if listenHost == "" {
+		listenHost = "127.0.0.1"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenHost, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("启动出站代理失败: %v", err)
+	}
+
+	p := &guardProxy{policy: policy, upstream: upstream, listener: listener, advertise: advertise, dialed: make(map[string]string)}
+	if ip := net.ParseIP(listenHost); ip == nil || !ip.IsLoopback() {
+		secret := make([]byte, 16)
+		rand.Read(secret)
+		p.token = hex.EncodeToString(secret)
+	}
+	p.transport = &http.Transport{
+		DialContext:        p.dialTarget,
+		DisableCompression: true,
+		IdleConnTimeout:    guardIdleTimeout,
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: guardDialTimeout}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[NETGUARD] 出站代理退出: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// URL 浏览器上下文使用的代理服务器地址
+func (p *guardProxy) URL() string {
+	if p.advertise == "" {
+		return "http://" + p.listener.Addr().String()
+	}
+	_, port, _ := net.SplitHostPort(p.listener.Addr().String())
+	return "http://" + net.JoinHostPort(p.advertise, port)
+}
+
+// credentials 浏览器连接代理时使用的凭据，不需要认证时为nil
+func (p *guardProxy) credentials() *ScreenshotProxy {
+	if p.token == "" {
+		return nil
+	}
+	return &ScreenshotProxy{Name: "guard", Scheme: "http", Server: p.URL(), Username: guardProxyUser, Password: p.token}
+}
+
+// Close 停止代理并关闭空闲连接
+func (p *guardProxy) Close() error {
+	p.transport.CloseIdleConnections()
+	return p.server.Close()
+}
+
+func (p *guardProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.token != "" && r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(guardProxyUser+":"+p.token)) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="whosee"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Scheme != "http" || r.URL.Host == "" {
+		http.Error(w, "unsupported proxy request", http.StatusBadRequest)
+		return
+	}
+	p.forward(w, r)
+}
+
+// tunnel 处理CONNECT请求，HTTPS、WSS以及经代理的WS连接都通过隧道发出
+func (p *guardProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	target, err := p.dialTarget(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.reject(w, r.Host, err)
+		return
+	}
+	defer target.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(target, buffered)
+		closeWrite(target)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, target)
+		closeWrite(client)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
+
+// forward 转发明文HTTP请求，连接同样经过dialTarget检查
+func (p *guardProxy) forward(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, header := range hopHeaders {
+		out.Header.Del(header)
+	}
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		p.reject(w, r.URL.Host, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// reject 连接被拒绝或失败时返回错误响应，Chrome据此以隧道连接失败结束请求
+func (p *guardProxy) reject(w http.ResponseWriter, host string, err error) {
+	if errors.Is(err, utils.ErrAddressBlocked) {
+		log.Printf("[NETGUARD] 拒绝浏览器连接 %s: %v", host, err)
+		http.Error(w, "blocked by outbound policy", http.StatusForbidden)
+		return
+	}
+	http.Error(w, "upstream connection failed", http.StatusBadGateway)
+}
+
+// dialTarget 解析并检查目标主机，依次连接检查通过的地址；解析失败时拒绝连接
+func (p *guardProxy) dialTarget(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", utils.ErrAddressBlocked, address)
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, guardLookupTimeout)
+	addrs, err := p.policy.ResolveHost(lookupCtx, host)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := p.dialAddr(ctx, addr.String(), port)
+		if err == nil {
+			p.recordDialed(address, addr.String())
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// recordDialed 记录目标实际连接的IP，浏览器经代理访问时Chrome报告的服务器地址是代理本身
+func (p *guardProxy) recordDialed(address, ip string) {
+	p.dialedMu.Lock()
+	defer p.dialedMu.Unlock()
+	if len(p.dialed) >= guardDialedLimit {
+		p.dialed = make(map[string]string)
+	}
+	p.dialed[address] = ip
+}
+
+// dialedIP 返回目标host:port最近一次实际连接的IP，没有记录时为空
+func (p *guardProxy) dialedIP(address string) string {
+	p.dialedMu.Lock()
+	defer p.dialedMu.Unlock()
+	return p.dialed[address]
+}
+
+// dialAddr 连接已检查的IP：直连时由NetworkPolicy.Control在建立连接前再次检查，经上游代理时以IP发起连接
+func (p *guardProxy) dialAddr(ctx context.Context, ip, port string) (net.Conn, error) {
+	target := net.JoinHostPort(ip, port)
+	if p.upstream == nil {
+		dialer := &net.Dialer{Timeout: guardDialTimeout, Control: p.policy.Control}
+		return dialer.DialContext(ctx, "tcp", target)
+	}
+
+	dialer := &net.Dialer{Timeout: guardDialTimeout}
+	upstream := strings.TrimPrefix(p.upstream.Server, p.upstream.Scheme+"://")
+	if p.upstream.Scheme == "socks5" {
+		var auth *proxy.Auth
+		if p.upstream.needsAuth() {
+			auth = &proxy.Auth{User: p.upstream.Username, Password: p.upstream.Password}
+		}
+		socks, err := proxy.SOCKS5("tcp", upstream, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", target)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", upstream)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理 %s 失败: %v", p.upstream.Name, err)
+	}
+	if p.upstream.Scheme == "https" {
+		serverName, _, _ := net.SplitHostPort(upstream)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("连接代理 %s 失败: %v", p.upstream.Name, err)
+		}
+		conn = tlsConn
+	}
+	tunnel, err := p.connectUpstream(conn, target)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// connectUpstream 通过上游HTTP代理建立到target的隧道，代理需要认证时附带Basic凭据
+func (p *guardProxy) connectUpstream(conn net.Conn, target string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(guardDialTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", target, target)
+	if p.upstream.needsAuth() {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.upstream.Username + ":" + p.upstream.Password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return nil, fmt.Errorf("连接代理 %s 失败: %v", p.upstream.Name, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, fmt.Errorf("连接代理 %s 失败: %v", p.upstream.Name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		log.Printf("[SCREENSHOT] 代理 %s 认证失败", p.upstream.Name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("代理 %s 拒绝连接: %s", p.upstream.Name, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// bufferedConn 读取CONNECT响应后reader中可能已缓冲了隧道数据，读操作先消费缓冲区
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// closeWrite 半关闭连接的写方向，另一方向的数据仍可继续传输
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		c.CloseWrite()
+		return
+	}
+	conn.Close()
+}
+
+// guardProxySet 按上游代理复用的出站代理，代理地址在进程内保持不变，持久会话创建时记录的代理地址后续任务仍然有效
+type guardProxySet struct {
+	policy    *utils.NetworkPolicy
+	listen    string // 监听地址，为空时只监听回环地址
+	advertise string // 远程浏览器访问出站代理使用的主机名
+	mu        sync.Mutex
+	proxies   map[string]*guardProxy // 上游代理名称 -> 出站代理，直连为空字符串
+}
+
+func newGuardProxySet(policy *utils.NetworkPolicy, listen, advertise string) *guardProxySet {
+	return &guardProxySet{policy: policy, listen: listen, advertise: advertise, proxies: make(map[string]*guardProxy)}
+}
+
+// proxyFor 返回经指定上游代理发出连接的出站代理，首次使用时启动
+func (s *guardProxySet) proxyFor(upstream *ScreenshotProxy) (*guardProxy, error) {
+	name := ""
+	if upstream != nil {
+		name = upstream.Name
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.proxies[name]; ok {
+		return p, nil
+	}
+	p, err := newGuardProxy(s.policy, upstream, s.listen, s.advertise)
+	if err != nil {
+		return nil, err
+	}
+	s.proxies[name] = p
+	return p, nil
+}
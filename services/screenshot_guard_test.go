@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"whosee/utils"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// TestNavigationGuard 测试浏览器请求的协议和地址检查，以及被拒绝文档的记录
+func TestNavigationGuard(t *testing.T) {
+	guard := newNavigationGuard(utils.NewNetworkPolicy(nil, nil))
+	paused := func(url string, resourceType network.ResourceType) *fetch.EventRequestPaused {
+		return &fetch.EventRequestPaused{Request: &network.Request{URL: url}, ResourceType: resourceType}
+	}
+	ctx := context.Background()
+
+	for _, url := range []string{"https://8.8.8.8/", "data:image/png;base64,AAAA", "about:blank"} {
+		if !guard.allow(ctx, paused(url, network.ResourceTypeImage)) {
+			t.Errorf("%s 应被允许", url)
+		}
+	}
+	if guard.metadata() != nil || guard.blockedDocument() != "" {
+		t.Error("没有被拒绝的请求时不应有记录")
+	}
+
+	// 跳转到元数据地址的文档请求
+	if guard.allow(ctx, paused("http://169.254.169.254/latest/meta-data/", network.ResourceTypeDocument)) {
+		t.Error("元数据地址应被拒绝")
+	}
+	for _, url := range []string{"http://127.0.0.1:6379/", "file:///etc/passwd", "ws://[::1]:9222/devtools", "https://unresolvable.invalid/"} {
+		if guard.allow(ctx, paused(url, network.ResourceTypeXHR)) {
+			t.Errorf("%s 应被拒绝", url)
+		}
+	}
+
+	if document := guard.blockedDocument(); document != "http://169.254.169.254/latest/meta-data/" {
+		t.Errorf("应记录被拒绝的文档请求: %q", document)
+	}
+	meta := guard.metadata()
+	if meta == nil || meta["count"] != int64(5) || len(meta["urls"].([]string)) != 5 {
+		t.Errorf("被拒绝请求的元数据不正确: %v", meta)
+	}
+}
+
+// connectThrough 通过代理发起CONNECT请求，返回代理的响应状态码
+func connectThrough(t *testing.T, proxyURL, target string) int {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+// TestGuardProxy 测试出站代理在连接时按策略检查目标地址，解析失败的主机同样拒绝
+func TestGuardProxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer target.Close()
+	targetHost := strings.TrimPrefix(target.URL, "http://")
+
+	blocking, err := newGuardProxy(utils.NewNetworkPolicy(nil, nil), nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocking.Close()
+	for _, host := range []string{targetHost, "localhost:80"} {
+		if code := connectThrough(t, blocking.URL(), host); code != http.StatusForbidden {
+			t.Errorf("CONNECT %s 应被拒绝: %d", host, code)
+		}
+	}
+	if code := connectThrough(t, blocking.URL(), "unresolvable.invalid:443"); code != http.StatusBadGateway {
+		t.Errorf("解析失败的主机不应建立隧道: %d", code)
+	}
+	proxyURL, _ := url.Parse(blocking.URL())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	if resp, err := client.Get(target.URL); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("明文HTTP请求应被拒绝: %v, %v", resp, err)
+	}
+
+	allowing, err := newGuardProxy(utils.NewNetworkPolicy([]string{"127.0.0.1"}, nil), nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer allowing.Close()
+	if code := connectThrough(t, allowing.URL(), targetHost); code != http.StatusOK {
+		t.Errorf("允许列表中的地址应建立隧道: %d", code)
+	}
+	if ip := allowing.dialedIP(targetHost); ip != "127.0.0.1" {
+		t.Errorf("应记录实际连接的IP: %q", ip)
+	}
+	proxyURL, _ = url.Parse(allowing.URL())
+	client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Get(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("转发的响应不正确: %q", body)
+	}
+}
+
+// TestGuardProxyUpstream 测试经上游代理时以检查后的IP发起CONNECT并附带代理凭据
+func TestGuardProxyUpstream(t *testing.T) {
+	connected := make(chan string, 2)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		connected <- r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	guard, err := newGuardProxy(utils.NewNetworkPolicy(nil, nil), &ScreenshotProxy{
+		Name: "us", Scheme: "http", Server: upstream.URL, Username: "user", Password: "pass",
+	}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer guard.Close()
+
+	if code := connectThrough(t, guard.URL(), "8.8.8.8:443"); code != http.StatusOK || <-connected != "8.8.8.8:443" {
+		t.Errorf("应经上游代理连接检查后的地址: %d", code)
+	}
+	if code := connectThrough(t, guard.URL(), "169.254.169.254:80"); code != http.StatusForbidden || len(connected) != 0 {
+		t.Errorf("经上游代理时同样应拒绝内网地址: %d", code)
+	}
+}
+
+// serveSOCKS5 接受一个SOCKS5连接，要求用户名密码认证，把请求连接的目标地址写入connected
+func serveSOCKS5(listener net.Listener, user, pass string, connected chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// 协商认证方式：版本、方法数、方法列表
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	io.ReadFull(r, methods)
+	conn.Write([]byte{5, 2})
+
+	// RFC 1929 用户名密码认证
+	readField := func() string {
+		n, _ := r.ReadByte()
+		field := make([]byte, n)
+		io.ReadFull(r, field)
+		return string(field)
+	}
+	r.ReadByte()
+	if readField() != user || readField() != pass {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	// CONNECT请求：版本、命令、保留、IPv4地址和端口
+	request := make([]byte, 10)
+	if _, err := io.ReadFull(r, request); err != nil || request[3] != 1 {
+		return
+	}
+	connected <- net.JoinHostPort(net.IP(request[4:8]).String(), fmt.Sprint(int(request[8])<<8|int(request[9])))
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+}
+
+// TestGuardProxySOCKS5 测试经带凭据的SOCKS5上游代理时认证并以检查后的IP发起连接
+func TestGuardProxySOCKS5(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	connected := make(chan string, 1)
+	go serveSOCKS5(listener, "user", "pass", connected)
+
+	guard, err := newGuardProxy(utils.NewNetworkPolicy(nil, nil), &ScreenshotProxy{
+		Name: "eu", Scheme: "socks5", Server: "socks5://" + listener.Addr().String(), Username: "user", Password: "pass",
+	}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer guard.Close()
+
+	if code := connectThrough(t, guard.URL(), "8.8.8.8:443"); code != http.StatusOK || <-connected != "8.8.8.8:443" {
+		t.Errorf("应经SOCKS5代理认证后连接检查后的地址: %d", code)
+	}
+}
+
+// TestContextProxyRemote 测试远程浏览器后端的代理选择：未配置可访问的主机名时不使用本机出站代理，
+// 配置后出站代理监听指定地址、使用该主机名并要求认证
+func TestContextProxyRemote(t *testing.T) {
+	us := &ScreenshotProxy{Name: "us", Scheme: "http", Server: "http://10.0.0.1:3128", Username: "user", Password: "pass"}
+
+	local := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 1})
+	if server, auth, err := local.contextProxy(nil); err != nil || !strings.HasPrefix(server, "http://127.0.0.1:") || auth != nil {
+		t.Errorf("本地浏览器应使用回环地址上的出站代理: %s, %v, %v", server, auth, err)
+	}
+
+	remote := NewChromeManager(&ChromeManagerConfig{MaxConcurrent: 1, RemoteEndpoints: []string{"ws://chrome:9222"}})
+	if server, auth, err := remote.contextProxy(nil); err != nil || server != "" || auth != nil {
+		t.Errorf("远程浏览器未配置主机名时应直连: %s, %v, %v", server, auth, err)
+	}
+	if server, auth, err := remote.contextProxy(us); err != nil || server != us.Server || auth != us {
+		t.Errorf("远程浏览器未配置主机名时应直接使用上游代理: %s, %v, %v", server, auth, err)
+	}
+	if remote.serverIPLookup(nil) != nil || remote.serverIPLookup(us)("example.com:443") != "" {
+		t.Error("远程浏览器直连时应使用Chrome报告的地址，直接使用上游代理时不记录地址")
+	}
+	if _, _, err := remote.contextProxy(&ScreenshotProxy{Name: "eu", Scheme: "socks5", Server: "socks5://10.0.0.2:1080", Username: "u"}); err == nil {
+		t.Error("远程浏览器无法对SOCKS5代理认证，应拒绝")
+	}
+
+	advertised := NewChromeManager(&ChromeManagerConfig{
+		MaxConcurrent:       1,
+		RemoteEndpoints:     []string{"ws://chrome:9222"},
+		GuardProxyListen:    "0.0.0.0",
+		GuardProxyAdvertise: "whosee-api",
+	})
+	server, auth, err := advertised.contextProxy(us)
+	if err != nil || !strings.HasPrefix(server, "http://whosee-api:") || auth == nil || auth.Username != guardProxyUser {
+		t.Fatalf("配置主机名后应使用出站代理并要求认证: %s, %v, %v", server, auth, err)
+	}
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server, "http://"))
+	if code := connectThrough(t, "http://127.0.0.1:"+port, "169.254.169.254:80"); code != http.StatusProxyAuthRequired {
+		t.Errorf("没有凭据时应要求代理认证: %d", code)
+	}
+}
@@ -0,0 +1,475 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图网络请求记录 - 通过CDP Network事件记录页面加载期间的全部请求并导出HAR 1.2
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// MaxHAREntries 单次截图最多记录的请求数，防止异常页面无限请求占用内存
+const MaxHAREntries = 2000
+
+const harPageID = "page_1"
+
+// HAR HAR 1.2 文档
+type HAR struct {
+	Log *HARLog `json:"log"`
+}
+
+// HARLog HAR日志
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Pages   []HARPage   `json:"pages"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator 生成HAR的程序
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage 页面信息
+type HARPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings 页面事件相对页面开始的毫秒数，-1表示未触发
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry 单个请求
+type HAREntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest 请求信息
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse 响应信息，未收到响应时status为0
+type HARResponse struct {
+	Status       int            `json:"status"`
+	StatusText   string         `json:"statusText"`
+	HTTPVersion  string         `json:"httpVersion"`
+	Cookies      []HARNameValue `json:"cookies"`
+	Headers      []HARNameValue `json:"headers"`
+	Content      HARContent     `json:"content"`
+	RedirectURL  string         `json:"redirectURL"`
+	HeadersSize  int            `json:"headersSize"`
+	BodySize     int            `json:"bodySize"`
+	TransferSize int            `json:"_transferSize"`
+}
+
+// HARContent 响应内容，只记录大小和类型，不保存响应体
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings 请求各阶段耗时（毫秒），-1表示该阶段未发生
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARNameValue 名称-值对
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harPending 记录中的请求
+type harPending struct {
+	entry    *HAREntry
+	start    float64 // 请求发出时的单调时间（秒）
+	timing   *network.ResourceTiming
+	finished bool
+}
+
+// harRecorder 监听单个标签页的网络事件，生成HAR
+type harRecorder struct {
+	mu        sync.Mutex
+	pageURL   string
+	startedAt time.Time
+	start     float64
+	pending   map[network.RequestID]*harPending
+	entries   []*harPending
+	dropped   int
+	onContent float64
+	onLoad    float64
+	serverIP  func(address string) string // 经代理访问时按host:port查询实际连接的IP，为nil时使用Chrome报告的地址
+}
+
+// newHARRecorder 创建记录器并注册事件监听，需要在导航之前调用
+func newHARRecorder(ctx context.Context, pageURL string, serverIP func(address string) string) *harRecorder {
+	r := &harRecorder{
+		pageURL:   pageURL,
+		serverIP:  serverIP,
+		pending:   make(map[network.RequestID]*harPending),
+		onContent: -1,
+		onLoad:    -1,
+	}
+	chromedp.ListenTarget(ctx, r.handleEvent)
+	return r
+}
+
+func (r *harRecorder) handleEvent(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// 重定向复用同一个RequestID，先结束上一跳
+		if p, ok := r.pending[e.RequestID]; ok && e.RedirectResponse != nil {
+			p.applyResponse(e.RedirectResponse, r.remoteIP(e.RedirectResponse))
+			p.entry.Response.RedirectURL = e.Request.URL
+			p.finish(monotonicSeconds(e.Timestamp), e.RedirectResponse.EncodedDataLength)
+			delete(r.pending, e.RequestID)
+		}
+		r.addRequest(e)
+	case *network.EventResponseReceived:
+		if p, ok := r.pending[e.RequestID]; ok {
+			p.applyResponse(e.Response, r.remoteIP(e.Response))
+		}
+	case *network.EventDataReceived:
+		if p, ok := r.pending[e.RequestID]; ok {
+			p.entry.Response.Content.Size += int(e.DataLength)
+		}
+	case *network.EventLoadingFinished:
+		if p, ok := r.pending[e.RequestID]; ok {
+			p.finish(monotonicSeconds(e.Timestamp), e.EncodedDataLength)
+			delete(r.pending, e.RequestID)
+		}
+	case *network.EventLoadingFailed:
+		if p, ok := r.pending[e.RequestID]; ok {
+			p.entry.Error = e.ErrorText
+			if e.BlockedReason != "" {
+				p.entry.Error = "blocked:" + e.BlockedReason.String()
+			}
+			p.finish(monotonicSeconds(e.Timestamp), 0)
+			delete(r.pending, e.RequestID)
+		}
+	case *page.EventDomContentEventFired:
+		if r.onContent < 0 && r.start > 0 {
+			r.onContent = roundMs((monotonicSeconds(e.Timestamp) - r.start) * 1000)
+		}
+	case *page.EventLoadEventFired:
+		if r.onLoad < 0 && r.start > 0 {
+			r.onLoad = roundMs((monotonicSeconds(e.Timestamp) - r.start) * 1000)
+		}
+	}
+}
+
+func (r *harRecorder) addRequest(e *network.EventRequestWillBeSent) {
+	if len(r.entries) >= MaxHAREntries {
+		r.dropped++
+		return
+	}
+
+	startedAt := time.Now()
+	if e.WallTime != nil {
+		startedAt = e.WallTime.Time()
+	}
+	start := monotonicSeconds(e.Timestamp)
+	if r.start == 0 {
+		r.start = start
+		r.startedAt = startedAt
+	}
+
+	bodySize := 0
+	if e.Request.HasPostData {
+		bodySize = -1
+	}
+
+	p := &harPending{
+		start: start,
+		entry: &HAREntry{
+			Pageref:         harPageID,
+			StartedDateTime: startedAt.UTC().Format(time.RFC3339Nano),
+			Request: HARRequest{
+				Method:      e.Request.Method,
+				URL:         e.Request.URL + e.Request.URLFragment,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []HARNameValue{},
+				Headers:     harHeaders(e.Request.Headers),
+				QueryString: harQueryString(e.Request.URL),
+				HeadersSize: -1,
+				BodySize:    bodySize,
+			},
+			Response: HARResponse{
+				Cookies:     []HARNameValue{},
+				Headers:     []HARNameValue{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			ResourceType: strings.ToLower(e.Type.String()),
+		},
+	}
+	r.pending[e.RequestID] = p
+	r.entries = append(r.entries, p)
+}
+
+// remoteIP 响应的服务器IP：浏览器经代理访问时Chrome报告的是代理地址，改由serverIP查询实际连接的IP
+func (r *harRecorder) remoteIP(resp *network.Response) string {
+	if r.serverIP == nil {
+		return resp.RemoteIPAddress
+	}
+	u, err := url.Parse(resp.URL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	return r.serverIP(net.JoinHostPort(u.Hostname(), port))
+}
+
+func (p *harPending) applyResponse(resp *network.Response, serverIP string) {
+	version := harHTTPVersion(resp.Protocol)
+	p.timing = resp.Timing
+	p.entry.Request.HTTPVersion = version
+	p.entry.Response.Status = int(resp.Status)
+	p.entry.Response.StatusText = resp.StatusText
+	p.entry.Response.HTTPVersion = version
+	p.entry.Response.Headers = harHeaders(resp.Headers)
+	p.entry.Response.Content.MimeType = resp.MimeType
+	p.entry.ServerIPAddress = serverIP
+}
+
+// finish 根据CDP计时计算各阶段耗时
+func (p *harPending) finish(end, encodedDataLength float64) {
+	p.finished = true
+	p.entry.Response.TransferSize = int(encodedDataLength)
+
+	timing := p.timing
+	if timing == nil {
+		// 无网络计时（内存缓存命中或请求失败）时整段计入blocked
+		total := max(roundMs((end-p.start)*1000), 0)
+		p.entry.Timings = HARTimings{Blocked: total, DNS: -1, Connect: -1, SSL: -1}
+		p.entry.Time = total
+		return
+	}
+
+	span := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return roundMs(end - start)
+	}
+
+	// blocked为请求开始到第一个网络阶段（DNS、建连或发送）
+	firstPhase := timing.SendStart
+	for _, phase := range []float64{timing.ConnectStart, timing.DNSStart} {
+		if phase >= 0 {
+			firstPhase = phase
+		}
+	}
+
+	t := HARTimings{
+		Blocked: roundMs(max(firstPhase, 0)),
+		DNS:     span(timing.DNSStart, timing.DNSEnd),
+		Connect: span(timing.ConnectStart, timing.ConnectEnd),
+		SSL:     span(timing.SslStart, timing.SslEnd),
+		Send:    max(span(timing.SendStart, timing.SendEnd), 0),
+		Wait:    roundMs(max(timing.ReceiveHeadersEnd-timing.SendEnd, 0)),
+		Receive: roundMs(max((end-timing.RequestTime)*1000-timing.ReceiveHeadersEnd, 0)),
+	}
+	p.entry.Timings = t
+
+	// 按HAR规范，总耗时为各阶段之和，ssl已包含在connect中
+	total := 0.0
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if phase > 0 {
+			total += phase
+		}
+	}
+	p.entry.Time = roundMs(total)
+}
+
+// build 生成HAR文档，尚未完成的请求保留已知信息
+func (r *harRecorder) build() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*HAREntry, 0, len(r.entries))
+	for _, p := range r.entries {
+		entry := *p.entry
+		if !p.finished && entry.Error == "" {
+			entry.Error = "请求未完成"
+		}
+		entries = append(entries, &entry)
+	}
+
+	startedAt := r.startedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
+	return &HAR{
+		Log: &HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "whosee", Version: "1.0"},
+			Pages: []HARPage{{
+				StartedDateTime: startedAt.UTC().Format(time.RFC3339Nano),
+				ID:              harPageID,
+				Title:           r.pageURL,
+				PageTimings:     HARPageTimings{OnContentLoad: r.onContent, OnLoad: r.onLoad},
+			}},
+			Entries: entries,
+		},
+	}
+}
+
+// summary 请求统计，写入响应元数据
+func (r *harRecorder) summary(har *HAR) map[string]interface{} {
+	r.mu.Lock()
+	dropped := r.dropped
+	r.mu.Unlock()
+
+	hosts := make(map[string]int)
+	failed := 0
+	var transferSize int
+	for _, entry := range har.Log.Entries {
+		if u, err := url.Parse(entry.Request.URL); err == nil && u.Host != "" {
+			hosts[u.Hostname()]++
+		}
+		if entry.Error != "" {
+			failed++
+		}
+		transferSize += entry.Response.TransferSize
+	}
+
+	hostList := make([]string, 0, len(hosts))
+	for host := range hosts {
+		hostList = append(hostList, host)
+	}
+	sort.Strings(hostList)
+
+	meta := map[string]interface{}{
+		"entries":       len(har.Log.Entries),
+		"failed":        failed,
+		"transfer_size": transferSize,
+		"hosts":         hostList,
+	}
+	if dropped > 0 {
+		meta["dropped"] = dropped
+	}
+	return meta
+}
+
+func monotonicSeconds(t *cdp.MonotonicTime) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
+
+func roundMs(ms float64) float64 {
+	return math.Round(ms*1000) / 1000
+}
+
+// harRedacted 替换凭据类请求头和响应头的值
+const harRedacted = "[REDACTED]"
+
+// harSensitiveHeaders 值中包含Cookie或认证凭据的头，HAR文件可通过URL公开访问，只保留名称
+var harSensitiveHeaders = map[string]bool{
+	"cookie":              true,
+	"set-cookie":          true,
+	"authorization":       true,
+	"proxy-authorization": true,
+}
+
+// harHeaders 转换请求头或响应头，凭据类头的值被替换为harRedacted；请求和响应的cookies数组同样不记录Cookie，始终为空
+func harHeaders(headers network.Headers) []HARNameValue {
+	result := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		sensitive := harSensitiveHeaders[strings.ToLower(name)]
+		// 多值响应头在CDP中以换行分隔
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			if sensitive {
+				v = harRedacted
+			}
+			result = append(result, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func harQueryString(rawURL string) []HARNameValue {
+	result := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			result = append(result, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// harHTTPVersion 将CDP协议名转换为HAR中的HTTP版本
+func harHTTPVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return "HTTP/1.1"
+	case "h2":
+		return "HTTP/2"
+	case "h3", "http/3":
+		return "HTTP/3"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
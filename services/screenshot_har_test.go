@@ -0,0 +1,145 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+func monotonicAt(seconds float64) *cdp.MonotonicTime {
+	t := cdp.MonotonicTime(cdp.MonotonicTimeEpoch.Add(time.Duration(seconds * float64(time.Second))))
+	return &t
+}
+
+// TestHARRecorder 测试由CDP事件生成HAR条目、重定向和失败请求
+func TestHARRecorder(t *testing.T) {
+	r := &harRecorder{
+		pageURL:   "https://example.com",
+		pending:   make(map[network.RequestID]*harPending),
+		onContent: -1,
+		onLoad:    -1,
+	}
+	wall := cdp.TimeSinceEpoch(time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC))
+
+	r.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request:   &network.Request{URL: "http://example.com/?a=1", Method: "GET"},
+		Timestamp: monotonicAt(100),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeDocument,
+	})
+	// 重定向到https
+	r.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request: &network.Request{URL: "https://example.com/", Method: "GET", Headers: network.Headers{
+			"Accept": "text/html", "Cookie": "sid=secret", "authorization": "Bearer token",
+		}},
+		Timestamp:        monotonicAt(100.05),
+		RedirectResponse: &network.Response{Status: 301, StatusText: "Moved", EncodedDataLength: 200},
+		Type:             network.ResourceTypeDocument,
+	})
+	r.handleEvent(&network.EventResponseReceived{
+		RequestID: "1",
+		Response: &network.Response{
+			Status:          200,
+			MimeType:        "text/html",
+			Protocol:        "h2",
+			RemoteIPAddress: "93.184.216.34",
+			Headers:         network.Headers{"Set-Cookie": "a=1\nb=2"},
+			Timing: &network.ResourceTiming{
+				RequestTime: 100.05, DNSStart: 1, DNSEnd: 11, ConnectStart: 11, ConnectEnd: 41,
+				SslStart: 21, SslEnd: 41, SendStart: 41, SendEnd: 42, ReceiveHeadersEnd: 92,
+			},
+		},
+	})
+	r.handleEvent(&network.EventDataReceived{RequestID: "1", DataLength: 5000})
+	r.handleEvent(&network.EventLoadingFinished{RequestID: "1", Timestamp: monotonicAt(100.15), EncodedDataLength: 1500})
+
+	r.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "2",
+		Request:   &network.Request{URL: "https://tracker.example.net/t.js", Method: "GET"},
+		Timestamp: monotonicAt(100.2),
+		Type:      network.ResourceTypeScript,
+	})
+	r.handleEvent(&network.EventLoadingFailed{RequestID: "2", Timestamp: monotonicAt(100.3), ErrorText: "net::ERR_NAME_NOT_RESOLVED"})
+	r.handleEvent(&page.EventLoadEventFired{Timestamp: monotonicAt(100.5)})
+
+	har := r.build()
+	entries := har.Log.Entries
+	if har.Log.Version != "1.2" || len(entries) != 3 {
+		t.Fatalf("期望3个条目, 实际 %d", len(entries))
+	}
+
+	redirect := entries[0]
+	if redirect.Response.Status != 301 || redirect.Response.RedirectURL != "https://example.com/" || len(redirect.Request.QueryString) != 1 {
+		t.Errorf("重定向条目不正确: %+v", redirect)
+	}
+	if redirect.StartedDateTime != "2026-10-18T08:00:00Z" {
+		t.Errorf("开始时间不正确: %s", redirect.StartedDateTime)
+	}
+
+	doc := entries[1]
+	if doc.Response.HTTPVersion != "HTTP/2" || doc.ServerIPAddress != "93.184.216.34" || doc.Response.Content.Size != 5000 {
+		t.Errorf("文档条目不正确: %+v", doc.Response)
+	}
+	if len(doc.Response.Headers) != 2 || doc.Response.Headers[0].Value != harRedacted || doc.Response.Headers[1].Value != harRedacted {
+		t.Errorf("多值响应头应拆分且Set-Cookie的值应被替换: %+v", doc.Response.Headers)
+	}
+	if len(doc.Request.Headers) != 3 || doc.Request.Headers[0].Value != "text/html" || doc.Request.Headers[1].Value != harRedacted || doc.Request.Headers[2].Value != harRedacted {
+		t.Errorf("Cookie和Authorization请求头的值应被替换: %+v", doc.Request.Headers)
+	}
+	expected := HARTimings{Blocked: 1, DNS: 10, Connect: 30, SSL: 20, Send: 1, Wait: 50, Receive: 8}
+	if doc.Timings != expected || doc.Time != 100 {
+		t.Errorf("计时不正确: %+v, time %v", doc.Timings, doc.Time)
+	}
+
+	failed := entries[2]
+	if failed.Error != "net::ERR_NAME_NOT_RESOLVED" || failed.Time != 100 {
+		t.Errorf("失败条目不正确: %+v", failed)
+	}
+	if har.Log.Pages[0].PageTimings.OnLoad != 500 {
+		t.Errorf("onLoad不正确: %v", har.Log.Pages[0].PageTimings.OnLoad)
+	}
+
+	summary := r.summary(har)
+	hosts := summary["hosts"].([]string)
+	if len(hosts) != 2 || hosts[0] != "example.com" || summary["failed"] != 1 {
+		t.Errorf("统计不正确: %+v", summary)
+	}
+
+	if _, err := json.Marshal(har); err != nil {
+		t.Errorf("HAR序列化失败: %v", err)
+	}
+}
+
+// TestHARRecorderProxiedServerIP 测试经出站代理时记录代理实际连接的IP，而不是Chrome报告的代理地址
+func TestHARRecorderProxiedServerIP(t *testing.T) {
+	var looked []string
+	r := &harRecorder{
+		pending:   make(map[network.RequestID]*harPending),
+		onContent: -1,
+		onLoad:    -1,
+		serverIP: func(address string) string {
+			looked = append(looked, address)
+			return map[string]string{"example.com:443": "93.184.216.34"}[address]
+		},
+	}
+	for i, rawURL := range []string{"https://example.com/", "http://cdn.example.net:8080/a.js"} {
+		id := network.RequestID(fmt.Sprint(i))
+		r.handleEvent(&network.EventRequestWillBeSent{RequestID: id, Request: &network.Request{URL: rawURL, Method: "GET"}, Timestamp: monotonicAt(100)})
+		r.handleEvent(&network.EventResponseReceived{RequestID: id, Response: &network.Response{URL: rawURL, Status: 200, RemoteIPAddress: "127.0.0.1"}})
+	}
+
+	entries := r.build().Log.Entries
+	if entries[0].ServerIPAddress != "93.184.216.34" || entries[1].ServerIPAddress != "" {
+		t.Errorf("服务器IP不正确: %q, %q", entries[0].ServerIPAddress, entries[1].ServerIPAddress)
+	}
+	if len(looked) != 2 || looked[1] != "cdn.example.net:8080" {
+		t.Errorf("查询的地址不正确: %v", looked)
+	}
+}
@@ -0,0 +1,457 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图历史记录 - 按域名和类型保存每次截图，支持历史查询和两次截图对比
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"whosee/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 历史记录参数
+const (
+	MaxHistoryEntries   = 100                 // 每个域名和类型最多保留的记录数
+	HistoryRetention    = 90 * 24 * time.Hour // 历史记录在无新截图后的保留时长
+	DefaultHistoryLimit = 20
+)
+
+// historyKeyPrefix 历史记录键前缀，与historyKey一致
+const historyKeyPrefix = "screenshot:history:"
+
+// ErrHistoryNotFound 历史记录不存在或不足以对比
+var ErrHistoryNotFound = errors.New("截图历史记录不存在")
+
+// ErrDiffNotComparable 两次截图的截图参数或尺寸不适合对比
+var ErrDiffNotComparable = errors.New("截图无法对比")
+
+// ScreenshotRecord 一次截图的历史记录
+type ScreenshotRecord struct {
+	ID           string         `json:"id"`
+	Domain       string         `json:"domain"`
+	Type         ScreenshotType `json:"type"`
+	ImageURL     string         `json:"image_url"`
+	ThumbnailURL string         `json:"thumbnail_url,omitempty"`
+	ImageKey     string         `json:"image_key,omitempty"`
+	ThumbnailKey string         `json:"thumbnail_key,omitempty"`
+	Format       ImageFormat    `json:"format"`
+	Size         int            `json:"size"`
+	Viewport     string         `json:"viewport,omitempty"` // 视口尺寸、缩放和整页参数，只有相同时才能对比
+	Device       string         `json:"device,omitempty"`
+	Setup        string         `json:"setup,omitempty"` // 改变页面内容的参数签名（交互步骤、隐藏元素、地区等），默认参数为空
+	CapturedAt   string         `json:"captured_at"`
+}
+
+// ScreenshotHistoryCSVHeader 历史记录CSV导出的表头
+var ScreenshotHistoryCSVHeader = []string{"id", "domain", "type", "image_url", "thumbnail_url", "format", "size", "captured_at"}
+
+// CSVRecord 历史记录的CSV行
+func (r *ScreenshotRecord) CSVRecord() []string {
+	return []string{r.ID, r.Domain, string(r.Type), r.ImageURL, r.ThumbnailURL, string(r.Format), strconv.Itoa(r.Size), r.CapturedAt}
+}
+
+// ScreenshotDiff 两次截图的对比结果
+type ScreenshotDiff struct {
+	Domain         string            `json:"domain"`
+	Type           ScreenshotType    `json:"type"`
+	From           *ScreenshotRecord `json:"from"`
+	To             *ScreenshotRecord `json:"to"`
+	DiffPercent    float64           `json:"diff_percent"`
+	ChangedPixels  int               `json:"changed_pixels"`
+	TotalPixels    int               `json:"total_pixels"`
+	ChangedRegions int               `json:"changed_regions"`
+	HashDistance   int               `json:"hash_distance"`
+	DiffImageURL   string            `json:"diff_image_url"`
+	DiffImageKey   string            `json:"diff_image_key,omitempty"`
+	GeneratedAt    string            `json:"generated_at"`
+	FromCache      bool              `json:"from_cache"`
+}
+
+// ScreenshotHistory 截图历史服务，记录存放在Redis有序集合中，差异图存放在文件存储的diffs目录
+type ScreenshotHistory struct {
+	redisClient *redis.Client
+	config      *ScreenshotServiceConfig
+	storage     Storage
+}
+
+// NewScreenshotHistory 创建截图历史服务
+func NewScreenshotHistory(redisClient *redis.Client, config *ScreenshotServiceConfig) *ScreenshotHistory {
+	if config == nil {
+		config = DefaultScreenshotServiceConfig
+	}
+
+	return &ScreenshotHistory{
+		redisClient: redisClient,
+		config:      config,
+		storage:     storageFor(config),
+	}
+}
+
+// Record 记录一次截图，超出条数上限的旧记录被移除（文件由清理任务回收）
+func (h *ScreenshotHistory) Record(ctx context.Context, record *ScreenshotRecord) error {
+	if h.redisClient == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	capturedAt, err := time.Parse(time.RFC3339Nano, record.CapturedAt)
+	if err != nil {
+		return fmt.Errorf("无效的截图时间: %v", err)
+	}
+
+	key := historyKey(record.Domain, record.Type)
+	pipe := h.redisClient.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(capturedAt.UnixNano()), Member: data})
+	pipe.ZRemRangeByRank(ctx, key, 0, -MaxHistoryEntries-1)
+	pipe.Expire(ctx, key, HistoryRetention)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// List 按时间倒序返回历史记录
+func (h *ScreenshotHistory) List(ctx context.Context, domain string, screenshotType ScreenshotType, limit int) ([]*ScreenshotRecord, error) {
+	if h.redisClient == nil {
+		return nil, fmt.Errorf("截图历史需要Redis")
+	}
+	if limit <= 0 || limit > MaxHistoryEntries {
+		limit = MaxHistoryEntries
+	}
+
+	members, err := h.redisClient.ZRevRange(ctx, historyKey(domain, screenshotType), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ScreenshotRecord, 0, len(members))
+	for _, member := range members {
+		var record ScreenshotRecord
+		if err := json.Unmarshal([]byte(member), &record); err != nil {
+			log.Printf("[SCREENSHOT] 解析历史记录失败: %v", err)
+			continue
+		}
+		h.signRecord(ctx, &record)
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+// Diff 对比两次截图，未指定ID时默认对比最近两次
+func (h *ScreenshotHistory) Diff(ctx context.Context, domain string, screenshotType ScreenshotType, fromID, toID string) (*ScreenshotDiff, error) {
+	records, err := h.List(ctx, domain, screenshotType, MaxHistoryEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := selectDiffPair(records, fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := utils.BuildCacheKey("screenshot", "diff", string(screenshotType), domain, from.ID, to.ID)
+	if cached := h.getCachedDiff(ctx, cacheKey); cached != nil {
+		cached.FromCache = true
+		return cached, nil
+	}
+
+	before, err := h.loadImage(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := h.loadImage(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// 尺寸不同时以较大的画布对比，两张图各自未超限时画布仍可能过大
+	width := max(before.Bounds().Dx(), after.Bounds().Dx())
+	height := max(before.Bounds().Dy(), after.Bounds().Dy())
+	if width*height > MaxDiffPixels {
+		return nil, fmt.Errorf("%w: 对比画布 %dx%d 超过 %d 像素", ErrDiffNotComparable, width, height, MaxDiffPixels)
+	}
+	comparison := compareImages(before, after)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, comparison.Diff); err != nil {
+		return nil, fmt.Errorf("编码差异图失败: %v", err)
+	}
+	diffKey := fmt.Sprintf("diffs/diff_%s_%s_%s_%s.png", screenshotType, utils.GenerateSecureFilename(domain), from.ID, to.ID)
+	if err := h.storage.Put(ctx, diffKey, buf.Bytes(), "image/png"); err != nil {
+		return nil, fmt.Errorf("保存差异图失败: %v", err)
+	}
+	diffURL, err := h.storage.SignedURL(ctx, diffKey, h.signedURLExpiry())
+	if err != nil {
+		return nil, fmt.Errorf("生成差异图访问URL失败: %v", err)
+	}
+	touchStorage(ctx, h.redisClient, diffKey, recordKey(from), recordKey(to))
+
+	diff := &ScreenshotDiff{
+		Domain:         domain,
+		Type:           screenshotType,
+		From:           from,
+		To:             to,
+		DiffPercent:    comparison.DiffPercent,
+		ChangedPixels:  comparison.ChangedPixels,
+		TotalPixels:    comparison.TotalPixels,
+		ChangedRegions: comparison.ChangedRegions,
+		HashDistance:   comparison.HashDistance,
+		DiffImageURL:   diffURL,
+		DiffImageKey:   diffKey,
+		GeneratedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	h.cacheDiff(ctx, cacheKey, diff)
+
+	log.Printf("[SCREENSHOT] 截图对比 %s %s -> %s: 差异 %.2f%%, 哈希距离 %d",
+		domain, from.ID, to.ID, diff.DiffPercent, diff.HashDistance)
+	return diff, nil
+}
+
+// selectDiffPair 从按时间倒序的记录中选出对比的两次截图，from早于to，指定的顺序相反时交换
+// 未指定from时选择to之前最近一次参数相同的截图；视口、设备、格式或其他截图参数不同的截图不能对比
+func selectDiffPair(records []*ScreenshotRecord, fromID, toID string) (*ScreenshotRecord, *ScreenshotRecord, error) {
+	find := func(id string) int {
+		for i, record := range records {
+			if record.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	toIndex := 0
+	if toID != "" {
+		if toIndex = find(toID); toIndex < 0 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrHistoryNotFound, toID)
+		}
+	}
+
+	if toIndex >= len(records) {
+		return nil, nil, fmt.Errorf("%w: 历史截图不足两张，无法对比", ErrHistoryNotFound)
+	}
+
+	fromIndex := -1
+	if fromID != "" {
+		if fromIndex = find(fromID); fromIndex < 0 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrHistoryNotFound, fromID)
+		}
+	} else {
+		for i := toIndex + 1; i < len(records); i++ {
+			if sameCaptureSetup(records[i], records[toIndex]) {
+				fromIndex = i
+				break
+			}
+		}
+		if fromIndex < 0 {
+			return nil, nil, fmt.Errorf("%w: 没有截图参数相同的更早截图，无法对比", ErrHistoryNotFound)
+		}
+	}
+
+	if fromIndex == toIndex {
+		return nil, nil, fmt.Errorf("%w: 不能与自身对比", ErrDiffNotComparable)
+	}
+	// 记录按时间倒序，索引较大的更早
+	if fromIndex < toIndex {
+		fromIndex, toIndex = toIndex, fromIndex
+	}
+	from, to := records[fromIndex], records[toIndex]
+	if !sameCaptureSetup(from, to) {
+		return nil, nil, fmt.Errorf("%w: 截图参数不同 (%s %s %s %s / %s %s %s %s)", ErrDiffNotComparable,
+			from.Viewport, from.Device, from.Format, from.Setup, to.Viewport, to.Device, to.Format, to.Setup)
+	}
+	return from, to, nil
+}
+
+// sameCaptureSetup 两次截图的视口、设备、格式和其他截图参数是否相同
+func sameCaptureSetup(a, b *ScreenshotRecord) bool {
+	return a.Viewport == b.Viewport && a.Device == b.Device && a.Format == b.Format && a.Setup == b.Setup
+}
+
+// captureSetupSignature 除视口和格式外改变页面内容的截图参数签名，全部为默认值时为空，与升级前的记录一致
+func captureSetupSignature(config *ScreenshotConfig) string {
+	var parts []string
+	if config.Selector != "" {
+		parts = append(parts, "selector="+config.Selector)
+	}
+	if len(config.Actions) > 0 {
+		actions, _ := json.Marshal(config.Actions)
+		parts = append(parts, "actions="+string(actions))
+	}
+	if config.HideCSS != "" {
+		parts = append(parts, "hide="+config.HideCSS)
+	}
+	if config.BlockAds {
+		parts = append(parts, "block_ads")
+	}
+	if region := config.Region; region != nil {
+		if region.Proxy != nil {
+			parts = append(parts, "proxy="+region.Proxy.Name)
+		}
+		if region.Locale != "" {
+			parts = append(parts, "locale="+region.Locale)
+		}
+		if region.Timezone != "" {
+			parts = append(parts, "timezone="+region.Timezone)
+		}
+		if geo := region.Geolocation; geo != nil {
+			parts = append(parts, fmt.Sprintf("geo=%g,%g,%g", geo.Latitude, geo.Longitude, geo.Accuracy))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(parts, "\n"))))[:16]
+}
+
+// signedURLExpiry 预签名URL有效期
+func (h *ScreenshotHistory) signedURLExpiry() time.Duration {
+	if h.config.SignedURLExpiry > 0 {
+		return h.config.SignedURLExpiry
+	}
+	return DefaultSignedURLExpiry
+}
+
+// signRecord 按存储键重新生成历史记录的访问URL，保存时的预签名URL可能已过期
+func (h *ScreenshotHistory) signRecord(ctx context.Context, record *ScreenshotRecord) {
+	if record.ImageKey != "" {
+		if signed, err := h.storage.SignedURL(ctx, record.ImageKey, h.signedURLExpiry()); err == nil {
+			record.ImageURL = signed
+		}
+	}
+	if record.ThumbnailKey != "" {
+		if signed, err := h.storage.SignedURL(ctx, record.ThumbnailKey, h.signedURLExpiry()); err == nil {
+			record.ThumbnailURL = signed
+		}
+	}
+}
+
+// recordKey 历史记录对应的存储键，兼容升级前只保存/static路径的记录
+func recordKey(record *ScreenshotRecord) string {
+	if record.ImageKey != "" {
+		return record.ImageKey
+	}
+	if strings.HasPrefix(record.ImageURL, "/static/") {
+		return storageKeyFromURL(record.ImageURL)
+	}
+	return ""
+}
+
+// pruneHistory 移除截图文件已被删除的历史记录，对比时不再选中这些记录
+func pruneHistory(ctx context.Context, redisClient *redis.Client, key string, deleted map[string]bool) error {
+	members, err := redisClient.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	var stale []interface{}
+	for _, member := range members {
+		var record ScreenshotRecord
+		if err := json.Unmarshal([]byte(member), &record); err == nil && deleted[recordKey(&record)] {
+			stale = append(stale, member)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return redisClient.ZRem(ctx, key, stale...).Err()
+}
+
+// loadImage 读取历史截图文件
+func (h *ScreenshotHistory) loadImage(ctx context.Context, record *ScreenshotRecord) (image.Image, error) {
+	key := recordKey(record)
+	if key == "" {
+		return nil, fmt.Errorf("截图 %s 没有保存文件", record.ID)
+	}
+	if record.Format == ImageWebP {
+		return nil, fmt.Errorf("webp格式截图不支持对比")
+	}
+
+	content, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("截图 %s 文件已被清理", record.ID)
+	}
+
+	// 解码前检查尺寸，超大整页截图解码和对比会占用大量内存
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("解码截图 %s 失败: %v", record.ID, err)
+	}
+	if config.Width*config.Height > MaxDiffPixels {
+		return nil, fmt.Errorf("%w: 截图 %s 尺寸 %dx%d 超过 %d 像素", ErrDiffNotComparable, record.ID, config.Width, config.Height, MaxDiffPixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("解码截图 %s 失败: %v", record.ID, err)
+	}
+	return img, nil
+}
+
+// historyKey 历史记录键
+func historyKey(domain string, screenshotType ScreenshotType) string {
+	return utils.BuildCacheKey("screenshot", "history", string(screenshotType), domain)
+}
+
+func (h *ScreenshotHistory) getCachedDiff(ctx context.Context, key string) *ScreenshotDiff {
+	data, err := h.redisClient.Get(ctx, key).Result()
+	if err != nil {
+		return nil
+	}
+
+	var diff ScreenshotDiff
+	if err := json.Unmarshal([]byte(data), &diff); err != nil || diff.From == nil || diff.To == nil {
+		return nil
+	}
+
+	// 差异图已被清理时重新生成
+	diffKey := diff.DiffImageKey
+	if diffKey == "" {
+		diffKey = storageKeyFromURL(diff.DiffImageURL)
+	}
+	if _, err := h.storage.Stat(ctx, diffKey); err != nil {
+		return nil
+	}
+	signed, err := h.storage.SignedURL(ctx, diffKey, h.signedURLExpiry())
+	if err != nil {
+		return nil
+	}
+	diff.DiffImageURL = signed
+	// 缓存中截图的预签名URL可能已过期
+	h.signRecord(ctx, diff.From)
+	h.signRecord(ctx, diff.To)
+	touchStorage(ctx, h.redisClient, diffKey, recordKey(diff.From), recordKey(diff.To))
+	return &diff
+}
+
+func (h *ScreenshotHistory) cacheDiff(ctx context.Context, key string, diff *ScreenshotDiff) {
+	expiration := h.config.CacheExpiration
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return
+	}
+	if err := h.redisClient.Set(ctx, key, data, expiration).Err(); err != nil {
+		log.Printf("[SCREENSHOT] 缓存对比结果失败: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图图片编码 - 输出格式、压缩质量、缩略图生成和文件大小限制
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// ImageFormat 截图图片格式
+type ImageFormat string
+
+const (
+	ImagePNG  ImageFormat = "png"
+	ImageJPEG ImageFormat = "jpeg"
+	ImageWebP ImageFormat = "webp"
+)
+
+// 图片参数限制
+const (
+	DefaultImageQuality = 80
+	MaxThumbnailSize    = 1024
+)
+
+// errFileTooLarge 截图或缩略图超过MaxFileSize
+var errFileTooLarge = errors.New("截图文件大小超过限制")
+
+// ImageOptions 图片输出配置
+type ImageOptions struct {
+	Format      ImageFormat
+	Quality     int // 仅对jpeg/webp有效
+	ThumbWidth  int
+	ThumbHeight int
+}
+
+// resolveImageOptions 解析图片格式参数，格式必须在允许列表中
+func resolveImageOptions(req *ScreenshotRequest, allowedFormats []string) (*ImageOptions, error) {
+	opts := &ImageOptions{
+		Format:      ImagePNG,
+		Quality:     DefaultImageQuality,
+		ThumbWidth:  req.ThumbWidth,
+		ThumbHeight: req.ThumbHeight,
+	}
+
+	if req.ImageFormat != "" {
+		name := strings.ToLower(req.ImageFormat)
+		allowed := false
+		for _, format := range allowedFormats {
+			if strings.EqualFold(format, name) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("不支持的图片格式: %s（可选: %s）", req.ImageFormat, strings.Join(allowedFormats, ", "))
+		}
+		if name == "jpg" {
+			name = string(ImageJPEG)
+		}
+		opts.Format = ImageFormat(name)
+	}
+
+	if req.Quality != 0 {
+		if req.Quality < 1 || req.Quality > 100 {
+			return nil, fmt.Errorf("图片质量必须在 1-100 之间")
+		}
+		opts.Quality = req.Quality
+	}
+
+	if opts.ThumbWidth < 0 || opts.ThumbHeight < 0 ||
+		opts.ThumbWidth > MaxThumbnailSize || opts.ThumbHeight > MaxThumbnailSize {
+		return nil, fmt.Errorf("缩略图尺寸必须在 1-%d 之间", MaxThumbnailSize)
+	}
+
+	// 元素和ITDog截图由chromedp以PNG输出，需要在服务端转码，Go标准库没有WebP编码器
+	if opts.Format == ImageWebP && req.Type != "" && req.Type != TypeBasic {
+		return nil, fmt.Errorf("webp格式仅支持基础截图")
+	}
+
+	return opts, nil
+}
+
+// wantsThumbnail 是否需要生成缩略图
+func (o *ImageOptions) wantsThumbnail() bool {
+	return o.ThumbWidth > 0 || o.ThumbHeight > 0
+}
+
+// extension 文件扩展名
+func (o *ImageOptions) extension() string {
+	return string(o.Format)
+}
+
+// mimeType 图片MIME类型
+func (o *ImageOptions) mimeType() string {
+	return "image/" + string(o.Format)
+}
+
+// cdpFormat 对应的CDP截图格式
+func (o *ImageOptions) cdpFormat() page.CaptureScreenshotFormat {
+	switch o.Format {
+	case ImageJPEG:
+		return page.CaptureScreenshotFormatJpeg
+	case ImageWebP:
+		return page.CaptureScreenshotFormatWebp
+	default:
+		return page.CaptureScreenshotFormatPng
+	}
+}
+
+// metadata 图片参数在响应元数据中的表示
+func (o *ImageOptions) metadata() map[string]interface{} {
+	meta := map[string]interface{}{
+		"format": o.Format,
+	}
+	if o.Format != ImagePNG {
+		meta["quality"] = o.Quality
+	}
+	return meta
+}
+
+// thumbnailScale 按比例缩放到缩略图尺寸内，只指定一边时按该边缩放，不放大
+func (o *ImageOptions) thumbnailScale(width, height float64) float64 {
+	scale := math.Inf(1)
+	if o.ThumbWidth > 0 {
+		scale = float64(o.ThumbWidth) / width
+	}
+	if o.ThumbHeight > 0 {
+		scale = math.Min(scale, float64(o.ThumbHeight)/height)
+	}
+	return math.Min(scale, 1)
+}
+
+// captureViewport 由Chrome直接按目标格式和质量截取视口或整个页面
+// 需要缩略图时再次以缩放后的裁剪区域截图，webp缩略图也无需服务端解码
+func captureViewport(opts *ImageOptions, fullPage bool, buf, thumb *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		capture := func() *page.CaptureScreenshotParams {
+			params := page.CaptureScreenshot().
+				WithFromSurface(true).
+				WithCaptureBeyondViewport(fullPage).
+				WithFormat(opts.cdpFormat())
+			if opts.Format != ImagePNG {
+				params = params.WithQuality(int64(opts.Quality))
+			}
+			return params
+		}
+
+		var err error
+		if *buf, err = capture().Do(ctx); err != nil {
+			return err
+		}
+		if !opts.wantsThumbnail() {
+			return nil
+		}
+
+		_, _, _, cssLayoutViewport, _, cssContentSize, err := page.GetLayoutMetrics().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("获取页面尺寸失败: %v", err)
+		}
+		clip := &page.Viewport{
+			X:      float64(cssLayoutViewport.PageX),
+			Y:      float64(cssLayoutViewport.PageY),
+			Width:  float64(cssLayoutViewport.ClientWidth),
+			Height: float64(cssLayoutViewport.ClientHeight),
+		}
+		if fullPage {
+			clip.X, clip.Y = 0, 0
+			clip.Width, clip.Height = cssContentSize.Width, cssContentSize.Height
+		}
+		clip.Scale = opts.thumbnailScale(clip.Width, clip.Height)
+
+		*thumb, err = capture().WithCaptureBeyondViewport(true).WithClip(clip).Do(ctx)
+		return err
+	})
+}
+
+// encodeImage 将Chrome输出的PNG转码为目标格式，PNG直接返回
+func encodeImage(pngData []byte, opts *ImageOptions) ([]byte, error) {
+	if opts.Format == ImagePNG {
+		return pngData, nil
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		return nil, fmt.Errorf("解码截图失败: %v", err)
+	}
+	return writeImage(img, opts)
+}
+
+// makeThumbnail 在服务端生成缩略图，源图片必须为PNG或JPEG
+func makeThumbnail(data []byte, opts *ImageOptions) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码截图失败: %v", err)
+	}
+
+	bounds := src.Bounds()
+	scale := opts.thumbnailScale(float64(bounds.Dx()), float64(bounds.Dy()))
+	width := int(math.Max(1, math.Round(float64(bounds.Dx())*scale)))
+	height := int(math.Max(1, math.Round(float64(bounds.Dy())*scale)))
+
+	return writeImage(resizeImage(src, width, height), opts)
+}
+
+// writeImage 按格式编码图片
+func writeImage(img image.Image, opts *ImageOptions) ([]byte, error) {
+	var out bytes.Buffer
+	var err error
+	switch opts.Format {
+	case ImageJPEG:
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: opts.Quality})
+	case ImagePNG:
+		err = png.Encode(&out, img)
+	default:
+		return nil, fmt.Errorf("服务端不支持编码 %s 格式", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码图片失败: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// resizeImage 区域平均缩小图片，每个目标像素取源图对应矩形内像素的均值
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, srcWidth, srcHeight))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1 && sy < srcHeight; sy++ {
+				for sx := x0; sx < x1 && sx < srcWidth; sx++ {
+					c := rgba.RGBAAt(sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			if n > 0 {
+				dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// TestResolveImageOptions 测试图片格式、质量和缩略图参数校验
+func TestResolveImageOptions(t *testing.T) {
+	allowed := DefaultScreenshotServiceConfig.AllowedFormats
+
+	opts, err := resolveImageOptions(&ScreenshotRequest{}, allowed)
+	if err != nil || opts.Format != ImagePNG || opts.wantsThumbnail() {
+		t.Errorf("默认应输出PNG且不生成缩略图: %+v, %v", opts, err)
+	}
+
+	opts, err = resolveImageOptions(&ScreenshotRequest{ImageFormat: "JPG", Quality: 60}, allowed)
+	if err != nil || opts.Format != ImageJPEG || opts.Quality != 60 || opts.extension() != "jpeg" {
+		t.Errorf("jpg应归一化为jpeg: %+v, %v", opts, err)
+	}
+
+	invalid := []*ScreenshotRequest{
+		{ImageFormat: "gif"},
+		{Quality: 101},
+		{ThumbWidth: 5000},
+		{Type: TypeElement, ImageFormat: "webp"},
+	}
+	for _, req := range invalid {
+		if _, err := resolveImageOptions(req, allowed); err == nil {
+			t.Errorf("期望参数 %+v 校验失败", req)
+		}
+	}
+}
+
+// TestMakeThumbnail 测试按比例缩放和转码
+func TestMakeThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ImageOptions{Format: ImageJPEG, Quality: 80, ThumbWidth: 100, ThumbHeight: 100}
+	thumb, err := makeThumbnail(buf.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("生成缩略图失败: %v", err)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(thumb))
+	if err != nil || format != "jpeg" {
+		t.Fatalf("缩略图应为JPEG: %s, %v", format, err)
+	}
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Errorf("缩略图尺寸 = %v, 期望 100x50", img.Bounds().Size())
+	}
+
+	if encoded, err := encodeImage(buf.Bytes(), &ImageOptions{Format: ImageWebP}); err == nil || encoded != nil {
+		t.Errorf("服务端不应支持WebP编码")
+	}
+}
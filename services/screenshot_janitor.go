@@ -0,0 +1,482 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图文件清理 - 后台删除没有缓存引用或超过保留期的文件，按最近访问时间淘汰以满足总大小配额
+ */
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 清理参数
+const (
+	DefaultJanitorInterval    = time.Hour
+	DefaultJanitorRetention   = 30 * 24 * time.Hour
+	DefaultJanitorGracePeriod = time.Hour // 跳过缓存的截图没有缓存引用，至少保留这段时间供客户端下载
+	storageAccessKey          = "screenshot:storage:access"
+	maxJanitorReportItems     = 200
+)
+
+// 文件被清理的原因
+const (
+	RemovalUnreferenced = "unreferenced" // 没有缓存、对比结果或定时任务引用
+	RemovalExpired      = "expired"      // 超过保留期
+	RemovalQuota        = "quota"        // 超出总大小配额，按最近访问时间淘汰
+)
+
+// janitorPrefixes 清理范围：截图、ITDog截图、差异图和报告
+var janitorPrefixes = []string{"screenshots/", "itdog/", "diffs/", "reports/"}
+
+// JanitorConfig 文件清理配置
+type JanitorConfig struct {
+	Interval    time.Duration // 清理间隔
+	Retention   time.Duration // 文件最长保留时间，0表示不按时间清理
+	MaxBytes    int64         // 文件总大小配额，0表示不限制
+	GracePeriod time.Duration // 新文件至少保留的时间，不因没有引用或超出配额被删除
+}
+
+// janitorConfigFromEnv 从环境变量读取清理配置
+func janitorConfigFromEnv() JanitorConfig {
+	config := JanitorConfig{
+		Interval:    DefaultJanitorInterval,
+		Retention:   DefaultJanitorRetention,
+		GracePeriod: DefaultJanitorGracePeriod,
+	}
+	envDuration := func(key string, target *time.Duration) {
+		if value := os.Getenv(key); value != "" {
+			if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+				*target = d
+			} else {
+				log.Printf("[JANITOR] 忽略无效的%s: %s", key, value)
+			}
+		}
+	}
+	envDuration("SCREENSHOT_JANITOR_INTERVAL", &config.Interval)
+	envDuration("SCREENSHOT_RETENTION", &config.Retention)
+	if value := os.Getenv("SCREENSHOT_STORAGE_QUOTA_MB"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.MaxBytes = int64(n) << 20
+		} else {
+			log.Printf("[JANITOR] 忽略无效的SCREENSHOT_STORAGE_QUOTA_MB: %s", value)
+		}
+	}
+	return config
+}
+
+// JanitorRemoval 被清理（试运行时为将被清理）的文件
+type JanitorRemoval struct {
+	Key        string `json:"key"`
+	Size       int64  `json:"size"`
+	Reason     string `json:"reason"`
+	LastAccess string `json:"last_access"`
+}
+
+// JanitorReport 一次清理的结果
+type JanitorReport struct {
+	DryRun            bool             `json:"dry_run"`
+	StartedAt         string           `json:"started_at"`
+	DurationMs        int64            `json:"duration_ms"`
+	ScannedFiles      int              `json:"scanned_files"`
+	ScannedBytes      int64            `json:"scanned_bytes"`
+	ReferencesChecked bool             `json:"references_checked"` // Redis不可用时不按缓存引用清理
+	RemovedFiles      int              `json:"removed_files"`
+	ReclaimedBytes    int64            `json:"reclaimed_bytes"`
+	RemainingBytes    int64            `json:"remaining_bytes"`
+	Errors            int              `json:"errors"`
+	Removals          []JanitorRemoval `json:"removals,omitempty"` // 最多列出200个文件
+}
+
+// JanitorStats 清理统计，只统计实际执行的清理，试运行不计入
+type JanitorStats struct {
+	Runs                int64          `json:"runs"`
+	TotalRemovedFiles   int64          `json:"total_removed_files"`
+	TotalReclaimedBytes int64          `json:"total_reclaimed_bytes"`
+	Interval            string         `json:"interval"`
+	Retention           string         `json:"retention"`
+	MaxBytes            int64          `json:"max_bytes"`
+	LastRun             *JanitorReport `json:"last_run,omitempty"`
+	LastDryRun          *JanitorReport `json:"last_dry_run,omitempty"`
+}
+
+// janitorTarget 一个存储及其清理范围
+type janitorTarget struct {
+	storage  Storage
+	prefixes []string
+}
+
+// janitorObject 待检查的文件
+type janitorObject struct {
+	ObjectInfo
+	target     int
+	lastAccess time.Time
+}
+
+// ScreenshotJanitor 截图文件清理服务
+// 缓存、对比结果和定时任务引用的文件保留到过期；最近访问时间记录在Redis有序集合中，没有记录时使用修改时间
+type ScreenshotJanitor struct {
+	sweepMu     sync.Mutex // 同一时间只执行一次清理
+	mu          sync.RWMutex
+	redisClient *redis.Client
+	targets     []janitorTarget
+	config      JanitorConfig
+	stats       JanitorStats
+	now         func() time.Time
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewScreenshotJanitor 创建文件清理服务，清理BaseDir下的本地文件，使用其他存储时同时清理该存储
+func NewScreenshotJanitor(service *ScreenshotService, redisClient *redis.Client, config JanitorConfig) *ScreenshotJanitor {
+	if config.Interval < time.Minute {
+		config.Interval = DefaultJanitorInterval
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = DefaultJanitorGracePeriod
+	}
+
+	targets := []janitorTarget{{storage: NewLocalStorage(service.config.BaseDir, ""), prefixes: janitorPrefixes}}
+	if local, ok := service.fileStorage().(*LocalStorage); !ok || local.root != service.config.BaseDir {
+		targets = append(targets, janitorTarget{storage: service.fileStorage(), prefixes: janitorPrefixes})
+	}
+
+	return &ScreenshotJanitor{
+		redisClient: redisClient,
+		targets:     targets,
+		config:      config,
+		stats: JanitorStats{
+			Interval:  config.Interval.String(),
+			Retention: config.Retention.String(),
+			MaxBytes:  config.MaxBytes,
+		},
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start 启动后台清理
+func (j *ScreenshotJanitor) Start() {
+	log.Printf("[JANITOR] 启动截图文件清理，间隔: %v，保留期: %v，配额: %d MB",
+		j.config.Interval, j.config.Retention, j.config.MaxBytes>>20)
+
+	go func() {
+		ticker := time.NewTicker(j.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := j.Sweep(context.Background(), false); err != nil {
+					log.Printf("[JANITOR] 清理失败: %v", err)
+				}
+			case <-j.stopChan:
+				log.Printf("[JANITOR] 截图文件清理已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台清理
+func (j *ScreenshotJanitor) Stop() {
+	j.stopOnce.Do(func() { close(j.stopChan) })
+}
+
+// Stats 返回清理统计
+func (j *ScreenshotJanitor) Stats() JanitorStats {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.stats
+}
+
+// Sweep 执行一次清理，dryRun为true时只返回将被删除的文件
+func (j *ScreenshotJanitor) Sweep(ctx context.Context, dryRun bool) (*JanitorReport, error) {
+	j.sweepMu.Lock()
+	defer j.sweepMu.Unlock()
+
+	startTime := j.now()
+	objects, err := j.listObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refs, refsChecked := j.collectReferences(ctx)
+	accessed := j.accessTimes(ctx)
+
+	report := &JanitorReport{
+		DryRun:            dryRun,
+		StartedAt:         startTime.UTC().Format(time.RFC3339),
+		ScannedFiles:      len(objects),
+		ReferencesChecked: refsChecked,
+	}
+	for i := range objects {
+		objects[i].lastAccess = objects[i].ModTime
+		if t, ok := accessed[objects[i].Key]; ok && t.After(objects[i].lastAccess) {
+			objects[i].lastAccess = t
+		}
+		report.ScannedBytes += objects[i].Size
+	}
+
+	removals := planSweep(objects, refs, refsChecked, startTime, j.config)
+	var removedKeys []interface{}
+	for _, object := range removals {
+		if !dryRun {
+			if err := j.targets[object.target].storage.Delete(ctx, object.Key); err != nil {
+				log.Printf("[JANITOR] 删除文件失败: %s, %v", object.Key, err)
+				report.Errors++
+				continue
+			}
+			removedKeys = append(removedKeys, object.Key)
+			j.dropReferences(ctx, object.Key, refs[object.Key])
+		}
+		report.RemovedFiles++
+		report.ReclaimedBytes += object.Size
+		if len(report.Removals) < maxJanitorReportItems {
+			report.Removals = append(report.Removals, JanitorRemoval{
+				Key:        object.Key,
+				Size:       object.Size,
+				Reason:     object.reason,
+				LastAccess: object.lastAccess.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	if !dryRun {
+		// 文件已被其他途径删除的访问记录一并移除，刚写入还未列出的文件除外
+		listed := make(map[string]bool, len(objects))
+		for _, object := range objects {
+			listed[object.Key] = true
+		}
+		for key, t := range accessed {
+			if !listed[key] && startTime.Sub(t) > j.config.GracePeriod {
+				removedKeys = append(removedKeys, key)
+			}
+		}
+	}
+	if len(removedKeys) > 0 && j.redisClient != nil {
+		j.redisClient.ZRem(ctx, storageAccessKey, removedKeys...)
+	}
+	report.RemainingBytes = report.ScannedBytes - report.ReclaimedBytes
+	report.DurationMs = j.now().Sub(startTime).Milliseconds()
+
+	j.mu.Lock()
+	if dryRun {
+		j.stats.LastDryRun = report
+	} else {
+		j.stats.Runs++
+		j.stats.TotalRemovedFiles += int64(report.RemovedFiles)
+		j.stats.TotalReclaimedBytes += report.ReclaimedBytes
+		j.stats.LastRun = report
+	}
+	j.mu.Unlock()
+
+	log.Printf("[JANITOR] 清理完成(试运行: %t): 扫描 %d 个文件 %d 字节，删除 %d 个文件，回收 %d 字节，失败 %d",
+		dryRun, report.ScannedFiles, report.ScannedBytes, report.RemovedFiles, report.ReclaimedBytes, report.Errors)
+	return report, nil
+}
+
+// janitorRemovalPlan 计划删除的文件
+type janitorRemovalPlan struct {
+	janitorObject
+	reason string
+}
+
+// planSweep 选出要删除的文件：先删除过期和没有引用的文件，剩余文件超出配额时按最近访问时间从旧到新淘汰
+func planSweep(objects []janitorObject, refs map[string][]string, refsChecked bool, now time.Time, config JanitorConfig) []janitorRemovalPlan {
+	var removals []janitorRemovalPlan
+	var remaining []janitorObject
+	var total int64
+	for _, object := range objects {
+		age := now.Sub(object.ModTime)
+		switch {
+		case config.Retention > 0 && age > config.Retention:
+			removals = append(removals, janitorRemovalPlan{object, RemovalExpired})
+		case refsChecked && len(refs[object.Key]) == 0 && age > config.GracePeriod:
+			removals = append(removals, janitorRemovalPlan{object, RemovalUnreferenced})
+		default:
+			remaining = append(remaining, object)
+			total += object.Size
+		}
+	}
+
+	if config.MaxBytes <= 0 || total <= config.MaxBytes {
+		return removals
+	}
+	sort.SliceStable(remaining, func(a, b int) bool {
+		return remaining[a].lastAccess.Before(remaining[b].lastAccess)
+	})
+	for _, object := range remaining {
+		if total <= config.MaxBytes {
+			break
+		}
+		if now.Sub(object.ModTime) <= config.GracePeriod {
+			continue
+		}
+		removals = append(removals, janitorRemovalPlan{object, RemovalQuota})
+		total -= object.Size
+	}
+	return removals
+}
+
+// listObjects 列出所有清理范围内的文件
+func (j *ScreenshotJanitor) listObjects(ctx context.Context) ([]janitorObject, error) {
+	var objects []janitorObject
+	for i, target := range j.targets {
+		for _, prefix := range target.prefixes {
+			listed, err := target.storage.List(ctx, prefix)
+			if err != nil {
+				return nil, fmt.Errorf("列出文件失败: %v", err)
+			}
+			for _, info := range listed {
+				objects = append(objects, janitorObject{ObjectInfo: info, target: i})
+			}
+		}
+	}
+	return objects, nil
+}
+
+// collectReferences 扫描截图缓存、历史记录、报告缓存、对比结果和定时任务，返回 存储键 -> 引用它的Redis键
+// 历史记录引用的文件保留到超过保留期或超出配额，删除后对应的历史记录一并移除
+func (j *ScreenshotJanitor) collectReferences(ctx context.Context) (map[string][]string, bool) {
+	if j.redisClient == nil {
+		return nil, false
+	}
+
+	refs := make(map[string][]string)
+	for _, pattern := range []string{"screenshot:*", "cache:screenshot:*", "cache:itdog:*", "cache:report:*"} {
+		var cursor uint64
+		for {
+			keys, next, err := j.redisClient.Scan(ctx, cursor, pattern, 100).Result()
+			if err != nil {
+				log.Printf("[JANITOR] 扫描缓存失败，本次不按引用清理: %v", err)
+				return nil, false
+			}
+			for _, key := range keys {
+				if key == storageAccessKey {
+					continue
+				}
+				var values []string
+				switch {
+				case key == screenshotJobsKey:
+					values, _ = j.redisClient.HVals(ctx, key).Result()
+				case strings.HasPrefix(key, historyKeyPrefix):
+					values, _ = j.redisClient.ZRange(ctx, key, 0, -1).Result()
+				default:
+					if value, err := j.redisClient.Get(ctx, key).Result(); err == nil {
+						values = []string{value}
+					}
+				}
+				for _, value := range values {
+					for _, storageKey := range referencedKeys(value) {
+						refs[storageKey] = append(refs[storageKey], key)
+					}
+				}
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return refs, true
+}
+
+// referencedKeys 提取缓存值中引用的存储键：/static/开头的URL，或以清理范围前缀开头的存储键
+func referencedKeys(value string) []string {
+	var data interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil
+	}
+
+	var keys []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case string:
+			key := v
+			if strings.HasPrefix(key, "/static/") {
+				key = storageKeyFromURL(key)
+			}
+			for _, prefix := range janitorPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+					break
+				}
+			}
+		}
+	}
+	walk(data)
+	return keys
+}
+
+// dropReferences 删除引用已清理文件的缓存，下次请求重新截图；历史记录只移除对应的条目，定时任务的保留列表不删除
+func (j *ScreenshotJanitor) dropReferences(ctx context.Context, storageKey string, keys []string) {
+	if j.redisClient == nil {
+		return
+	}
+	for _, key := range keys {
+		switch {
+		case key == screenshotJobsKey:
+		case strings.HasPrefix(key, historyKeyPrefix):
+			if err := pruneHistory(ctx, j.redisClient, key, map[string]bool{storageKey: true}); err != nil {
+				log.Printf("[JANITOR] 移除历史记录失败: %s, %v", key, err)
+			}
+		default:
+			j.redisClient.Del(ctx, key)
+		}
+	}
+}
+
+// accessTimes 读取文件的最近访问时间
+func (j *ScreenshotJanitor) accessTimes(ctx context.Context) map[string]time.Time {
+	accessed := make(map[string]time.Time)
+	if j.redisClient == nil {
+		return accessed
+	}
+	members, err := j.redisClient.ZRangeWithScores(ctx, storageAccessKey, 0, -1).Result()
+	if err != nil {
+		return accessed
+	}
+	for _, member := range members {
+		if key, ok := member.Member.(string); ok {
+			accessed[key] = time.Unix(int64(member.Score), 0)
+		}
+	}
+	return accessed
+}
+
+// touchStorage 记录文件的访问时间，供超出配额时按最近访问时间淘汰
+func touchStorage(ctx context.Context, redisClient *redis.Client, keys ...string) {
+	if redisClient == nil {
+		return
+	}
+	score := float64(time.Now().Unix())
+	members := make([]*redis.Z, 0, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			members = append(members, &redis.Z{Score: score, Member: key})
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+	if err := redisClient.ZAdd(ctx, storageAccessKey, members...).Err(); err != nil {
+		log.Printf("[JANITOR] 记录文件访问时间失败: %v", err)
+	}
+}
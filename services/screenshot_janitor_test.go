@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestPlanSweep 测试过期、无引用和超出配额三种清理规则
+func TestPlanSweep(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	object := func(key string, size int64, modAgo, accessAgo time.Duration) janitorObject {
+		return janitorObject{
+			ObjectInfo: ObjectInfo{Key: key, Size: size, ModTime: now.Add(-modAgo)},
+			lastAccess: now.Add(-accessAgo),
+		}
+	}
+	objects := []janitorObject{
+		object("screenshots/expired.png", 10, 40*24*time.Hour, time.Hour),
+		object("screenshots/orphan.png", 10, 2*time.Hour, 2*time.Hour),
+		object("screenshots/fresh.png", 10, time.Minute, time.Minute),
+		object("screenshots/old-access.png", 30, 5*time.Hour, 4*time.Hour),
+		object("screenshots/recent-access.png", 30, 5*time.Hour, time.Minute),
+	}
+	refs := map[string][]string{
+		"screenshots/expired.png":       {"screenshot:basic:1"},
+		"screenshots/old-access.png":    {"screenshot:basic:2"},
+		"screenshots/recent-access.png": {"screenshot:basic:3"},
+	}
+	config := JanitorConfig{Retention: 30 * 24 * time.Hour, GracePeriod: time.Hour}
+
+	reasons := func(removals []janitorRemovalPlan) map[string]string {
+		result := make(map[string]string)
+		for _, removal := range removals {
+			result[removal.Key] = removal.reason
+		}
+		return result
+	}
+
+	got := reasons(planSweep(objects, refs, true, now, config))
+	want := map[string]string{"screenshots/expired.png": RemovalExpired, "screenshots/orphan.png": RemovalUnreferenced}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("无配额时清理 %v, 期望 %v", got, want)
+	}
+
+	// 剩余70字节，配额50：淘汰最久未访问的文件，宽限期内的新文件不淘汰
+	config.MaxBytes = 50
+	got = reasons(planSweep(objects, refs, true, now, config))
+	want["screenshots/old-access.png"] = RemovalQuota
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("有配额时清理 %v, 期望 %v", got, want)
+	}
+
+	// 无法读取缓存引用时不删除无引用的文件
+	config.MaxBytes = 0
+	got = reasons(planSweep(objects, nil, false, now, config))
+	if !reflect.DeepEqual(got, map[string]string{"screenshots/expired.png": RemovalExpired}) {
+		t.Errorf("未检查引用时清理 %v", got)
+	}
+}
+
+// TestReferencedKeys 测试从缓存值中提取存储键，兼容旧版/static URL
+func TestReferencedKeys(t *testing.T) {
+	value := `{"image_url":"https://bucket.s3.amazonaws.com/screenshots/a.png?X-Amz-Signature=x","image_key":"screenshots/a.png",
+		"thumbnail_url":"/static/screenshots/a_thumb.png","metadata":{"har":{"key":"screenshots/a.har"}},
+		"captures":["itdog/b.png"],"image_base64":"aGVsbG8/d29ybGQ=","message":"diffs are here"}`
+	keys := referencedKeys(value)
+	sort.Strings(keys)
+	want := []string{"itdog/b.png", "screenshots/a.har", "screenshots/a.png", "screenshots/a_thumb.png"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("提取的存储键 %v, 期望 %v", keys, want)
+	}
+	if keys := referencedKeys("not json"); len(keys) != 0 {
+		t.Errorf("非JSON值不应有引用: %v", keys)
+	}
+}
+
+// TestScreenshotJanitorSweep 测试试运行不删除文件、实际清理后统计回收字节数
+func TestScreenshotJanitorSweep(t *testing.T) {
+	baseDir := t.TempDir()
+	config := *DefaultScreenshotServiceConfig
+	config.BaseDir = baseDir
+	janitor := NewScreenshotJanitor(&ScreenshotService{config: &config}, nil, JanitorConfig{Retention: 24 * time.Hour})
+
+	write := func(key string, size int, age time.Duration) string {
+		path := filepath.Join(baseDir, filepath.FromSlash(key))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, make([]byte, size), 0644)
+		modTime := time.Now().Add(-age)
+		os.Chtimes(path, modTime, modTime)
+		return path
+	}
+	expired := write("screenshots/old.png", 100, 48*time.Hour)
+	report := write("reports/old.pdf", 50, 72*time.Hour)
+	kept := write("diffs/new.png", 10, time.Hour)
+	write("static.txt", 1000, 72*time.Hour) // 不在清理范围内
+
+	dryRun, err := janitor.Sweep(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.ScannedFiles != 3 || dryRun.RemovedFiles != 2 || dryRun.ReclaimedBytes != 150 || dryRun.ReferencesChecked {
+		t.Errorf("试运行结果不正确: %+v", dryRun)
+	}
+	if _, err := os.Stat(expired); err != nil {
+		t.Error("试运行不应删除文件")
+	}
+	if stats := janitor.Stats(); stats.Runs != 0 || stats.LastDryRun == nil {
+		t.Errorf("试运行不应计入清理统计: %+v", stats)
+	}
+
+	if _, err := janitor.Sweep(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{expired, report} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s 应被删除", path)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Error("未过期的文件不应被删除")
+	}
+	if stats := janitor.Stats(); stats.Runs != 1 || stats.TotalRemovedFiles != 2 || stats.TotalReclaimedBytes != 150 || stats.LastRun.RemainingBytes != 10 {
+		t.Errorf("清理统计不正确: %+v", stats)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图出口代理与地区模拟 - 服务端命名代理白名单、语言区域、时区和地理位置覆盖
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// 地理位置精度范围（米）
+const (
+	DefaultGeolocationAccuracy = 100
+	MaxGeolocationAccuracy     = 100000
+)
+
+var (
+	proxyNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	localePattern    = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,3}$`)
+)
+
+// ScreenshotProxy 服务端配置的命名代理，请求只能通过名称选择，地址和凭据不会返回给调用方
+type ScreenshotProxy struct {
+	Name     string
+	Scheme   string // http/https/socks5
+	Server   string // scheme://host:port，由出站代理连接；远程浏览器未配置出站代理地址时直接传给Target.createBrowserContext
+	Username string
+	Password string
+}
+
+// needsAuth 代理是否需要认证
+func (p *ScreenshotProxy) needsAuth() bool {
+	return p != nil && p.Username != ""
+}
+
+// Geolocation 模拟的地理位置
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"` // 精度（米），默认100
+}
+
+// RegionConfig 出口代理与地区模拟配置
+type RegionConfig struct {
+	Proxy       *ScreenshotProxy
+	Locale      string
+	Timezone    string
+	Geolocation *Geolocation
+}
+
+// loadProxies 解析命名代理列表，格式为 name=scheme://[user:pass@]host:port，以逗号或换行分隔
+// 无效条目记录日志后忽略，不影响其余代理
+func loadProxies(spec string) map[string]*ScreenshotProxy {
+	proxies := make(map[string]*ScreenshotProxy)
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		proxy, err := parseProxy(entry)
+		if err != nil {
+			log.Printf("[SCREENSHOT] 忽略代理配置: %v", err)
+			continue
+		}
+		proxies[proxy.Name] = proxy
+	}
+	if len(proxies) > 0 {
+		log.Printf("[SCREENSHOT] 已加载 %d 个命名代理: %s", len(proxies), strings.Join(proxyNames(proxies), ", "))
+	}
+	return proxies
+}
+
+// parseProxy 解析单个命名代理
+func parseProxy(entry string) (*ScreenshotProxy, error) {
+	name, rawURL, ok := strings.Cut(entry, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || !proxyNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("代理名称无效，应为 name=url 且名称由小写字母、数字、下划线或连字符组成")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("代理 %s 地址无效", name)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("代理 %s 不支持的协议: %s", name, u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("代理 %s 必须包含主机和端口", name)
+	}
+
+	proxy := &ScreenshotProxy{
+		Name:   name,
+		Scheme: scheme,
+		Server: fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(u.Hostname(), u.Port())),
+	}
+	if u.User != nil {
+		proxy.Username = u.User.Username()
+		proxy.Password, _ = u.User.Password()
+	}
+	return proxy, nil
+}
+
+// proxyNames 排序后的代理名称
+func proxyNames(proxies map[string]*ScreenshotProxy) []string {
+	names := make([]string, 0, len(proxies))
+	for name := range proxies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseGeolocation 解析查询参数中的地理位置，格式为 纬度,经度[,精度]
+func ParseGeolocation(value string) (*Geolocation, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("geolocation格式应为 纬度,经度[,精度]")
+	}
+	numbers := make([]float64, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("geolocation格式应为 纬度,经度[,精度]")
+		}
+		numbers[i] = n
+	}
+	geo := &Geolocation{Latitude: numbers[0], Longitude: numbers[1]}
+	if len(numbers) == 3 {
+		geo.Accuracy = numbers[2]
+	}
+	return geo, nil
+}
+
+// resolveRegion 根据请求参数生成地区配置，代理只能从服务端白名单中选择
+func resolveRegion(req *ScreenshotRequest, proxies map[string]*ScreenshotProxy) (*RegionConfig, error) {
+	region := &RegionConfig{}
+
+	if req.Proxy != "" {
+		proxy, ok := proxies[strings.ToLower(req.Proxy)]
+		if !ok {
+			if len(proxies) == 0 {
+				return nil, fmt.Errorf("服务未配置代理，不能使用proxy参数")
+			}
+			return nil, fmt.Errorf("未知的代理: %s，可选: %s", req.Proxy, strings.Join(proxyNames(proxies), ", "))
+		}
+		region.Proxy = proxy
+	}
+
+	if req.Locale != "" {
+		if !localePattern.MatchString(req.Locale) {
+			return nil, fmt.Errorf("无效的locale: %s，应为如zh-CN、en-US的语言标签", req.Locale)
+		}
+		region.Locale = req.Locale
+	}
+
+	if req.Timezone != "" {
+		// Local依赖服务器配置，不是确定的时区
+		if req.Timezone == "Local" {
+			return nil, fmt.Errorf("无效的timezone: %s", req.Timezone)
+		}
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("无效的timezone: %s，应为如Asia/Shanghai的IANA时区", req.Timezone)
+		}
+		region.Timezone = req.Timezone
+	}
+
+	if geo := req.Geolocation; geo != nil {
+		if geo.Latitude < -90 || geo.Latitude > 90 {
+			return nil, fmt.Errorf("geolocation纬度必须在-90到90之间")
+		}
+		if geo.Longitude < -180 || geo.Longitude > 180 {
+			return nil, fmt.Errorf("geolocation经度必须在-180到180之间")
+		}
+		if geo.Accuracy < 0 || geo.Accuracy > MaxGeolocationAccuracy {
+			return nil, fmt.Errorf("geolocation精度必须在0-%d米之间", MaxGeolocationAccuracy)
+		}
+		region.Geolocation = &Geolocation{Latitude: geo.Latitude, Longitude: geo.Longitude, Accuracy: geo.Accuracy}
+		if region.Geolocation.Accuracy == 0 {
+			region.Geolocation.Accuracy = DefaultGeolocationAccuracy
+		}
+	}
+
+	return region, nil
+}
+
+// isDefault 未设置任何地区参数
+func (r *RegionConfig) isDefault() bool {
+	return r.Proxy == nil && r.Locale == "" && r.Timezone == "" && r.Geolocation == nil
+}
+
+// emulate 返回设置语言区域、时区和地理位置的动作，需要在视口模拟之后、导航之前执行
+// userAgent为请求覆盖的UA，Accept-Language只能随UA一起设置，为空时沿用浏览器默认UA
+func (r *RegionConfig) emulate(userAgent string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if r.Timezone != "" {
+			if err := emulation.SetTimezoneOverride(r.Timezone).Do(ctx); err != nil {
+				return fmt.Errorf("设置时区失败: %v", err)
+			}
+		}
+
+		if r.Locale != "" {
+			if err := emulation.SetLocaleOverride().WithLocale(r.Locale).Do(ctx); err != nil {
+				return fmt.Errorf("设置语言区域失败: %v", err)
+			}
+			if userAgent == "" {
+				c := chromedp.FromContext(ctx)
+				var err error
+				if _, _, _, userAgent, _, err = browser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+					return fmt.Errorf("获取浏览器UA失败: %v", err)
+				}
+			}
+			if err := emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(acceptLanguage(r.Locale)).Do(ctx); err != nil {
+				return fmt.Errorf("设置Accept-Language失败: %v", err)
+			}
+		}
+
+		if geo := r.Geolocation; geo != nil {
+			// 定位权限授予当前任务的浏览器上下文，不影响其他任务
+			c := chromedp.FromContext(ctx)
+			grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).
+				WithBrowserContextID(c.BrowserContextID)
+			if err := grant.Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+				return fmt.Errorf("授予定位权限失败: %v", err)
+			}
+			if err := emulation.SetGeolocationOverride().
+				WithLatitude(geo.Latitude).
+				WithLongitude(geo.Longitude).
+				WithAccuracy(geo.Accuracy).
+				Do(ctx); err != nil {
+				return fmt.Errorf("设置地理位置失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// acceptLanguage 根据语言区域生成Accept-Language，带地区的标签追加基础语言作为备选
+func acceptLanguage(locale string) string {
+	base, _, found := strings.Cut(locale, "-")
+	if !found {
+		return locale
+	}
+	return fmt.Sprintf("%s,%s;q=0.9", locale, base)
+}
+
+// metadata 响应中返回的地区设置，代理只返回名称和协议
+func (r *RegionConfig) metadata() map[string]interface{} {
+	meta := make(map[string]interface{})
+	if r.Proxy != nil {
+		meta["proxy"] = r.Proxy.Name
+		meta["proxy_type"] = r.Proxy.Scheme
+	}
+	if r.Locale != "" {
+		meta["locale"] = r.Locale
+	}
+	if r.Timezone != "" {
+		meta["timezone"] = r.Timezone
+	}
+	if r.Geolocation != nil {
+		meta["geolocation"] = r.Geolocation
+	}
+	return meta
+}
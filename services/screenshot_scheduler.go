@@ -0,0 +1,599 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 定时截图任务 - 按cron表达式定期截图，任务和下次执行时间保存在Redis中，多副本部署时每次执行只由一个副本完成
+ */
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"whosee/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+)
+
+// 定时任务参数
+const (
+	screenshotJobsKey      = "screenshot:jobs"
+	screenshotJobLockKey   = "screenshot:jobs:lock"
+	schedulerTickInterval  = 30 * time.Second
+	jobRunLockTTL          = MinJobInterval // 执行锁有效期，执行间隔不小于该值，过期后不会与下一次执行冲突
+	jobUpdateRetries       = 5
+	DefaultJobRetention    = 10
+	MaxJobRetention        = 100
+	MaxScreenshotJobs      = 200
+	MinJobInterval         = 5 * time.Minute
+	screenshotJobQueueSize = MaxScreenshotJobs
+)
+
+// ScreenshotJobResult 任务最近一次执行结果
+type ScreenshotJobResult struct {
+	Success    bool   `json:"success"`
+	ImageURL   string `json:"image_url,omitempty"`
+	ImageKey   string `json:"image_key,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// ScreenshotJob 定时截图任务
+type ScreenshotJob struct {
+	ID       string         `json:"id"`
+	Domain   string         `json:"domain"`
+	Schedule string         `json:"schedule"` // 标准5段cron表达式，或 @every 1h、@daily 等
+	Type     ScreenshotType `json:"type"`
+	Width    int            `json:"width,omitempty"`
+	Height   int            `json:"height,omitempty"`
+	Device   string         `json:"device,omitempty"`
+	FullPage bool           `json:"full_page,omitempty"`
+
+	Retention int `json:"retention"` // 保留的截图文件数量，超出的旧文件被删除
+
+	CreatedAt           string               `json:"created_at"`
+	NextRun             string               `json:"next_run,omitempty"`
+	LastRun             string               `json:"last_run,omitempty"`
+	LastResult          *ScreenshotJobResult `json:"last_result,omitempty"`
+	Runs                int                  `json:"runs"`
+	Failures            int                  `json:"failures"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+	Captures            []string             `json:"captures,omitempty"` // 保留的截图存储键，最新在前
+	Running             bool                 `json:"running"`
+
+	schedule cron.Schedule
+	nextRun  time.Time
+}
+
+// request 任务对应的截图请求，跳过缓存以保证每次都是新截图
+func (j *ScreenshotJob) request() *ScreenshotRequest {
+	return &ScreenshotRequest{
+		Type:      j.Type,
+		Domain:    j.Domain,
+		Format:    FormatFile,
+		Width:     j.Width,
+		Height:    j.Height,
+		Device:    j.Device,
+		FullPage:  j.FullPage,
+		SkipCache: true,
+	}
+}
+
+// ScreenshotScheduler 定时截图调度器
+// Redis哈希是任务的唯一来源，下次执行时间也保存在其中；多个副本同时调度时，每次执行用SETNX锁保证只有一个副本截图
+// 任务并发数低于ChromeManager的并发上限，始终为在线截图请求保留槽位
+type ScreenshotScheduler struct {
+	mu          sync.Mutex
+	service     *ScreenshotService
+	redisClient *redis.Client
+	jobs        map[string]*ScreenshotJob // 未配置Redis时的任务存储
+	running     map[string]bool           // 本副本已入队或正在执行的任务
+	owner       string                    // 执行锁的值，标识当前副本
+	queue       chan string
+	workers     int
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewScreenshotScheduler 创建定时截图调度器
+func NewScreenshotScheduler(service *ScreenshotService, redisClient *redis.Client) *ScreenshotScheduler {
+	workers := 1
+	if service.chromeManager != nil {
+		workers = max(1, service.chromeManager.MaxConcurrent()-1)
+	}
+	hostname, _ := os.Hostname()
+
+	return &ScreenshotScheduler{
+		service:     service,
+		redisClient: redisClient,
+		jobs:        make(map[string]*ScreenshotJob),
+		running:     make(map[string]bool),
+		owner:       fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		queue:       make(chan string, screenshotJobQueueSize),
+		workers:     workers,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start 启动调度，任务在每次调度时从Redis读取
+func (s *ScreenshotScheduler) Start() {
+	jobs, err := s.loadJobs(context.Background())
+	if err != nil {
+		log.Printf("[SCHEDULER] 加载定时截图任务失败: %v", err)
+	}
+	log.Printf("[SCHEDULER] 启动定时截图调度，任务数: %d，并发: %d", len(jobs), s.workers)
+
+	for i := 0; i < s.workers; i++ {
+		go s.worker()
+	}
+
+	go func() {
+		ticker := time.NewTicker(schedulerTickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.dispatchDue(now)
+			case <-s.stopChan:
+				log.Printf("[SCHEDULER] 定时截图调度已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止调度，正在执行的截图会继续完成
+func (s *ScreenshotScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+}
+
+// AddJob 校验并注册任务
+func (s *ScreenshotScheduler) AddJob(job *ScreenshotJob) (*ScreenshotJob, error) {
+	if err := s.prepareJob(job); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job.ID = strconv.FormatInt(now.UnixNano(), 36)
+	job.CreatedAt = now.UTC().Format(time.RFC3339)
+	job.setNextRun(job.schedule.Next(now))
+
+	if s.redisClient == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.jobs) >= MaxScreenshotJobs {
+			return nil, fmt.Errorf("定时截图任务数量已达上限 %d", MaxScreenshotJobs)
+		}
+		s.jobs[job.ID] = job
+	} else {
+		ctx := context.Background()
+		count, err := s.redisClient.HLen(ctx, screenshotJobsKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("读取任务失败: %v", err)
+		}
+		if count >= MaxScreenshotJobs {
+			return nil, fmt.Errorf("定时截图任务数量已达上限 %d", MaxScreenshotJobs)
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.redisClient.HSetNX(ctx, screenshotJobsKey, job.ID, data).Err(); err != nil {
+			return nil, fmt.Errorf("保存任务失败: %v", err)
+		}
+	}
+
+	log.Printf("[SCHEDULER] 添加定时截图任务 %s: %s %s，下次执行: %s", job.ID, job.Domain, job.Schedule, job.NextRun)
+	return job.snapshot(), nil
+}
+
+// DeleteJob 删除任务，已保留的截图文件不会被删除
+func (s *ScreenshotScheduler) DeleteJob(id string) (bool, error) {
+	if s.redisClient == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.jobs[id]; !ok {
+			return false, nil
+		}
+		delete(s.jobs, id)
+	} else {
+		deleted, err := s.redisClient.HDel(context.Background(), screenshotJobsKey, id).Result()
+		if err != nil {
+			return false, err
+		}
+		if deleted == 0 {
+			return false, nil
+		}
+	}
+	log.Printf("[SCHEDULER] 删除定时截图任务 %s", id)
+	return true, nil
+}
+
+// GetJob 获取任务状态，任务不存在时返回nil
+func (s *ScreenshotScheduler) GetJob(id string) (*ScreenshotJob, error) {
+	return s.getJob(context.Background(), id)
+}
+
+// ListJobs 按创建时间返回所有任务状态
+func (s *ScreenshotScheduler) ListJobs() ([]*ScreenshotJob, error) {
+	jobs, err := s.loadJobs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// prepareJob 校验任务参数并解析调度表达式
+func (s *ScreenshotScheduler) prepareJob(job *ScreenshotJob) error {
+	if !utils.IsValidDomain(job.Domain) {
+		return fmt.Errorf("无效的域名格式: %s", job.Domain)
+	}
+	if job.Type == "" {
+		job.Type = TypeBasic
+	}
+	if job.Type == TypeElement {
+		return fmt.Errorf("定时任务不支持元素截图")
+	}
+	if s.service.getDescription(job.Type) == "" {
+		return fmt.Errorf("不支持的截图类型: %s", job.Type)
+	}
+	if _, err := resolveViewport(job.request()); err != nil {
+		return err
+	}
+
+	if job.Retention == 0 {
+		job.Retention = DefaultJobRetention
+	}
+	if job.Retention < 1 || job.Retention > MaxJobRetention {
+		return fmt.Errorf("保留数量必须在 1-%d 之间", MaxJobRetention)
+	}
+
+	schedule, err := cron.ParseStandard(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("无效的调度表达式: %v", err)
+	}
+	first := schedule.Next(time.Now())
+	if schedule.Next(first).Sub(first) < MinJobInterval {
+		return fmt.Errorf("执行间隔不能小于 %v", MinJobInterval)
+	}
+	job.schedule = schedule
+	return nil
+}
+
+// decodeJob 解析Redis中保存的任务，没有下次执行时间的旧任务从当前时间计算
+func decodeJob(data string) (*ScreenshotJob, error) {
+	var job ScreenshotJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, err
+	}
+	schedule, err := cron.ParseStandard(job.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("调度表达式无效: %v", err)
+	}
+	job.schedule = schedule
+	if next, err := time.Parse(time.RFC3339, job.NextRun); err == nil {
+		job.nextRun = next
+	} else {
+		job.setNextRun(schedule.Next(time.Now()))
+	}
+	return &job, nil
+}
+
+// loadJobs 读取所有任务
+func (s *ScreenshotScheduler) loadJobs(ctx context.Context) ([]*ScreenshotJob, error) {
+	if s.redisClient == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		jobs := make([]*ScreenshotJob, 0, len(s.jobs))
+		for _, job := range s.jobs {
+			jobs = append(jobs, job.snapshot())
+		}
+		return jobs, nil
+	}
+
+	entries, err := s.redisClient.HGetAll(ctx, screenshotJobsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*ScreenshotJob, 0, len(entries))
+	for id, data := range entries {
+		job, err := decodeJob(data)
+		if err != nil {
+			log.Printf("[SCHEDULER] 解析任务 %s 失败: %v", id, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// getJob 读取单个任务，不存在时返回nil
+func (s *ScreenshotScheduler) getJob(ctx context.Context, id string) (*ScreenshotJob, error) {
+	if s.redisClient == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if job, ok := s.jobs[id]; ok {
+			return job.snapshot(), nil
+		}
+		return nil, nil
+	}
+
+	data, err := s.redisClient.HGet(ctx, screenshotJobsKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJob(data)
+}
+
+// updateJob 读取、修改并写回任务，Redis中使用WATCH乐观锁，其他副本同时修改时重试；任务已被删除时返回nil
+func (s *ScreenshotScheduler) updateJob(ctx context.Context, id string, update func(job *ScreenshotJob)) (*ScreenshotJob, error) {
+	if s.redisClient == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		job, ok := s.jobs[id]
+		if !ok {
+			return nil, nil
+		}
+		update(job)
+		return job.snapshot(), nil
+	}
+
+	var updated *ScreenshotJob
+	txf := func(tx *redis.Tx) error {
+		updated = nil
+		data, err := tx.HGet(ctx, screenshotJobsKey, id).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		job, err := decodeJob(data)
+		if err != nil {
+			return err
+		}
+		update(job)
+		encoded, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, screenshotJobsKey, id, encoded)
+			return nil
+		})
+		updated = job
+		return err
+	}
+	for i := 0; i < jobUpdateRetries; i++ {
+		if err := s.redisClient.Watch(ctx, txf, screenshotJobsKey); !errors.Is(err, redis.TxFailedErr) {
+			return updated, err
+		}
+	}
+	return nil, fmt.Errorf("任务 %s 并发修改冲突", id)
+}
+
+// claim 获取一次计划执行的锁，锁键包含计划执行时间，同一次执行只有一个副本能获取
+// 锁不主动释放，过期前其他副本即使读到旧的下次执行时间也不会重复执行
+func (s *ScreenshotScheduler) claim(ctx context.Context, job *ScreenshotJob) bool {
+	if s.redisClient == nil {
+		return true
+	}
+	key := fmt.Sprintf("%s:%s:%d", screenshotJobLockKey, job.ID, job.nextRun.Unix())
+	acquired, err := s.redisClient.SetNX(ctx, key, s.owner, jobRunLockTTL).Result()
+	if err != nil {
+		log.Printf("[SCHEDULER] 获取任务 %s 执行锁失败: %v", job.ID, err)
+		return false
+	}
+	return acquired
+}
+
+// dispatchDue 将到期任务放入执行队列，同一任务不会重叠执行
+// 错过超过一个周期的执行（如所有副本都停止期间）不补跑，从当前时间重新计算下次执行
+func (s *ScreenshotScheduler) dispatchDue(now time.Time) {
+	ctx := context.Background()
+	jobs, err := s.loadJobs(ctx)
+	if err != nil {
+		log.Printf("[SCHEDULER] 读取定时截图任务失败: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if now.Before(job.nextRun) || s.isRunning(job.ID) || !s.claim(ctx, job) {
+			continue
+		}
+
+		missed := job.schedule.Next(job.nextRun).Before(now)
+		next := job.schedule.Next(now)
+		if _, err := s.updateJob(ctx, job.ID, func(j *ScreenshotJob) {
+			j.setNextRun(next)
+			j.Running = !missed
+		}); err != nil {
+			log.Printf("[SCHEDULER] 更新任务 %s 下次执行时间失败: %v", job.ID, err)
+			continue
+		}
+		if missed {
+			log.Printf("[SCHEDULER] 任务 %s 错过了 %s 的执行，不补跑，下次执行: %s", job.ID, job.NextRun, next.UTC().Format(time.RFC3339))
+			continue
+		}
+
+		if !s.enqueue(job.ID) {
+			log.Printf("[SCHEDULER] 执行队列已满，跳过任务 %s 本次执行", job.ID)
+			s.updateJob(ctx, job.ID, func(j *ScreenshotJob) { j.Running = false })
+		}
+	}
+}
+
+// isRunning 任务是否已在本副本入队或执行中
+func (s *ScreenshotScheduler) isRunning(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[id]
+}
+
+// enqueue 将任务放入执行队列，队列已满时返回false
+func (s *ScreenshotScheduler) enqueue(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.queue <- id:
+		s.running[id] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// worker 逐个执行队列中的任务
+func (s *ScreenshotScheduler) worker() {
+	for {
+		select {
+		case id := <-s.queue:
+			s.run(id)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// run 执行一次截图并更新任务状态
+func (s *ScreenshotScheduler) run(id string) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.mu.Unlock()
+	}()
+
+	ctx := context.Background()
+	job, err := s.getJob(ctx, id)
+	if err != nil {
+		log.Printf("[SCHEDULER] 读取任务 %s 失败: %v", id, err)
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	startTime := time.Now()
+	response, err := s.service.TakeScreenshot(ctx, job.request())
+	duration := time.Since(startTime)
+
+	result := &ScreenshotJobResult{
+		DurationMs: duration.Milliseconds(),
+		FinishedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	switch {
+	case err != nil:
+		result.Error = "INTERNAL_ERROR"
+		result.Message = err.Error()
+	case !response.Success:
+		result.Error = response.Error
+		result.Message = response.Message
+	default:
+		result.Success = true
+		result.ImageURL = response.ImageURL
+		result.ImageKey = response.ImageKey
+	}
+
+	if s.service.chromeManager != nil {
+		if result.Success {
+			s.service.chromeManager.OnSuccess(duration)
+		} else {
+			s.service.chromeManager.OnFailure(duration)
+		}
+	}
+
+	// 执行期间任务可能已被删除，截图文件由清理任务回收
+	var expired []string
+	job, err = s.updateJob(ctx, id, func(job *ScreenshotJob) {
+		job.Running = false
+		job.Runs++
+		job.LastRun = startTime.UTC().Format(time.RFC3339)
+		job.LastResult = result
+		expired = nil
+		if result.Success {
+			job.ConsecutiveFailures = 0
+			expired = trimCaptures(job, result.ImageKey)
+		} else {
+			job.Failures++
+			job.ConsecutiveFailures++
+		}
+	})
+	if err != nil {
+		log.Printf("[SCHEDULER] 保存任务 %s 状态失败: %v", id, err)
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	if result.Success {
+		s.removeCaptures(ctx, job, expired)
+		log.Printf("[SCHEDULER] 任务 %s 截图成功: %s, 耗时: %v", id, job.Domain, duration)
+	} else {
+		log.Printf("[SCHEDULER] 任务 %s 截图失败: %s, %s: %s", id, job.Domain, result.Error, result.Message)
+	}
+}
+
+// trimCaptures 记录新截图，返回超出保留数量的旧截图
+func trimCaptures(job *ScreenshotJob, imageKey string) []string {
+	job.Captures = append([]string{imageKey}, job.Captures...)
+	if len(job.Captures) <= job.Retention {
+		return nil
+	}
+	expired := append([]string(nil), job.Captures[job.Retention:]...)
+	job.Captures = job.Captures[:job.Retention]
+	return expired
+}
+
+// removeCaptures 删除过期截图文件并移除对应的历史记录，兼容升级前保存的/static路径
+func (s *ScreenshotScheduler) removeCaptures(ctx context.Context, job *ScreenshotJob, expired []string) {
+	if len(expired) == 0 {
+		return
+	}
+
+	deleted := make(map[string]bool, len(expired))
+	for _, capture := range expired {
+		key := storageKeyFromURL(capture)
+		if err := s.service.fileStorage().Delete(ctx, key); err != nil {
+			log.Printf("[SCHEDULER] 删除过期截图失败: %s, %v", capture, err)
+			continue
+		}
+		deleted[key] = true
+	}
+	if s.redisClient != nil && len(deleted) > 0 {
+		if err := pruneHistory(ctx, s.redisClient, historyKey(job.Domain, job.Type), deleted); err != nil {
+			log.Printf("[SCHEDULER] 移除任务 %s 的过期历史记录失败: %v", job.ID, err)
+		}
+	}
+}
+
+func (j *ScreenshotJob) setNextRun(next time.Time) {
+	j.nextRun = next
+	j.NextRun = next.UTC().Format(time.RFC3339)
+}
+
+// snapshot 返回任务状态副本，避免调用方与调度器并发访问
+func (j *ScreenshotJob) snapshot() *ScreenshotJob {
+	copied := *j
+	copied.Captures = append([]string(nil), j.Captures...)
+	if j.LastResult != nil {
+		result := *j.LastResult
+		copied.LastResult = &result
+	}
+	return &copied
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestScreenshotSchedulerAddJob 测试任务校验、默认值和最小执行间隔
+func TestScreenshotSchedulerAddJob(t *testing.T) {
+	service := &ScreenshotService{config: DefaultScreenshotServiceConfig}
+	scheduler := NewScreenshotScheduler(service, nil)
+
+	job, err := scheduler.AddJob(&ScreenshotJob{Domain: "example.com", Schedule: "@every 1h", Device: "ipad"})
+	if err != nil {
+		t.Fatalf("AddJob 返回错误: %v", err)
+	}
+	if job.Type != TypeBasic || job.Retention != DefaultJobRetention || job.NextRun == "" {
+		t.Errorf("任务默认值不正确: %+v", job)
+	}
+	if got, err := scheduler.ListJobs(); err != nil || len(got) != 1 || got[0].ID != job.ID {
+		t.Errorf("ListJobs 返回 %v, %v", got, err)
+	}
+
+	invalid := []*ScreenshotJob{
+		{Domain: "not a domain", Schedule: "@hourly"},
+		{Domain: "example.com", Schedule: "every hour"},
+		{Domain: "example.com", Schedule: "* * * * *"},
+		{Domain: "example.com", Schedule: "@hourly", Type: TypeElement},
+		{Domain: "example.com", Schedule: "@hourly", Retention: MaxJobRetention + 1},
+		{Domain: "example.com", Schedule: "@hourly", Width: 20000},
+	}
+	for _, job := range invalid {
+		if _, err := scheduler.AddJob(job); err == nil {
+			t.Errorf("期望任务 %+v 校验失败", job)
+		}
+	}
+}
+
+// TestScreenshotSchedulerDispatch 测试到期任务入队且不会重叠执行
+func TestScreenshotSchedulerDispatch(t *testing.T) {
+	service := &ScreenshotService{config: DefaultScreenshotServiceConfig}
+	scheduler := NewScreenshotScheduler(service, nil)
+	job, err := scheduler.AddJob(&ScreenshotJob{Domain: "example.com", Schedule: "@every 10m"})
+	if err != nil {
+		t.Fatalf("AddJob 返回错误: %v", err)
+	}
+
+	scheduler.dispatchDue(time.Now())
+	if len(scheduler.queue) != 0 {
+		t.Fatalf("未到期的任务不应入队")
+	}
+
+	later := time.Now().Add(11 * time.Minute)
+	scheduler.dispatchDue(later)
+	scheduler.dispatchDue(later.Add(11 * time.Minute))
+	if got, _ := scheduler.GetJob(job.ID); len(scheduler.queue) != 1 || !got.Running {
+		t.Errorf("执行中的任务不应重复入队, 队列长度 %d", len(scheduler.queue))
+	}
+}
+
+// TestScreenshotSchedulerMissedRun 测试错过超过一个周期的执行不补跑，只推进下次执行时间
+func TestScreenshotSchedulerMissedRun(t *testing.T) {
+	service := &ScreenshotService{config: DefaultScreenshotServiceConfig}
+	scheduler := NewScreenshotScheduler(service, nil)
+	job, err := scheduler.AddJob(&ScreenshotJob{Domain: "example.com", Schedule: "@every 10m"})
+	if err != nil {
+		t.Fatalf("AddJob 返回错误: %v", err)
+	}
+
+	later := time.Now().Add(25 * time.Minute)
+	scheduler.dispatchDue(later)
+	got, _ := scheduler.GetJob(job.ID)
+	if len(scheduler.queue) != 0 || got.Running || !got.nextRun.After(later) {
+		t.Errorf("错过的执行不应补跑: 队列长度 %d, %+v", len(scheduler.queue), got)
+	}
+}
+
+// TestScreenshotSchedulerRetain 测试超出保留数量的旧截图文件被删除
+func TestScreenshotSchedulerRetain(t *testing.T) {
+	baseDir := t.TempDir()
+	config := *DefaultScreenshotServiceConfig
+	config.BaseDir = baseDir
+	scheduler := NewScreenshotScheduler(&ScreenshotService{config: &config}, nil)
+
+	if err := os.MkdirAll(filepath.Join(baseDir, "screenshots"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	job := &ScreenshotJob{Retention: 2}
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		if err := os.WriteFile(filepath.Join(baseDir, "screenshots", name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		scheduler.removeCaptures(context.Background(), job, trimCaptures(job, "/static/screenshots/"+name))
+	}
+
+	if len(job.Captures) != 2 || job.Captures[0] != "/static/screenshots/c.png" {
+		t.Errorf("保留的截图不正确: %v", job.Captures)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "screenshots", "a.png")); !os.IsNotExist(err) {
+		t.Errorf("超出保留数量的截图应被删除")
+	}
+}
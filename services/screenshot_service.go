@@ -8,13 +8,19 @@ package services
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"whosee/utils"
@@ -27,11 +33,11 @@ import (
 type ScreenshotType string
 
 const (
-	TypeBasic        ScreenshotType = "basic"        // 基础截图
-	TypeElement      ScreenshotType = "element"      // 元素截图
-	TypeItdogMap     ScreenshotType = "itdog_map"    // ITDog地图
-	TypeItdogTable   ScreenshotType = "itdog_table"  // ITDog表格
-	TypeItdogIP      ScreenshotType = "itdog_ip"     // ITDog IP统计
+	TypeBasic        ScreenshotType = "basic"         // 基础截图
+	TypeElement      ScreenshotType = "element"       // 元素截图
+	TypeItdogMap     ScreenshotType = "itdog_map"     // ITDog地图
+	TypeItdogTable   ScreenshotType = "itdog_table"   // ITDog表格
+	TypeItdogIP      ScreenshotType = "itdog_ip"      // ITDog IP统计
 	TypeItdogResolve ScreenshotType = "itdog_resolve" // ITDog综合测速
 )
 
@@ -49,38 +55,119 @@ const MaxUserCacheExpireHours = 72 // 最多3天
 
 // ScreenshotRequest 统一截图请求结构
 type ScreenshotRequest struct {
-	Type        ScreenshotType `json:"type"`                  // 截图类型
-	Domain      string         `json:"domain"`                // 目标域名
-	URL         string         `json:"url,omitempty"`         // 完整URL（优先级高于domain）
-	Selector    string         `json:"selector,omitempty"`    // CSS/XPath选择器
-	Format      OutputFormat   `json:"format"`                // 输出格式
-	WaitTime    int            `json:"wait_time,omitempty"`   // 等待时间（秒）
-	Timeout     int            `json:"timeout,omitempty"`     // 超时时间（秒）
+	Type        ScreenshotType `json:"type"`                   // 截图类型
+	Domain      string         `json:"domain"`                 // 目标域名
+	URL         string         `json:"url,omitempty"`          // 完整URL（优先级高于domain）
+	Selector    string         `json:"selector,omitempty"`     // CSS/XPath选择器
+	Format      OutputFormat   `json:"format"`                 // 输出格式
+	WaitTime    int            `json:"wait_time,omitempty"`    // 等待时间（秒）
+	Timeout     int            `json:"timeout,omitempty"`      // 超时时间（秒）
 	CacheExpire int            `json:"cache_expire,omitempty"` // 缓存过期时间（小时）
+
+	// 视口与设备模拟
+	Width             int     `json:"width,omitempty"`               // 视口宽度
+	Height            int     `json:"height,omitempty"`              // 视口高度
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"` // 设备像素比
+	FullPage          bool    `json:"full_page,omitempty"`           // 是否截取整个页面
+	Mobile            bool    `json:"mobile,omitempty"`              // 是否模拟移动端
+	Device            string  `json:"device,omitempty"`              // 设备预设（iphone、pixel、ipad等）
+	UserAgent         string  `json:"user_agent,omitempty"`          // 覆盖User-Agent
+
+	// 页面就绪等待策略
+	WaitUntil     string `json:"wait_until,omitempty"`      // load/domcontentloaded/networkidle/selector/function/fixed
+	WaitSelector  string `json:"wait_selector,omitempty"`   // selector策略的CSS/XPath选择器
+	WaitFunction  string `json:"wait_function,omitempty"`   // function策略的JS表达式
+	NetworkIdleMs int    `json:"network_idle_ms,omitempty"` // networkidle策略要求的空闲时长（毫秒）
+	MaxWait       int    `json:"max_wait,omitempty"`        // 最长等待时间（秒）
+
+	// 图片输出参数
+	ImageFormat string `json:"image_format,omitempty"` // png/jpeg/jpg/webp，默认png
+	Quality     int    `json:"quality,omitempty"`      // jpeg/webp压缩质量 1-100
+	ThumbWidth  int    `json:"thumb_width,omitempty"`  // 缩略图最大宽度
+	ThumbHeight int    `json:"thumb_height,omitempty"` // 缩略图最大高度
+
+	// 页面内容与网络请求记录
+	Extract ExtractMode `json:"extract,omitempty"` // true/meta只提取元信息，可用逗号追加html、text
+	HAR     bool        `json:"har,omitempty"`     // 记录页面加载期间的网络请求并导出HAR
+
+	// 控制台错误数（含未捕获异常和资源加载失败）达到该值时请求失败，0表示不检查
+	FailOnConsoleErrors int `json:"fail_on_console_errors,omitempty"`
+
+	// 截图前按顺序执行的交互步骤，set_cookie在导航之前执行
+	Actions []*ScreenshotAction `json:"actions,omitempty"`
+
+	// 页面净化
+	HideSelectors []string `json:"hide_selectors,omitempty"` // 隐藏的元素（visibility:hidden）
+	BlockAds      bool     `json:"block_ads,omitempty"`      // 拦截广告、跟踪和客服挂件请求
+	ShowConsent   bool     `json:"show_consent,omitempty"`   // 保留同意弹窗，默认基础截图隐藏常见CMP弹窗
+
+	// 持久会话键，相同键的请求共享Cookie和本地存储（如登录状态），结果不缓存；为空时每次使用全新的隔离上下文
+	PersistSession string `json:"persist_session,omitempty"`
+
+	// 出口代理与地区模拟
+	Proxy       string       `json:"proxy,omitempty"`       // 服务端配置的代理名称
+	Locale      string       `json:"locale,omitempty"`      // 语言区域（如zh-CN），同时设置Accept-Language
+	Timezone    string       `json:"timezone,omitempty"`    // IANA时区（如Asia/Shanghai）
+	Geolocation *Geolocation `json:"geolocation,omitempty"` // 地理位置，自动授予定位权限
+
+	SkipCache bool `json:"-"` // 跳过缓存读写，定时任务需要每次真实截图且不覆盖在线请求的缓存
 }
 
 // ScreenshotResponse 统一截图响应结构
 type ScreenshotResponse struct {
-	Success     bool   `json:"success"`                // 是否成功
-	ImageURL    string `json:"image_url,omitempty"`    // 图片URL/Base64
-	ImageBase64 string `json:"image_base64,omitempty"` // Base64数据（仅Base64格式）
-	FromCache   bool   `json:"from_cache,omitempty"`   // 是否来自缓存
-	Error       string `json:"error,omitempty"`        // 错误码
-	Message     string `json:"message,omitempty"`      // 错误描述
-	Metadata    map[string]interface{} `json:"metadata,omitempty"` // 元数据
+	Success      bool                   `json:"success"`                 // 是否成功
+	ImageURL     string                 `json:"image_url,omitempty"`     // 图片URL/Base64
+	ImageBase64  string                 `json:"image_base64,omitempty"`  // Base64数据（仅Base64格式）
+	ThumbnailURL string                 `json:"thumbnail_url,omitempty"` // 缩略图URL/Base64
+	ImageKey     string                 `json:"image_key,omitempty"`     // 文件存储键，缓存命中时据此重新生成访问URL
+	ThumbnailKey string                 `json:"thumbnail_key,omitempty"` // 缩略图存储键
+	Extracted    *PageContent           `json:"extracted,omitempty"`     // 提取的页面内容
+	FromCache    bool                   `json:"from_cache,omitempty"`    // 是否来自缓存
+	Error        string                 `json:"error,omitempty"`         // 错误码
+	Message      string                 `json:"message,omitempty"`       // 错误描述
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`      // 元数据
 }
 
 // ScreenshotConfig 截图配置
 type ScreenshotConfig struct {
 	Type        ScreenshotType
+	Domain      string
 	URL         string
 	Selector    string
 	WaitTime    time.Duration
 	Timeout     time.Duration
 	CacheKey    string
-	FilePath    string
-	FileURL     string
+	FileKey     string // 文件存储键，为空表示Base64输出
 	Description string
+	Viewport    *ViewportConfig
+	Wait        *WaitConfig
+	Image       *ImageOptions
+	ThumbKey    string
+	Extract     *ExtractOptions
+	HAR         bool
+	HARKey      string
+
+	ConsoleErrorLimit int
+	Actions           []*ScreenshotAction
+	HideCSS           string
+	BlockAds          bool
+	Session           string
+	Region            *RegionConfig
+}
+
+// screenshotTask 单次截图执行过程中的运行时状态
+type screenshotTask struct {
+	config  *ScreenshotConfig
+	tracker *pageTracker
+	wait    waitResult
+	buf     []byte
+	thumb   []byte
+	content *PageContent
+	har     *harRecorder
+	console *consoleRecorder
+	actions []*ActionResult
+	blocked int64
+	guard   *navigationGuard
 }
 
 // ScreenshotService 截图服务
@@ -88,16 +175,25 @@ type ScreenshotService struct {
 	chromeManager *ChromeManager
 	redisClient   *redis.Client
 	config        *ScreenshotServiceConfig
+	history       *ScreenshotHistory
+	adBlocklist   *hostBlocklist
+	proxies       map[string]*ScreenshotProxy
+	networkPolicy *utils.NetworkPolicy
+	storage       Storage
 }
 
 // ScreenshotServiceConfig 服务配置
 type ScreenshotServiceConfig struct {
-	BaseDir          string        // 基础目录
-	CacheExpiration  time.Duration // 默认缓存过期时间
-	DefaultTimeout   time.Duration // 默认超时时间
-	DefaultWaitTime  time.Duration // 默认等待时间
-	MaxFileSize      int64         // 最大文件大小
-	AllowedFormats   []string      // 允许的图片格式
+	BaseDir         string        // 基础目录
+	CacheExpiration time.Duration // 默认缓存过期时间
+	DefaultTimeout  time.Duration // 默认超时时间
+	DefaultWaitTime time.Duration // 默认等待时间
+	MaxFileSize     int64         // 最大文件大小
+	AllowedFormats  []string      // 允许的图片格式
+	AdBlocklistFile string        // 额外的广告拦截列表文件，为空时读取SCREENSHOT_AD_BLOCKLIST
+	Proxies         string        // 命名代理列表（name=scheme://[user:pass@]host:port，逗号分隔），为空时读取SCREENSHOT_PROXIES
+	Storage         Storage       // 文件存储，为空时按SCREENSHOT_STORAGE选择，默认为BaseDir下的本地磁盘
+	SignedURLExpiry time.Duration // 预签名URL有效期，默认24小时
 }
 
 // DefaultScreenshotServiceConfig 默认配置
@@ -120,7 +216,22 @@ func NewScreenshotService(chromeManager *ChromeManager, redisClient *redis.Clien
 		chromeManager: chromeManager,
 		redisClient:   redisClient,
 		config:        config,
+		history:       NewScreenshotHistory(redisClient, config),
+		networkPolicy: utils.GetNetworkPolicy(),
+		storage:       storageFor(config),
+	}
+
+	blocklistFile := config.AdBlocklistFile
+	if blocklistFile == "" {
+		blocklistFile = os.Getenv("SCREENSHOT_AD_BLOCKLIST")
+	}
+	service.adBlocklist = loadAdBlocklist(blocklistFile)
+
+	proxies := config.Proxies
+	if proxies == "" {
+		proxies = os.Getenv("SCREENSHOT_PROXIES")
 	}
+	service.proxies = loadProxies(proxies)
 
 	// 创建必要的目录
 	service.ensureDirectories()
@@ -163,11 +274,18 @@ func (s *ScreenshotService) TakeScreenshot(ctx context.Context, req *ScreenshotR
 		}, nil
 	}
 
-	// 检查缓存
-	if cached := s.getFromCache(config.CacheKey); cached != nil {
-		log.Printf("[SCREENSHOT] 使用缓存: %s", config.Description)
-		cached.FromCache = true
-		return cached, nil
+	// 检查缓存，持久会话的页面取决于会话状态，不使用缓存
+	if !req.SkipCache && config.Session == "" {
+		if cached := s.getFromCache(config.CacheKey); cached != nil {
+			// 文件可能已被清理，存在时按存储键重新生成预签名URL；文件已不存在时重新截图
+			if err := s.verifyCachedFiles(ctx, cached); err != nil {
+				log.Printf("[SCREENSHOT] 缓存文件不可用，重新截图: %s, 错误: %v", config.Description, err)
+			} else if err := s.signURLs(ctx, cached); err == nil {
+				log.Printf("[SCREENSHOT] 使用缓存: %s", config.Description)
+				cached.FromCache = true
+				return cached, nil
+			}
+		}
 	}
 
 	// 检查熔断器
@@ -190,8 +308,19 @@ func (s *ScreenshotService) TakeScreenshot(ctx context.Context, req *ScreenshotR
 		return s.handleError(err, config)
 	}
 
-	// 缓存结果
-	s.cacheResult(config.CacheKey, response, req.CacheExpire)
+	// 页面检查未通过的结果不缓存也不记录历史
+	if !response.Success {
+		log.Printf("[SCREENSHOT] %s未通过: %s, 耗时: %v", config.Description, response.Message, duration)
+		return response, nil
+	}
+
+	// 缓存结果，跳过缓存的请求（如定时任务）不覆盖共享的缓存
+	if config.Session == "" && !req.SkipCache {
+		s.cacheResult(config.CacheKey, response, req.CacheExpire)
+	}
+
+	// 记录截图历史，仅保存了文件的截图可用于对比
+	s.recordHistory(ctx, config, response)
 
 	log.Printf("[SCREENSHOT] %s成功, 耗时: %v", config.Description, duration)
 	return response, nil
@@ -211,6 +340,10 @@ func (s *ScreenshotService) validateRequest(req *ScreenshotRequest) error {
 		return fmt.Errorf("不安全的URL: %s", req.URL)
 	}
 
+	if err := s.checkTargetHost(req); err != nil {
+		return err
+	}
+
 	if req.Type == TypeElement && req.Selector == "" {
 		return fmt.Errorf("元素截图必须提供选择器")
 	}
@@ -219,6 +352,51 @@ func (s *ScreenshotService) validateRequest(req *ScreenshotRequest) error {
 		return fmt.Errorf("超时时间不能超过300秒")
 	}
 
+	if _, err := resolveViewport(req); err != nil {
+		return err
+	}
+
+	if _, err := resolveWaitConfig(req, s.config.DefaultWaitTime, 0); err != nil {
+		return err
+	}
+
+	if _, err := resolveImageOptions(req, s.config.AllowedFormats); err != nil {
+		return err
+	}
+
+	if _, err := parseExtractOptions(req.Extract); err != nil {
+		return err
+	}
+
+	if req.FailOnConsoleErrors < 0 || req.FailOnConsoleErrors > MaxConsoleErrorsLimit {
+		return fmt.Errorf("fail_on_console_errors必须在0-%d之间", MaxConsoleErrorsLimit)
+	}
+
+	if err := validateHideSelectors(req.HideSelectors); err != nil {
+		return err
+	}
+
+	if err := validatePersistSession(req.PersistSession); err != nil {
+		return err
+	}
+
+	if _, err := resolveRegion(req, s.proxies); err != nil {
+		return err
+	}
+
+	if len(req.Actions) > 0 {
+		if req.Type != TypeBasic && req.Type != TypeElement {
+			return fmt.Errorf("交互步骤仅支持basic和element截图")
+		}
+		targetURL := req.URL
+		if targetURL == "" {
+			targetURL = s.buildURL(req.Type, req.Domain)
+		}
+		if err := validateActions(req.Actions, targetURL); err != nil {
+			return err
+		}
+	}
+
 	// 验证选择器安全性
 	if req.Selector != "" {
 		if strings.Contains(req.Selector, "javascript:") ||
@@ -231,13 +409,50 @@ func (s *ScreenshotService) validateRequest(req *ScreenshotRequest) error {
 	return nil
 }
 
+// checkTargetHost 提前解析目标主机，解析到内网地址或解析失败时直接拒绝
+// 页面跳转和子资源请求在浏览器中由navigationGuard逐个检查
+func (s *ScreenshotService) checkTargetHost(req *ScreenshotRequest) error {
+	host := req.Domain
+	switch {
+	case req.URL != "":
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return fmt.Errorf("无效的URL: %s", req.URL)
+		}
+		host = u.Hostname()
+	case req.Type != TypeBasic && req.Type != TypeElement:
+		return nil // ITDog截图访问的是固定的测试站点，域名只作为测试参数
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), guardLookupTimeout)
+	defer cancel()
+	err := s.networkPolicy.CheckHost(ctx, host)
+	if errors.Is(err, utils.ErrAddressBlocked) {
+		return fmt.Errorf("不安全的URL: %v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("无法解析目标主机 %s: %v", host, err)
+	}
+	return nil
+}
+
 // generateConfig 生成截图配置
 func (s *ScreenshotService) generateConfig(req *ScreenshotRequest) (*ScreenshotConfig, error) {
 	config := &ScreenshotConfig{
 		Type:        req.Type,
+		Domain:      req.Domain,
 		Selector:    req.Selector,
 		Description: s.getDescription(req.Type),
 	}
+	if config.Domain == "" {
+		config.Domain = utils.SanitizeDomain(req.URL)
+	}
+
+	viewport, err := resolveViewport(req)
+	if err != nil {
+		return nil, err
+	}
+	config.Viewport = viewport
 
 	// 设置URL
 	if req.URL != "" {
@@ -257,12 +472,47 @@ func (s *ScreenshotService) generateConfig(req *ScreenshotRequest) (*ScreenshotC
 		config.Timeout = time.Duration(req.Timeout) * time.Second
 	}
 
+	wait, err := resolveWaitConfig(req, s.config.DefaultWaitTime, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	config.Wait = wait
+
+	image, err := resolveImageOptions(req, s.config.AllowedFormats)
+	if err != nil {
+		return nil, err
+	}
+	config.Image = image
+
+	extract, err := parseExtractOptions(req.Extract)
+	if err != nil {
+		return nil, err
+	}
+	config.Extract = extract
+	config.HAR = req.HAR
+	config.ConsoleErrorLimit = req.FailOnConsoleErrors
+	config.Actions = req.Actions
+	config.BlockAds = req.BlockAds
+	config.Session = req.PersistSession
+	if config.Region, err = resolveRegion(req, s.proxies); err != nil {
+		return nil, err
+	}
+	// 同意弹窗规则只用于基础截图，元素和ITDog截图的目标区域是确定的
+	config.HideCSS = buildHideCSS(req.HideSelectors, req.Type == TypeBasic && !req.ShowConsent)
+
 	// 设置缓存键
 	config.CacheKey = s.buildCacheKey(req)
 
-	// 设置文件路径（仅文件格式需要）
+	// 设置文件路径（仅文件格式需要），缩略图和HAR与截图共用同一文件名前缀
 	if req.Format == FormatFile {
-		config.FilePath, config.FileURL = s.buildFilePaths(req)
+		base := s.buildFileBase(req, config.CacheKey)
+		config.FileKey = base + "." + image.extension()
+		if image.wantsThumbnail() {
+			config.ThumbKey = base + "_thumb." + image.extension()
+		}
+		if req.HAR {
+			config.HARKey = base + ".har"
+		}
 	}
 
 	return config, nil
@@ -280,17 +530,36 @@ func (s *ScreenshotService) buildURL(screenshotType ScreenshotType, domain strin
 
 // buildCacheKey 构建缓存键
 func (s *ScreenshotService) buildCacheKey(req *ScreenshotRequest) string {
-	data := fmt.Sprintf("%s_%s_%s_%s_%d_%d",
-		req.Type, req.Domain, req.URL, req.Selector, req.WaitTime, req.Timeout)
+	data := fmt.Sprintf("%s_%s_%s_%s_%d_%d_%d_%d_%g_%t_%t_%s_%s_%s_%s_%s_%d_%d",
+		req.Type, req.Domain, req.URL, req.Selector, req.WaitTime, req.Timeout,
+		req.Width, req.Height, req.DeviceScaleFactor, req.FullPage, req.Mobile,
+		strings.ToLower(req.Device), req.UserAgent,
+		strings.ToLower(req.WaitUntil), req.WaitSelector, req.WaitFunction, req.NetworkIdleMs, req.MaxWait)
+	data += fmt.Sprintf("_%s_%d_%d_%d_%s", strings.ToLower(req.ImageFormat), req.Quality, req.ThumbWidth, req.ThumbHeight,
+		strings.ToLower(string(req.Extract)))
+	data += fmt.Sprintf("_%t_%d", req.HAR, req.FailOnConsoleErrors)
+	data += fmt.Sprintf("_%s_%t_%t", strings.Join(req.HideSelectors, "|"), req.BlockAds, req.ShowConsent)
+	data += fmt.Sprintf("_%s_%s_%s", strings.ToLower(req.Proxy), req.Locale, req.Timezone)
+	if geo := req.Geolocation; geo != nil {
+		data += fmt.Sprintf("_%g_%g_%g", geo.Latitude, geo.Longitude, geo.Accuracy)
+	}
+	if len(req.Actions) > 0 {
+		actions, _ := json.Marshal(req.Actions)
+		data += "_" + string(actions)
+	}
 	hash := fmt.Sprintf("%x", md5.Sum([]byte(data)))
 	return fmt.Sprintf("screenshot:%s:%s", req.Type, hash[:16])
 }
 
-// buildFilePaths 构建文件路径
-func (s *ScreenshotService) buildFilePaths(req *ScreenshotRequest) (string, string) {
+// buildFileBase 构建不含扩展名的文件存储键
+// 文件名包含缓存键哈希和随机后缀，同一秒内对同一域名的不同参数或并发截图不会互相覆盖
+func (s *ScreenshotService) buildFileBase(req *ScreenshotRequest, cacheKey string) string {
 	// 安全的文件名生成
 	safeDomain := utils.GenerateSecureFilename(req.Domain)
 	timestamp := time.Now().Unix()
+	hash := cacheKey[strings.LastIndex(cacheKey, ":")+1:]
+	random := make([]byte, 4)
+	rand.Read(random)
 
 	var subDir string
 	switch req.Type {
@@ -300,11 +569,69 @@ func (s *ScreenshotService) buildFilePaths(req *ScreenshotRequest) (string, stri
 		subDir = "screenshots"
 	}
 
-	fileName := fmt.Sprintf("%s_%s_%d.png", req.Type, safeDomain, timestamp)
-	filePath := filepath.Join(s.config.BaseDir, subDir, fileName)
-	fileURL := fmt.Sprintf("/static/%s/%s", subDir, fileName)
+	fileName := fmt.Sprintf("%s_%s_%d_%s_%s", req.Type, safeDomain, timestamp, hash, hex.EncodeToString(random))
+	return subDir + "/" + fileName
+}
+
+// fileStorage 文件存储，未通过NewScreenshotService创建时按配置选择
+func (s *ScreenshotService) fileStorage() Storage {
+	if s.storage == nil {
+		s.storage = storageFor(s.config)
+	}
+	return s.storage
+}
 
-	return filePath, fileURL
+// signedURLExpiry 预签名URL有效期
+func (s *ScreenshotService) signedURLExpiry() time.Duration {
+	if s.config.SignedURLExpiry > 0 {
+		return s.config.SignedURLExpiry
+	}
+	return DefaultSignedURLExpiry
+}
+
+// verifyCachedFiles 检查缓存条目引用的截图文件仍然存在，没有存储键的旧缓存条目不检查
+func (s *ScreenshotService) verifyCachedFiles(ctx context.Context, response *ScreenshotResponse) error {
+	if response.ImageKey == "" {
+		return nil
+	}
+	_, err := s.fileStorage().Stat(ctx, response.ImageKey)
+	return err
+}
+
+// signURLs 按存储键生成图片、缩略图和HAR的访问URL，没有存储键的旧缓存条目保持原URL
+func (s *ScreenshotService) signURLs(ctx context.Context, response *ScreenshotResponse) error {
+	storage := s.fileStorage()
+	sign := func(key string, target *string) error {
+		if key == "" {
+			return nil
+		}
+		signed, err := storage.SignedURL(ctx, key, s.signedURLExpiry())
+		if err != nil {
+			return err
+		}
+		*target = signed
+		return nil
+	}
+
+	if err := sign(response.ImageKey, &response.ImageURL); err != nil {
+		return err
+	}
+	if err := sign(response.ThumbnailKey, &response.ThumbnailURL); err != nil {
+		return err
+	}
+	harKey := ""
+	if har, ok := response.Metadata["har"].(map[string]interface{}); ok {
+		if harKey, _ = har["key"].(string); harKey != "" {
+			harURL := ""
+			if err := sign(harKey, &harURL); err != nil {
+				return err
+			}
+			har["url"] = harURL
+		}
+	}
+	// 生成URL即视为访问，超出存储配额时最久未访问的文件先被清理
+	touchStorage(ctx, s.redisClient, response.ImageKey, response.ThumbnailKey, harKey)
+	return nil
 }
 
 // getDescription 获取描述
@@ -322,8 +649,21 @@ func (s *ScreenshotService) getDescription(screenshotType ScreenshotType) string
 
 // executeScreenshot 执行截图
 func (s *ScreenshotService) executeScreenshot(ctx context.Context, config *ScreenshotConfig) (*ScreenshotResponse, error) {
-	// 获取Chrome上下文
-	chromeCtx, cancel, err := s.chromeManager.GetContext(config.Timeout)
+	task := &screenshotTask{
+		config: config,
+		guard:  newNavigationGuard(s.networkPolicy),
+	}
+	interceptor := &requestInterceptor{guard: task.guard, blocked: &task.blocked}
+	if config.BlockAds {
+		interceptor.blocklist = s.adBlocklist
+	}
+
+	// 获取Chrome上下文，请求拦截和出站代理由ChromeManager在导航之前设置
+	chromeCtx, cancel, err := s.chromeManager.GetSessionContext(config.Timeout, BrowserContextOptions{
+		Session:     config.Session,
+		Proxy:       config.Region.Proxy,
+		Interceptor: interceptor,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取Chrome上下文失败: %v", err)
 	}
@@ -333,77 +673,134 @@ func (s *ScreenshotService) executeScreenshot(ctx context.Context, config *Scree
 	taskCtx, taskCancel := context.WithTimeout(chromeCtx, config.Timeout)
 	defer taskCancel()
 
+	task.tracker = newPageTracker(taskCtx)
+	task.console = newConsoleRecorder(taskCtx)
+	if config.HAR {
+		task.har = newHARRecorder(taskCtx, config.URL, s.chromeManager.serverIPLookup(config.Region.Proxy))
+	}
+
+	// 视口和地区模拟、Cookie和样式注入都必须在导航之前设置
+	setup := chromedp.Tasks{config.Viewport.emulate(), config.Region.emulate(config.Viewport.UserAgent)}
+	if len(config.Actions) > 0 {
+		setup = append(setup, setActionCookies(config.Actions, config.URL, &task.actions))
+	}
+	if config.HideCSS != "" {
+		setup = append(setup, injectStyles(config.HideCSS))
+	}
+	if err := chromedp.Run(taskCtx, setup); err != nil {
+		return nil, err
+	}
+
 	// 执行截图操作
-	var buf []byte
 	switch config.Type {
 	case TypeBasic:
-		err = s.takeBasicScreenshot(taskCtx, config, &buf)
+		err = s.takeBasicScreenshot(taskCtx, task)
 	case TypeElement:
-		err = s.takeElementScreenshot(taskCtx, config, &buf)
+		err = s.takeElementScreenshot(taskCtx, task)
 	case TypeItdogMap, TypeItdogTable, TypeItdogIP, TypeItdogResolve:
-		err = s.takeItdogScreenshot(taskCtx, config, &buf)
+		err = s.takeItdogScreenshot(taskCtx, task)
 	default:
 		return nil, fmt.Errorf("不支持的截图类型: %s", config.Type)
 	}
 
 	if err != nil {
+		// 页面或其跳转目标被出站策略拒绝时导航失败，返回明确的原因
+		if document := task.guard.blockedDocument(); document != "" {
+			return nil, fmt.Errorf("%w: %s", utils.ErrAddressBlocked, document)
+		}
 		return nil, err
 	}
 
+	// 在同一页面中提取内容，失败不影响截图结果
+	var extractErr error
+	if config.Extract != nil {
+		if extractErr = chromedp.Run(taskCtx, extractContent(config.Extract, &task.content)); extractErr != nil {
+			log.Printf("[SCREENSHOT] %s: %v", config.URL, extractErr)
+		}
+	}
+
 	// 处理结果
-	return s.processResult(buf, config)
+	response, err := s.processResult(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	if extractErr != nil {
+		response.Metadata["extract_error"] = extractErr.Error()
+	}
+
+	// 控制台错误超过阈值时保留截图和记录，但请求视为失败
+	console := task.console.result()
+	response.Metadata["console"] = console
+	if config.ConsoleErrorLimit > 0 && console.Errors >= config.ConsoleErrorLimit {
+		response.Success = false
+		response.Error = "CONSOLE_ERRORS"
+		response.Message = fmt.Sprintf("页面控制台错误 %d 个，达到阈值 %d", console.Errors, config.ConsoleErrorLimit)
+	}
+	return response, nil
 }
 
 // takeBasicScreenshot 基础截图
-func (s *ScreenshotService) takeBasicScreenshot(ctx context.Context, config *ScreenshotConfig, buf *[]byte) error {
+func (s *ScreenshotService) takeBasicScreenshot(ctx context.Context, task *screenshotTask) error {
+	config := task.config
+
 	return chromedp.Run(ctx,
-		chromedp.Navigate(config.URL),
-		chromedp.Sleep(config.WaitTime),
-		chromedp.CaptureScreenshot(buf),
+		navigateAndWait(config.URL, config.Wait, task.tracker, &task.wait),
+		runActions(config.Actions, config.URL, &task.actions),
+		captureViewport(config.Image, config.Viewport.FullPage, &task.buf, &task.thumb),
 	)
 }
 
 // takeElementScreenshot 元素截图
-func (s *ScreenshotService) takeElementScreenshot(ctx context.Context, config *ScreenshotConfig, buf *[]byte) error {
+func (s *ScreenshotService) takeElementScreenshot(ctx context.Context, task *screenshotTask) error {
+	config := task.config
 	// 检查选择器类型
-	var selectorType chromedp.QueryOption
-	if strings.HasPrefix(config.Selector, "//") {
-		selectorType = chromedp.BySearch // XPath
-	} else {
-		selectorType = chromedp.ByQuery // CSS
-	}
+	selectorType := selectorQueryOption(config.Selector)
 
 	return chromedp.Run(ctx,
-		chromedp.Navigate(config.URL),
-		chromedp.Sleep(config.WaitTime),
+		navigateAndWait(config.URL, config.Wait, task.tracker, &task.wait),
+		runActions(config.Actions, config.URL, &task.actions),
 		chromedp.WaitVisible(config.Selector, selectorType),
-		chromedp.Screenshot(config.Selector, buf, chromedp.NodeVisible, selectorType),
+		chromedp.Screenshot(config.Selector, &task.buf, chromedp.NodeVisible, selectorType),
 	)
 }
 
+// itdogSettleTime ITDog测试完成后等待地图和表格渲染的网络空闲时长
+const itdogSettleTime = time.Second
+
+// itdogStartActions 开始ITDog测试的交互步骤
+var itdogStartActions = []*ScreenshotAction{
+	{Action: ActionClick, Selector: ".btn.btn-primary.ml-3.mb-3", TimeoutMs: 30000},
+}
+
 // takeItdogScreenshot ITDog截图
-func (s *ScreenshotService) takeItdogScreenshot(ctx context.Context, config *ScreenshotConfig, buf *[]byte) error {
+func (s *ScreenshotService) takeItdogScreenshot(ctx context.Context, task *screenshotTask) error {
+	config := task.config
 	// 设置选择器
 	selector := s.getItdogSelector(config.Type)
 
+	// ITDog页面固定等待DOM就绪，测试按钮可见即可开始
+	pageWait := &WaitConfig{Strategy: WaitDOMContentLoaded, MaxWait: config.Wait.MaxWait}
+
 	return chromedp.Run(ctx,
 		// 导航到页面
-		chromedp.Navigate(config.URL),
-		chromedp.Sleep(2*time.Second),
+		navigateAndWait(config.URL, pageWait, task.tracker, &task.wait),
 
-		// 等待并点击测试按钮
-		chromedp.WaitVisible(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery),
-		chromedp.Click(".btn.btn-primary.ml-3.mb-3", chromedp.ByQuery),
-		chromedp.Sleep(3*time.Second),
+		// 等待并点击测试按钮，内部步骤的结果不写入响应的actions元数据
+		runActions(itdogStartActions, config.URL, new([]*ActionResult)),
 
 		// 等待测试完成
 		s.waitForItdogCompletion(),
 
-		// 等待页面更新
-		chromedp.Sleep(5*time.Second),
+		// 等待页面更新：网络空闲后再截图，最多等待原先的5秒
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if err := task.tracker.waitNetworkIdle(ctx, itdogSettleTime, 5*time.Second); err != nil && ctx.Err() != nil {
+				return err
+			}
+			return nil
+		}),
 
 		// 截图
-		s.screenshotItdogElement(selector, buf),
+		s.screenshotItdogElement(selector, &task.buf),
 	)
 }
 
@@ -458,49 +855,188 @@ func (s *ScreenshotService) waitForItdogCompletion() chromedp.Action {
 // screenshotItdogElement 截图ITDog元素
 func (s *ScreenshotService) screenshotItdogElement(selector string, buf *[]byte) chromedp.Action {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		if strings.HasPrefix(selector, "//") {
-			return chromedp.Screenshot(selector, buf, chromedp.NodeVisible, chromedp.BySearch).Do(ctx)
-		}
-		return chromedp.Screenshot(selector, buf, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
+		return chromedp.Screenshot(selector, buf, chromedp.NodeVisible, selectorQueryOption(selector)).Do(ctx)
 	})
 }
 
 // processResult 处理截图结果
-func (s *ScreenshotService) processResult(buf []byte, config *ScreenshotConfig) (*ScreenshotResponse, error) {
-	if len(buf) == 0 {
+func (s *ScreenshotService) processResult(ctx context.Context, task *screenshotTask) (*ScreenshotResponse, error) {
+	config := task.config
+	if len(task.buf) == 0 {
 		return nil, fmt.Errorf("截图数据为空")
 	}
 
+	// 基础截图由Chrome直接输出目标格式，其余类型输出PNG需要转码
+	buf, thumb := task.buf, task.thumb
+	var err error
+	if config.Type != TypeBasic {
+		if buf, err = encodeImage(buf, config.Image); err != nil {
+			return nil, err
+		}
+	}
+	if config.Image.wantsThumbnail() && len(thumb) == 0 {
+		if thumb, err = makeThumbnail(task.buf, config.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.config.MaxFileSize > 0 && (int64(len(buf)) > s.config.MaxFileSize || int64(len(thumb)) > s.config.MaxFileSize) {
+		return nil, fmt.Errorf("%w: %d 字节，上限 %d 字节", errFileTooLarge, len(buf), s.config.MaxFileSize)
+	}
+
+	imageMeta := config.Image.metadata()
+	if len(thumb) > 0 {
+		imageMeta["thumbnail_size"] = len(thumb)
+	}
+
 	response := &ScreenshotResponse{
-		Success: true,
+		Success:   true,
+		Extracted: task.content,
 		Metadata: map[string]interface{}{
 			"size":        len(buf),
 			"type":        config.Type,
 			"description": config.Description,
+			"viewport":    config.Viewport.metadata(),
+			"wait":        task.wait.metadata(config.Wait),
+			"image":       imageMeta,
 		},
 	}
 
 	// 根据输出格式处理
-	if config.FilePath != "" {
+	if config.FileKey != "" {
 		// 文件格式
-		if err := os.WriteFile(config.FilePath, buf, 0644); err != nil {
+		storage := s.fileStorage()
+		if err := storage.Put(ctx, config.FileKey, buf, config.Image.mimeType()); err != nil {
 			return nil, fmt.Errorf("保存文件失败: %v", err)
 		}
-		response.ImageURL = config.FileURL
+		response.ImageKey = config.FileKey
+
+		if len(thumb) > 0 {
+			if err := storage.Put(ctx, config.ThumbKey, thumb, config.Image.mimeType()); err != nil {
+				return nil, fmt.Errorf("保存缩略图失败: %v", err)
+			}
+			response.ThumbnailKey = config.ThumbKey
+		}
 	} else {
 		// Base64格式
 		base64Data := base64.StdEncoding.EncodeToString(buf)
-		response.ImageURL = fmt.Sprintf("data:image/png;base64,%s", base64Data)
+		response.ImageURL = fmt.Sprintf("data:%s;base64,%s", config.Image.mimeType(), base64Data)
 		response.ImageBase64 = base64Data
+
+		if len(thumb) > 0 {
+			response.ThumbnailURL = fmt.Sprintf("data:%s;base64,%s", config.Image.mimeType(), base64.StdEncoding.EncodeToString(thumb))
+		}
+	}
+
+	if len(task.actions) > 0 {
+		response.Metadata["actions"] = task.actions
+	}
+	if config.BlockAds {
+		response.Metadata["blocked_requests"] = atomic.LoadInt64(&task.blocked)
+	}
+	if !config.Region.isDefault() {
+		response.Metadata["region"] = config.Region.metadata()
+	}
+	if denied := task.guard.metadata(); denied != nil {
+		response.Metadata["denied_requests"] = denied
+	}
+
+	// 网络请求记录：文件格式保存在图片旁边，Base64格式直接内嵌
+	if task.har != nil {
+		har := task.har.build()
+		harMeta := task.har.summary(har)
+		if config.HARKey != "" {
+			data, err := json.Marshal(har)
+			if err != nil {
+				return nil, fmt.Errorf("编码HAR失败: %v", err)
+			}
+			if err := s.fileStorage().Put(ctx, config.HARKey, data, "application/json"); err != nil {
+				return nil, fmt.Errorf("保存HAR失败: %v", err)
+			}
+			harMeta["key"] = config.HARKey
+		} else {
+			harMeta["document"] = har
+		}
+		response.Metadata["har"] = harMeta
 	}
 
+	if err := s.signURLs(ctx, response); err != nil {
+		return nil, fmt.Errorf("生成文件访问URL失败: %v", err)
+	}
 	return response, nil
 }
 
+// recordHistory 将本次截图写入历史记录
+func (s *ScreenshotService) recordHistory(ctx context.Context, config *ScreenshotConfig, response *ScreenshotResponse) {
+	record := newHistoryRecord(config, response, time.Now())
+	if record == nil {
+		return
+	}
+	if err := s.history.Record(ctx, record); err != nil {
+		log.Printf("[SCREENSHOT] 记录截图历史失败: %v", err)
+	}
+}
+
+// newHistoryRecord 由截图结果生成历史记录；Base64输出没有文件无法对比，登录会话的截图包含账号内容，
+// 历史按域名共享，这两类截图不记录，返回nil
+func newHistoryRecord(config *ScreenshotConfig, response *ScreenshotResponse, now time.Time) *ScreenshotRecord {
+	if config.FileKey == "" || config.Session != "" {
+		return nil
+	}
+
+	record := &ScreenshotRecord{
+		ID:           strconv.FormatInt(now.UnixNano(), 36),
+		Domain:       config.Domain,
+		Type:         config.Type,
+		ImageURL:     response.ImageURL,
+		ThumbnailURL: response.ThumbnailURL,
+		ImageKey:     response.ImageKey,
+		ThumbnailKey: response.ThumbnailKey,
+		Format:       config.Image.Format,
+		Viewport:     config.Viewport.signature(),
+		Device:       config.Viewport.Device,
+		Setup:        captureSetupSignature(config),
+		CapturedAt:   now.UTC().Format(time.RFC3339Nano),
+	}
+	if size, ok := response.Metadata["size"].(int); ok {
+		record.Size = size
+	}
+	return record
+}
+
 // handleError 处理错误
 func (s *ScreenshotService) handleError(err error, config *ScreenshotConfig) (*ScreenshotResponse, error) {
 	errStr := err.Error()
 
+	// 文件大小超限
+	if errors.Is(err, errFileTooLarge) {
+		return &ScreenshotResponse{
+			Success: false,
+			Error:   "FILE_TOO_LARGE",
+			Message: errStr,
+		}, nil
+	}
+
+	// 页面或其跳转目标被出站策略拒绝
+	if errors.Is(err, utils.ErrAddressBlocked) {
+		return &ScreenshotResponse{
+			Success: false,
+			Error:   "URL_BLOCKED",
+			Message: errStr,
+		}, nil
+	}
+
+	// 交互步骤失败，返回逐步执行结果
+	var stepErr *actionError
+	if errors.As(err, &stepErr) {
+		return &ScreenshotResponse{
+			Success:  false,
+			Error:    "ACTION_FAILED",
+			Message:  errStr,
+			Metadata: map[string]interface{}{"actions": stepErr.results},
+		}, nil
+	}
+
 	// 网络连接错误
 	if strings.Contains(errStr, "net::ERR_NAME_NOT_RESOLVED") ||
 		strings.Contains(errStr, "net::ERR_CONNECTION_REFUSED") ||
@@ -607,4 +1143,4 @@ func (s *ScreenshotService) cacheResult(key string, response *ScreenshotResponse
 	}
 
 	s.redisClient.Set(context.Background(), key, data, expiration)
-}
\ No newline at end of file
+}
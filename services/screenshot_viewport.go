@@ -0,0 +1,188 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图视口与设备模拟 - 视口尺寸、像素比、移动端模式、设备预设和UA覆盖
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+)
+
+// 视口参数限制，防止超大页面耗尽Chrome内存
+const (
+	DefaultViewportWidth  = 1920
+	DefaultViewportHeight = 1080
+	MinViewportSize       = 100
+	MaxViewportWidth      = 3840
+	MaxViewportHeight     = 4320
+	MaxDeviceScaleFactor  = 4.0
+	MaxUserAgentLength    = 512
+)
+
+// devicePresets 支持的设备预设，名称不区分大小写
+var devicePresets = map[string]device.Info{
+	"iphone":    device.IPhone15.Device(),
+	"iphone-se": device.IPhoneSE.Device(),
+	"pixel":     device.Pixel5.Device(),
+	"galaxy":    device.GalaxyS9.Device(),
+	"ipad":      device.IPad.Device(),
+	"ipad-mini": device.IPadMini.Device(),
+	"ipad-pro":  device.IPadPro.Device(),
+}
+
+// DevicePresetNames 返回支持的设备预设名称
+func DevicePresetNames() []string {
+	names := make([]string, 0, len(devicePresets))
+	for name := range devicePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ViewportConfig 截图视口配置
+type ViewportConfig struct {
+	Width             int64
+	Height            int64
+	DeviceScaleFactor float64
+	Mobile            bool
+	Touch             bool
+	UserAgent         string
+	FullPage          bool
+	Device            string
+}
+
+// resolveViewport 合并设备预设与显式参数，显式参数优先
+func resolveViewport(req *ScreenshotRequest) (*ViewportConfig, error) {
+	viewport := &ViewportConfig{
+		Width:             DefaultViewportWidth,
+		Height:            DefaultViewportHeight,
+		DeviceScaleFactor: 1,
+		FullPage:          req.FullPage,
+	}
+
+	if req.Device != "" {
+		name := strings.ToLower(req.Device)
+		preset, ok := devicePresets[name]
+		if !ok {
+			return nil, fmt.Errorf("不支持的设备预设: %s（可选: %s）", req.Device, strings.Join(DevicePresetNames(), ", "))
+		}
+		viewport.Device = name
+		viewport.Width = preset.Width
+		viewport.Height = preset.Height
+		viewport.DeviceScaleFactor = preset.Scale
+		viewport.Mobile = preset.Mobile
+		viewport.Touch = preset.Touch
+		viewport.UserAgent = preset.UserAgent
+	}
+
+	if req.Width > 0 {
+		viewport.Width = int64(req.Width)
+	}
+	if req.Height > 0 {
+		viewport.Height = int64(req.Height)
+	}
+	if req.DeviceScaleFactor > 0 {
+		viewport.DeviceScaleFactor = req.DeviceScaleFactor
+	}
+	if req.Mobile {
+		viewport.Mobile = true
+		viewport.Touch = true
+	}
+	if req.UserAgent != "" {
+		viewport.UserAgent = req.UserAgent
+	}
+
+	if err := viewport.validate(); err != nil {
+		return nil, err
+	}
+	return viewport, nil
+}
+
+// validate 校验视口参数范围
+func (v *ViewportConfig) validate() error {
+	if v.Width < MinViewportSize || v.Width > MaxViewportWidth {
+		return fmt.Errorf("宽度必须在 %d-%d 之间", MinViewportSize, MaxViewportWidth)
+	}
+	if v.Height < MinViewportSize || v.Height > MaxViewportHeight {
+		return fmt.Errorf("高度必须在 %d-%d 之间", MinViewportSize, MaxViewportHeight)
+	}
+	if v.DeviceScaleFactor <= 0 || v.DeviceScaleFactor > MaxDeviceScaleFactor {
+		return fmt.Errorf("设备像素比必须在 0-%.0f 之间", MaxDeviceScaleFactor)
+	}
+	if len(v.UserAgent) > MaxUserAgentLength || strings.ContainsAny(v.UserAgent, "\r\n") {
+		return fmt.Errorf("无效的User-Agent")
+	}
+	return nil
+}
+
+// isDefault 是否与Chrome启动时的窗口一致，一致时无需设置模拟参数
+func (v *ViewportConfig) isDefault() bool {
+	return v.Width == DefaultViewportWidth && v.Height == DefaultViewportHeight &&
+		v.DeviceScaleFactor == 1 && !v.Mobile && !v.Touch && v.UserAgent == ""
+}
+
+// emulate 返回设置视口和UA的动作，需要在导航之前执行
+func (v *ViewportConfig) emulate() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if v.isDefault() {
+			return nil
+		}
+
+		opts := []chromedp.EmulateViewportOption{chromedp.EmulateScale(v.DeviceScaleFactor)}
+		if v.Mobile {
+			opts = append(opts, chromedp.EmulateMobile)
+		}
+		if v.Touch {
+			opts = append(opts, chromedp.EmulateTouch)
+		}
+		if err := chromedp.EmulateViewport(v.Width, v.Height, opts...).Do(ctx); err != nil {
+			return fmt.Errorf("设置视口失败: %v", err)
+		}
+
+		if v.UserAgent != "" {
+			if err := emulation.SetUserAgentOverride(v.UserAgent).Do(ctx); err != nil {
+				return fmt.Errorf("设置User-Agent失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// signature 影响截图尺寸和布局的视口参数，视口不同的截图不能对比
+func (v *ViewportConfig) signature() string {
+	signature := fmt.Sprintf("%dx%d@%g", v.Width, v.Height, v.DeviceScaleFactor)
+	if v.Mobile {
+		signature += ",mobile"
+	}
+	if v.FullPage {
+		signature += ",full_page"
+	}
+	return signature
+}
+
+// metadata 视口参数在响应元数据中的表示
+func (v *ViewportConfig) metadata() map[string]interface{} {
+	meta := map[string]interface{}{
+		"width":               v.Width,
+		"height":              v.Height,
+		"device_scale_factor": v.DeviceScaleFactor,
+		"mobile":              v.Mobile,
+		"full_page":           v.FullPage,
+	}
+	if v.Device != "" {
+		meta["device"] = v.Device
+	}
+	if v.UserAgent != "" {
+		meta["user_agent"] = v.UserAgent
+	}
+	return meta
+}
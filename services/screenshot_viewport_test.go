@@ -0,0 +1,60 @@
+package services
+
+import "testing"
+
+// TestResolveViewport 测试设备预设与显式参数的合并和校验
+func TestResolveViewport(t *testing.T) {
+	viewport, err := resolveViewport(&ScreenshotRequest{Device: "iPhone", Width: 400})
+	if err != nil {
+		t.Fatalf("resolveViewport 返回错误: %v", err)
+	}
+	if viewport.Width != 400 || !viewport.Mobile || viewport.DeviceScaleFactor <= 1 || viewport.UserAgent == "" {
+		t.Errorf("设备预设合并结果不正确: %+v", viewport)
+	}
+
+	viewport, err = resolveViewport(&ScreenshotRequest{})
+	if err != nil || !viewport.isDefault() {
+		t.Errorf("未指定参数时应使用默认视口: %+v, %v", viewport, err)
+	}
+
+	invalid := []*ScreenshotRequest{
+		{Device: "nokia"},
+		{Width: 20000},
+		{DeviceScaleFactor: 10},
+		{UserAgent: "bad\r\nHeader: x"},
+	}
+	for _, req := range invalid {
+		if _, err := resolveViewport(req); err == nil {
+			t.Errorf("期望参数 %+v 校验失败", req)
+		}
+	}
+}
+
+// TestBuildCacheKeyIncludesViewport 测试不同视口参数生成不同的缓存键
+func TestBuildCacheKeyIncludesViewport(t *testing.T) {
+	service := &ScreenshotService{config: DefaultScreenshotServiceConfig}
+	base := ScreenshotRequest{Type: TypeBasic, Domain: "example.com"}
+
+	variants := []ScreenshotRequest{base}
+	for _, mutate := range []func(*ScreenshotRequest){
+		func(r *ScreenshotRequest) { r.Width = 800 },
+		func(r *ScreenshotRequest) { r.FullPage = true },
+		func(r *ScreenshotRequest) { r.Mobile = true },
+		func(r *ScreenshotRequest) { r.Device = "ipad" },
+		func(r *ScreenshotRequest) { r.UserAgent = "custom" },
+		func(r *ScreenshotRequest) { r.DeviceScaleFactor = 2 },
+	} {
+		req := base
+		mutate(&req)
+		variants = append(variants, req)
+	}
+
+	seen := make(map[string]bool)
+	for i := range variants {
+		key := service.buildCacheKey(&variants[i])
+		if seen[key] {
+			t.Errorf("缓存键冲突: %+v", variants[i])
+		}
+		seen[key] = true
+	}
+}
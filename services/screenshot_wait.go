@@ -0,0 +1,309 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图等待策略 - 基于CDP页面生命周期和网络事件，替代固定时长的等待
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// WaitStrategy 页面就绪等待策略
+type WaitStrategy string
+
+const (
+	WaitFixed            WaitStrategy = "fixed"            // 固定等待wait_time秒（兼容旧行为）
+	WaitLoad             WaitStrategy = "load"             // 等待load事件
+	WaitDOMContentLoaded WaitStrategy = "domcontentloaded" // 等待DOMContentLoaded事件
+	WaitNetworkIdle      WaitStrategy = "networkidle"      // 等待网络空闲
+	WaitSelector         WaitStrategy = "selector"         // 等待元素可见
+	WaitFunction         WaitStrategy = "function"         // 等待JS表达式返回真值
+)
+
+// 等待策略参数限制
+const (
+	DefaultNetworkIdleTime = 500 * time.Millisecond
+	MaxNetworkIdleTime     = 10 * time.Second
+	DefaultMaxWait         = 15 * time.Second
+	MaxWaitCap             = 60 * time.Second
+	MaxWaitFunctionLength  = 2048
+	waitPollInterval       = 100 * time.Millisecond
+)
+
+// WaitConfig 等待策略配置
+type WaitConfig struct {
+	Strategy  WaitStrategy
+	Selector  string
+	Function  string
+	IdleTime  time.Duration
+	MaxWait   time.Duration
+	FixedWait time.Duration
+}
+
+// resolveWaitConfig 根据请求生成等待配置
+// 未指定策略时保持固定等待（wait_time或默认等待时间），与旧版行为一致
+func resolveWaitConfig(req *ScreenshotRequest, defaultWait, timeout time.Duration) (*WaitConfig, error) {
+	wait := &WaitConfig{
+		Strategy: WaitStrategy(strings.ToLower(req.WaitUntil)),
+		Selector: req.WaitSelector,
+		Function: req.WaitFunction,
+		IdleTime: DefaultNetworkIdleTime,
+		MaxWait:  DefaultMaxWait,
+	}
+
+	if wait.Strategy == "" {
+		wait.Strategy = WaitFixed
+	}
+
+	switch wait.Strategy {
+	case WaitFixed:
+		wait.FixedWait = defaultWait
+		if req.WaitTime > 0 {
+			wait.FixedWait = time.Duration(req.WaitTime) * time.Second
+		}
+	case WaitLoad, WaitDOMContentLoaded, WaitNetworkIdle:
+	case WaitSelector:
+		if wait.Selector == "" {
+			return nil, fmt.Errorf("selector等待策略必须提供wait_selector")
+		}
+		if strings.Contains(wait.Selector, "javascript:") || strings.Contains(wait.Selector, "eval(") {
+			return nil, fmt.Errorf("wait_selector包含不安全的内容")
+		}
+	case WaitFunction:
+		if wait.Function == "" {
+			return nil, fmt.Errorf("function等待策略必须提供wait_function")
+		}
+		if len(wait.Function) > MaxWaitFunctionLength {
+			return nil, fmt.Errorf("wait_function长度不能超过%d", MaxWaitFunctionLength)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的等待策略: %s", req.WaitUntil)
+	}
+
+	if req.NetworkIdleMs > 0 {
+		wait.IdleTime = time.Duration(req.NetworkIdleMs) * time.Millisecond
+		if wait.IdleTime > MaxNetworkIdleTime {
+			return nil, fmt.Errorf("network_idle_ms不能超过%d", MaxNetworkIdleTime.Milliseconds())
+		}
+	}
+
+	if req.MaxWait > 0 {
+		wait.MaxWait = time.Duration(req.MaxWait) * time.Second
+	}
+	if wait.MaxWait > MaxWaitCap {
+		wait.MaxWait = MaxWaitCap
+	}
+	// 为截图本身预留时间
+	if timeout > 0 && wait.MaxWait > timeout/2 {
+		wait.MaxWait = timeout / 2
+	}
+	if wait.Strategy == WaitFixed && wait.FixedWait > wait.MaxWait {
+		wait.FixedWait = wait.MaxWait
+	}
+
+	return wait, nil
+}
+
+// pageTracker 跟踪单个标签页的生命周期事件和进行中的网络请求
+type pageTracker struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]struct{}
+	lastActivity time.Time
+	domReady     chan struct{}
+	loaded       chan struct{}
+	domOnce      sync.Once
+	loadOnce     sync.Once
+}
+
+// newPageTracker 创建跟踪器并注册事件监听，需要在导航之前调用
+func newPageTracker(ctx context.Context) *pageTracker {
+	t := &pageTracker{
+		inflight:     make(map[network.RequestID]struct{}),
+		lastActivity: time.Now(),
+		domReady:     make(chan struct{}),
+		loaded:       make(chan struct{}),
+	}
+	chromedp.ListenTarget(ctx, t.handleEvent)
+	return t
+}
+
+func (t *pageTracker) handleEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case *page.EventDomContentEventFired:
+		t.domOnce.Do(func() { close(t.domReady) })
+	case *page.EventLoadEventFired:
+		t.domOnce.Do(func() { close(t.domReady) })
+		t.loadOnce.Do(func() { close(t.loaded) })
+	case *network.EventRequestWillBeSent:
+		t.mu.Lock()
+		t.inflight[e.RequestID] = struct{}{}
+		t.lastActivity = time.Now()
+		t.mu.Unlock()
+	case *network.EventLoadingFinished:
+		t.finishRequest(e.RequestID)
+	case *network.EventLoadingFailed:
+		t.finishRequest(e.RequestID)
+	}
+}
+
+func (t *pageTracker) finishRequest(id network.RequestID) {
+	t.mu.Lock()
+	delete(t.inflight, id)
+	t.lastActivity = time.Now()
+	t.mu.Unlock()
+}
+
+// idleFor 没有进行中的请求且持续时间超过d
+func (t *pageTracker) idleFor(d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inflight) == 0 && time.Since(t.lastActivity) >= d
+}
+
+// waitNetworkIdle 等待网络空闲，超过maxWait返回context.DeadlineExceeded
+func (t *pageTracker) waitNetworkIdle(ctx context.Context, idle, maxWait time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if t.idleFor(idle) {
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			return waitCtx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitResult 实际等待情况，写入响应元数据
+type waitResult struct {
+	Strategy WaitStrategy
+	Waited   time.Duration
+	TimedOut bool
+}
+
+func (r *waitResult) metadata(config *WaitConfig) map[string]interface{} {
+	meta := map[string]interface{}{
+		"strategy":    r.Strategy,
+		"waited_ms":   r.Waited.Milliseconds(),
+		"timed_out":   r.TimedOut,
+		"max_wait_ms": config.MaxWait.Milliseconds(),
+	}
+	if config.Strategy == WaitNetworkIdle {
+		meta["network_idle_ms"] = config.IdleTime.Milliseconds()
+	}
+	return meta
+}
+
+// navigateAndWait 导航到URL并按策略等待，等待超时不视为失败，继续截图并在元数据中标记
+func navigateAndWait(url string, config *WaitConfig, tracker *pageTracker, result *waitResult) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// 不使用chromedp.Navigate，它会一直等待load事件，无法实现更早的就绪判断
+		_, _, errorText, err := page.Navigate(url).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return fmt.Errorf("页面导航失败: %s", errorText)
+		}
+
+		startTime := time.Now()
+		result.Strategy = config.Strategy
+		err = waitForStrategy(ctx, config, tracker)
+		result.Waited = time.Since(startTime)
+
+		if err == context.DeadlineExceeded && ctx.Err() == nil {
+			result.TimedOut = true
+			return nil
+		}
+		return err
+	})
+}
+
+// waitForStrategy 执行具体的等待策略
+func waitForStrategy(ctx context.Context, config *WaitConfig, tracker *pageTracker) error {
+	waitCtx, cancel := context.WithTimeout(ctx, config.MaxWait)
+	defer cancel()
+
+	var err error
+	switch config.Strategy {
+	case WaitFixed:
+		select {
+		case <-time.After(config.FixedWait):
+		case <-waitCtx.Done():
+			err = waitCtx.Err()
+		}
+	case WaitDOMContentLoaded:
+		err = waitForChannel(waitCtx, tracker.domReady)
+	case WaitLoad:
+		err = waitForChannel(waitCtx, tracker.loaded)
+	case WaitNetworkIdle:
+		// 先等待DOM就绪，避免导航刚开始、请求尚未发出时被误判为空闲
+		if err = waitForChannel(waitCtx, tracker.domReady); err == nil {
+			err = tracker.waitNetworkIdle(waitCtx, config.IdleTime, config.MaxWait)
+		}
+	case WaitSelector:
+		err = chromedp.WaitVisible(config.Selector, selectorQueryOption(config.Selector)).Do(waitCtx)
+	case WaitFunction:
+		err = waitForFunction(waitCtx, config.Function)
+	}
+
+	// chromedp在上下文超时时可能返回包装后的错误，统一为DeadlineExceeded
+	if err != nil && waitCtx.Err() == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func waitForChannel(ctx context.Context, ch <-chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitForFunction 轮询JS表达式直到返回真值，表达式执行异常视为未就绪
+func waitForFunction(ctx context.Context, expression string) error {
+	script := fmt.Sprintf(`(() => { try { return !!(%s); } catch (e) { return false; } })()`, expression)
+	for {
+		var ready bool
+		if err := chromedp.Evaluate(script, &ready).Do(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("执行wait_function失败: %v", err)
+		}
+		if ready {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// selectorQueryOption 以 // 开头的选择器按XPath处理，其余按CSS处理
+func selectorQueryOption(selector string) chromedp.QueryOption {
+	if strings.HasPrefix(selector, "//") {
+		return chromedp.BySearch
+	}
+	return chromedp.ByQuery
+}
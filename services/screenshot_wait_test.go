@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestResolveWaitConfig 测试等待策略的默认值、校验和上限
+func TestResolveWaitConfig(t *testing.T) {
+	wait, err := resolveWaitConfig(&ScreenshotRequest{}, 3*time.Second, 60*time.Second)
+	if err != nil || wait.Strategy != WaitFixed || wait.FixedWait != 3*time.Second {
+		t.Errorf("未指定参数时应保持默认的固定等待: %+v, %v", wait, err)
+	}
+
+	wait, err = resolveWaitConfig(&ScreenshotRequest{WaitTime: 5}, 3*time.Second, 60*time.Second)
+	if err != nil || wait.Strategy != WaitFixed || wait.FixedWait != 5*time.Second {
+		t.Errorf("指定wait_time时应保持固定等待: %+v, %v", wait, err)
+	}
+
+	wait, err = resolveWaitConfig(&ScreenshotRequest{WaitUntil: "Load", MaxWait: 120}, 0, 30*time.Second)
+	if err != nil || wait.Strategy != WaitLoad || wait.MaxWait != 15*time.Second {
+		t.Errorf("最长等待应限制为超时时间的一半: %+v, %v", wait, err)
+	}
+
+	invalid := []*ScreenshotRequest{
+		{WaitUntil: "forever"},
+		{WaitUntil: "selector"},
+		{WaitUntil: "function"},
+		{WaitUntil: "networkidle", NetworkIdleMs: 60000},
+	}
+	for _, req := range invalid {
+		if _, err := resolveWaitConfig(req, 0, 0); err == nil {
+			t.Errorf("期望参数 %+v 校验失败", req)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// 提取页面内容，在截图完成后于同一页面中执行，返回值按PageContent结构序列化
+(({ includeHTML, includeText, maxLinks }) => {
+  const meta = (selector) => {
+    const el = document.querySelector(selector);
+    return el ? (el.getAttribute('content') || '').trim() : '';
+  };
+  const collect = (prefix, attr) => {
+    const tags = {};
+    document.querySelectorAll(`meta[${attr}^="${prefix}"]`).forEach((el) => {
+      const key = el.getAttribute(attr);
+      const value = (el.getAttribute('content') || '').trim();
+      if (key && value && !(key in tags)) tags[key] = value;
+    });
+    return tags;
+  };
+
+  const canonicalEl = document.querySelector('link[rel="canonical"]');
+  const pageHost = location.hostname;
+
+  const links = [];
+  const seen = new Set();
+  for (const a of document.querySelectorAll('a[href]')) {
+    if (links.length >= maxLinks) break;
+    let url;
+    try {
+      url = new URL(a.getAttribute('href'), location.href);
+    } catch (e) {
+      continue;
+    }
+    if (!/^https?:$/.test(url.protocol) || url.hostname === pageHost) continue;
+    url.hash = '';
+    if (seen.has(url.href)) continue;
+    seen.add(url.href);
+    links.push({ url: url.href, host: url.hostname, text: (a.innerText || '').trim().slice(0, 200) });
+  }
+
+  const technologies = [];
+  const detect = (name, test, version) => {
+    try {
+      if (test()) technologies.push({ name, version: (version && version()) || '' });
+    } catch (e) {}
+  };
+  const html = document.documentElement.outerHTML;
+  detect('React', () => window.React || document.querySelector('[data-reactroot], [data-reactid]'), () => window.React && window.React.version);
+  detect('Next.js', () => window.__NEXT_DATA__ || document.getElementById('__next'));
+  detect('Vue.js', () => window.Vue || window.__VUE__ || document.querySelector('[data-v-app]'), () => window.Vue && window.Vue.version);
+  detect('Nuxt.js', () => window.__NUXT__ || document.getElementById('__nuxt'));
+  detect('Angular', () => window.ng || document.querySelector('[ng-version]'), () => {
+    const el = document.querySelector('[ng-version]');
+    return el && el.getAttribute('ng-version');
+  });
+  detect('AngularJS', () => window.angular, () => window.angular.version && window.angular.version.full);
+  detect('Svelte', () => document.querySelector('[class*="svelte-"]'));
+  detect('Gatsby', () => window.___gatsby || document.getElementById('___gatsby'));
+  detect('Ember.js', () => window.Ember, () => window.Ember.VERSION);
+  detect('Alpine.js', () => window.Alpine, () => window.Alpine.version);
+  detect('jQuery', () => window.jQuery, () => window.jQuery.fn && window.jQuery.fn.jquery);
+  detect('WordPress', () => html.includes('/wp-content/') || html.includes('/wp-includes/'));
+  detect('Shopify', () => window.Shopify);
+  detect('Google Analytics', () => window.ga || window.gtag || window.dataLayer);
+
+  return {
+    title: document.title || '',
+    description: meta('meta[name="description"]'),
+    canonical: canonicalEl ? canonicalEl.href : '',
+    generator: meta('meta[name="generator"]'),
+    language: document.documentElement.lang || '',
+    open_graph: collect('og:', 'property'),
+    twitter: collect('twitter:', 'name'),
+    links,
+    technologies,
+    html: includeHTML ? html : '',
+    text: includeText && document.body ? document.body.innerText : '',
+  };
+})
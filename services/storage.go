@@ -0,0 +1,352 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 截图文件存储 - 本地磁盘、S3/MinIO兼容存储和内存实现，多副本部署时共享截图、HAR和差异图
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSignedURLExpiry 预签名URL默认有效期，缓存命中时重新签名，不需要与缓存时间一致
+const DefaultSignedURLExpiry = 24 * time.Hour
+
+// ErrObjectNotFound 存储对象不存在
+var ErrObjectNotFound = errors.New("存储对象不存在")
+
+// ObjectInfo 存储对象信息
+type ObjectInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Storage 截图文件存储，键为/分隔的相对路径（如 screenshots/basic_example_com_1700000000.png）
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get 读取对象，不存在时返回ErrObjectNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// SignedURL 返回客户端可直接访问的URL：本地存储为/static路径，S3为预签名URL，配置了CDN时为CDN地址
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	// List 列出键以prefix开头的对象
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Stat 获取对象信息，不存在时返回ErrObjectNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+}
+
+// cleanKey 规范化对象键，防止路径穿越
+func cleanKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if cleaned == "" || cleaned == "." {
+		return "", fmt.Errorf("无效的存储键: %q", key)
+	}
+	return cleaned, nil
+}
+
+// storageKeyFromURL 将 /static/ 开头的旧文件URL转换为存储键，其余值视为存储键
+// 兼容升级前缓存和历史记录中只有URL的条目
+func storageKeyFromURL(value string) string {
+	return strings.TrimPrefix(value, "/static/")
+}
+
+// publicObjectURL 配置了CDN地址时直接拼接对象键
+func publicObjectURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
+
+var (
+	sharedStorage     Storage
+	sharedStorageErr  error
+	sharedStorageOnce sync.Once
+)
+
+// InitStorageFromEnv 按SCREENSHOT_STORAGE初始化全局文件存储，配置无效时返回错误，启动时调用以便尽早失败
+func InitStorageFromEnv() error {
+	sharedStorageOnce.Do(func() {
+		switch backend := strings.ToLower(os.Getenv("SCREENSHOT_STORAGE")); backend {
+		case "", "local":
+		case "s3":
+			s3, err := NewS3StorageFromEnv()
+			if err != nil {
+				sharedStorageErr = fmt.Errorf("S3存储配置无效: %w", err)
+				return
+			}
+			sharedStorage = s3
+			log.Printf("[STORAGE] 使用S3存储: %s", s3)
+		default:
+			sharedStorageErr = fmt.Errorf("未知的存储类型: %s", backend)
+		}
+	})
+	return sharedStorageErr
+}
+
+// storageFor 选择文件存储：显式配置优先；SCREENSHOT_STORAGE=s3时使用全局S3存储；否则使用BaseDir下的本地磁盘
+// 存储配置无效时不回退到本地磁盘，否则多副本之间的文件互不可见
+func storageFor(config *ScreenshotServiceConfig) Storage {
+	if config.Storage != nil {
+		return config.Storage
+	}
+	if err := InitStorageFromEnv(); err != nil {
+		return unavailableStorage{err: err}
+	}
+	if sharedStorage != nil {
+		return sharedStorage
+	}
+	return NewLocalStorage(config.BaseDir, os.Getenv("SCREENSHOT_STORAGE_PUBLIC_URL"))
+}
+
+// unavailableStorage 存储配置无效时使用，所有操作返回配置错误
+type unavailableStorage struct {
+	err error
+}
+
+func (u unavailableStorage) Put(context.Context, string, []byte, string) error {
+	return u.err
+}
+
+func (u unavailableStorage) Get(context.Context, string) ([]byte, error) {
+	return nil, u.err
+}
+
+func (u unavailableStorage) Delete(context.Context, string) error {
+	return u.err
+}
+
+func (u unavailableStorage) SignedURL(context.Context, string, time.Duration) (string, error) {
+	return "", u.err
+}
+
+func (u unavailableStorage) List(context.Context, string) ([]ObjectInfo, error) {
+	return nil, u.err
+}
+
+func (u unavailableStorage) Stat(context.Context, string) (*ObjectInfo, error) {
+	return nil, u.err
+}
+
+// LocalStorage 本地磁盘存储，文件通过 /static 路由访问
+type LocalStorage struct {
+	root       string
+	publicBase string // CDN地址，为空时返回/static路径
+}
+
+// NewLocalStorage 创建本地磁盘存储
+func NewLocalStorage(root, publicBase string) *LocalStorage {
+	return &LocalStorage{root: root, publicBase: publicBase}
+}
+
+func (l *LocalStorage) path(key string) (string, string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// Put 先写临时文件再重命名，读取方不会看到写了一半的文件；临时文件名唯一，并发写同一键互不干扰
+func (l *LocalStorage) Put(_ context.Context, key string, data []byte, _ string) error {
+	_, filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (l *LocalStorage) Get(_ context.Context, key string) ([]byte, error) {
+	_, filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return data, err
+}
+
+func (l *LocalStorage) Delete(_ context.Context, key string) error {
+	_, filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStorage) SignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if l.publicBase != "" {
+		return publicObjectURL(l.publicBase, key), nil
+	}
+	return "/static/" + key, nil
+}
+
+func (l *LocalStorage) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(l.root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(l.root, filePath)
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// 跳过与前缀无关的目录
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasSuffix(key, ".tmp") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+func (l *LocalStorage) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	key, filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// MemoryStorage 内存存储，用于测试和不需要持久化的单实例环境
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStorage 创建内存存储
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string]memoryObject)}
+}
+
+func (m *MemoryStorage) Put(_ context.Context, key string, data []byte, _ string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{data: append([]byte(nil), data...), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryStorage) Get(_ context.Context, key string) ([]byte, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return append([]byte(nil), object.data...), nil
+}
+
+func (m *MemoryStorage) Delete(_ context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *MemoryStorage) SignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return "memory://" + key, nil
+}
+
+func (m *MemoryStorage) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var objects []ObjectInfo
+	for key, object := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *MemoryStorage) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return &ObjectInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime}, nil
+}
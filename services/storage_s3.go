@@ -0,0 +1,397 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: S3/MinIO兼容存储 - 使用AWS Signature V4签名请求和生成预签名URL
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3签名参数
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3MaxPresignTTL   = 7 * 24 * time.Hour // SigV4预签名URL的最长有效期
+	s3RequestTimeout  = 60 * time.Second
+)
+
+// S3Config S3存储配置
+type S3Config struct {
+	Endpoint        string // 服务地址，为空时使用AWS的 https://s3.<region>.amazonaws.com
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool   // 路径风格访问（MinIO需要），否则使用虚拟主机风格 <bucket>.<host>
+	PublicBaseURL   string // CDN地址，设置后返回CDN URL而不是预签名URL
+}
+
+// S3Storage S3/MinIO兼容存储
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3StorageFromEnv 从环境变量创建S3存储
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	pathStyle := endpoint != ""
+	if value := os.Getenv("S3_FORCE_PATH_STYLE"); value != "" {
+		pathStyle = value == "true"
+	}
+	return NewS3Storage(S3Config{
+		Endpoint:        endpoint,
+		Region:          os.Getenv("S3_REGION"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle:       pathStyle,
+		PublicBaseURL:   os.Getenv("SCREENSHOT_STORAGE_PUBLIC_URL"),
+	})
+}
+
+// NewS3Storage 创建S3存储
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("需要设置S3_BUCKET、S3_ACCESS_KEY_ID和S3_SECRET_ACCESS_KEY")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3地址: %s", config.Endpoint)
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// String 日志中显示的存储位置，不含凭据
+func (s *S3Storage) String() string {
+	return fmt.Sprintf("%s/%s", s.endpoint.Host, s.config.Bucket)
+}
+
+// objectURL 对象的请求地址，key为空时为存储桶地址
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path = "/" + s.config.Bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = awsURIEncode(u.Path, false)
+	return &u
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), nil, header, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil, nil, nil)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(key), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &ObjectInfo{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+// s3ListResult ListObjectsV2响应
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List 使用ListObjectsV2分页列出对象
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, s.objectURL(""), query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析S3列表响应失败: %v", err)
+		}
+
+		for _, item := range result.Contents {
+			objects = append(objects, ObjectInfo{Key: item.Key, Size: item.Size, ModTime: item.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// SignedURL 生成GET预签名URL，配置了CDN地址时返回CDN URL
+func (s *S3Storage) SignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if s.config.PublicBaseURL != "" {
+		return publicObjectURL(s.config.PublicBaseURL, key), nil
+	}
+	if expires <= 0 {
+		expires = DefaultSignedURLExpiry
+	}
+	if expires > s3MaxPresignTTL {
+		expires = s3MaxPresignTTL
+	}
+
+	now := s.now().UTC()
+	u := s.objectURL(key)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.config.AccessKeyID + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	header := http.Header{"Host": {u.Host}}
+	signature := s.signature(now, http.MethodGet, u, query, header, []string{"host"}, s3UnsignedPayload)
+	query.Set("X-Amz-Signature", signature)
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// do 签名并发送请求，404返回ErrObjectNotFound，其余非2xx状态返回S3错误码
+func (s *S3Storage) do(ctx context.Context, method string, u *url.URL, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, s3RequestTimeout)
+	now := s.now().UTC()
+	payloadHash := sha256Hex(body)
+
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Host", u.Host)
+	header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := make([]string, 0, len(header))
+	for name := range header {
+		signed = append(signed, strings.ToLower(name))
+	}
+	sort.Strings(signed)
+	signature := s.signature(now, method, u, query, header, signed, payloadHash)
+	header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, s.scope(now), strings.Join(signed, ";"), signature))
+
+	target := *u
+	target.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	header.Del("Host")
+	req.Header = header
+	req.ContentLength = int64(len(body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("S3请求失败: %w", err)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, u.Path)
+	}
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s: %s %s", method, u.Path, s3Err.Code, s3Err.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s: 状态码 %d", method, u.Path, resp.StatusCode)
+}
+
+// cancelOnClose 响应体关闭时释放请求超时上下文
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (s *S3Storage) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.config.Region)
+}
+
+// signature 计算SigV4签名，signedHeaders为小写并已排序
+func (s *S3Storage) signature(now time.Time, method string, u *url.URL, query url.Values, header http.Header, signedHeaders []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		awsURIEncode(u.Path, false),
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery 按键排序并按SigV4规则编码的查询字符串
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode 除未保留字符外全部百分号编码，路径中的/按encodeSlash决定是否编码
+func awsURIEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
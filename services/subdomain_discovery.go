@@ -0,0 +1,312 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 基于证书透明日志的子域名发现服务
+ */
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"whosee/types"
+	"whosee/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SubdomainCacheTTL 子域名发现结果的缓存时间，CT日志更新频率较低
+const SubdomainCacheTTL = 12 * time.Hour
+
+// SubdomainEntry 单个子域名
+type SubdomainEntry struct {
+	Name      string   `json:"name"`                // 子域名（通配证书已折叠为其父级名称）
+	Wildcard  bool     `json:"wildcard"`            // 是否存在覆盖其下级的通配证书
+	Sources   []string `json:"sources"`             // 发现该子域名的数据源
+	Issuers   []string `json:"issuers,omitempty"`   // 签发机构
+	FirstSeen string   `json:"firstSeen,omitempty"` // 最早证书生效时间
+	LastSeen  string   `json:"lastSeen,omitempty"`  // 最晚证书过期时间
+	Addresses []string `json:"addresses,omitempty"` // 解析得到的IP地址（仅resolve时填充）
+}
+
+// SubdomainSourceStatus 单个数据源的查询状态
+type SubdomainSourceStatus struct {
+	Source       string `json:"source"`
+	Success      bool   `json:"success"`
+	Entries      int    `json:"entries"`
+	Error        string `json:"error,omitempty"`
+	ResponseTime int64  `json:"responseTimeMs"`
+}
+
+// SubdomainDiscoveryResult 子域名发现结果
+type SubdomainDiscoveryResult struct {
+	Domain     string                  `json:"domain"`
+	Subdomains []SubdomainEntry        `json:"subdomains"`
+	Total      int                     `json:"total"`
+	Sources    []SubdomainSourceStatus `json:"sources"`
+	QueryTime  string                  `json:"queryTime"`
+	IsCached   bool                    `json:"isCached"`
+}
+
+// SubdomainDiscovery 子域名发现服务，聚合多个证书透明日志数据源
+type SubdomainDiscovery struct {
+	mu      sync.RWMutex
+	sources []types.CTLogSource
+	rdb     *redis.Client
+}
+
+// NewSubdomainDiscovery 创建子域名发现服务
+func NewSubdomainDiscovery(rdb *redis.Client) *SubdomainDiscovery {
+	return &SubdomainDiscovery{
+		sources: make([]types.CTLogSource, 0),
+		rdb:     rdb,
+	}
+}
+
+// AddSource 添加数据源
+func (d *SubdomainDiscovery) AddSource(source types.CTLogSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sources = append(d.sources, source)
+	log.Printf("添加证书透明日志数据源: %s", source.Name())
+}
+
+// SourceNames 返回已注册的数据源名称
+func (d *SubdomainDiscovery) SourceNames() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	names := make([]string, len(d.sources))
+	for i, s := range d.sources {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Discover 查询所有数据源并合并结果，结果按域名缓存
+func (d *SubdomainDiscovery) Discover(ctx context.Context, domain string) (*SubdomainDiscoveryResult, error) {
+	domain = utils.SanitizeDomain(domain)
+	cacheKey := utils.BuildCacheKey("cache", "subdomains", domain)
+
+	if cached, ok := d.getCache(ctx, cacheKey); ok {
+		log.Printf("[SUBDOMAINS] 命中缓存: %s", domain)
+		return cached, nil
+	}
+
+	d.mu.RLock()
+	sources := make([]types.CTLogSource, len(d.sources))
+	copy(sources, d.sources)
+	d.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("没有可用的证书透明日志数据源")
+	}
+
+	// 并行查询所有数据源
+	type sourceResult struct {
+		index   int
+		entries []types.CTLogEntry
+		status  SubdomainSourceStatus
+	}
+	resultChan := make(chan sourceResult, len(sources))
+
+	for i, source := range sources {
+		go func(index int, s types.CTLogSource) {
+			startTime := time.Now()
+			entries, err := s.Search(ctx, domain)
+
+			status := SubdomainSourceStatus{
+				Source:       s.Name(),
+				Success:      err == nil,
+				Entries:      len(entries),
+				ResponseTime: time.Since(startTime).Milliseconds(),
+			}
+			if err != nil {
+				status.Error = err.Error()
+				log.Printf("[SUBDOMAINS] 数据源 %s 查询 %s 失败: %v", s.Name(), domain, err)
+			}
+			resultChan <- sourceResult{index: index, entries: entries, status: status}
+		}(i, source)
+	}
+
+	statuses := make([]SubdomainSourceStatus, len(sources))
+	collector := newSubdomainCollector(domain)
+	succeeded := 0
+	lastError := ""
+	for range sources {
+		r := <-resultChan
+		statuses[r.index] = r.status
+		if r.status.Success {
+			succeeded++
+			collector.add(r.status.Source, r.entries)
+		} else {
+			lastError = r.status.Error
+		}
+	}
+
+	if succeeded == 0 {
+		return nil, fmt.Errorf("所有证书透明日志数据源均查询失败: %s", lastError)
+	}
+
+	subdomains := collector.result()
+	result := &SubdomainDiscoveryResult{
+		Domain:     domain,
+		Subdomains: subdomains,
+		Total:      len(subdomains),
+		Sources:    statuses,
+		QueryTime:  time.Now().Format(time.RFC3339),
+	}
+
+	// 部分数据源失败时不缓存，避免长时间返回不完整的结果
+	if succeeded == len(sources) {
+		d.setCache(ctx, cacheKey, result, SubdomainCacheTTL)
+	}
+
+	log.Printf("[SUBDOMAINS] %s 发现子域名 %d 个，数据源成功 %d/%d", domain, len(subdomains), succeeded, len(sources))
+	return result, nil
+}
+
+func (d *SubdomainDiscovery) getCache(ctx context.Context, key string) (*SubdomainDiscoveryResult, bool) {
+	if d.rdb == nil {
+		return nil, false
+	}
+	data, err := d.rdb.Get(ctx, key).Result()
+	if err != nil {
+		return nil, false
+	}
+	var result SubdomainDiscoveryResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		log.Printf("[SUBDOMAINS] 解析缓存数据失败: %v", err)
+		return nil, false
+	}
+	result.IsCached = true
+	return &result, true
+}
+
+func (d *SubdomainDiscovery) setCache(ctx context.Context, key string, result *SubdomainDiscoveryResult, ttl time.Duration) {
+	if d.rdb == nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := d.rdb.Set(ctx, key, data, ttl).Err(); err != nil {
+		log.Printf("[SUBDOMAINS] 缓存结果失败: %v", err)
+	}
+}
+
+// subdomainCollector 合并多个数据源的证书记录：去重、折叠通配符、统计时间范围
+type subdomainCollector struct {
+	domain string
+	items  map[string]*subdomainItem
+}
+
+type subdomainItem struct {
+	wildcard  bool
+	sources   map[string]struct{}
+	issuers   map[string]struct{}
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+func newSubdomainCollector(domain string) *subdomainCollector {
+	return &subdomainCollector{
+		domain: domain,
+		items:  make(map[string]*subdomainItem),
+	}
+}
+
+func (c *subdomainCollector) add(source string, entries []types.CTLogEntry) {
+	for _, entry := range entries {
+		for _, raw := range entry.Names {
+			name, wildcard, ok := c.normalize(raw)
+			if !ok {
+				continue
+			}
+
+			item, exists := c.items[name]
+			if !exists {
+				item = &subdomainItem{
+					sources: make(map[string]struct{}),
+					issuers: make(map[string]struct{}),
+				}
+				c.items[name] = item
+			}
+
+			item.wildcard = item.wildcard || wildcard
+			item.sources[source] = struct{}{}
+			if entry.Issuer != "" {
+				item.issuers[entry.Issuer] = struct{}{}
+			}
+			if !entry.NotBefore.IsZero() && (item.firstSeen.IsZero() || entry.NotBefore.Before(item.firstSeen)) {
+				item.firstSeen = entry.NotBefore
+			}
+			if entry.NotAfter.After(item.lastSeen) {
+				item.lastSeen = entry.NotAfter
+			}
+		}
+	}
+}
+
+// normalize 清理主机名，*.foo.example.com 折叠为 foo.example.com 并标记通配
+func (c *subdomainCollector) normalize(raw string) (string, bool, bool) {
+	name := strings.Trim(strings.ToLower(strings.TrimSpace(raw)), ".")
+	if name == "" || strings.Contains(name, "@") || strings.ContainsAny(name, " /") {
+		return "", false, false
+	}
+
+	wildcard := false
+	for strings.HasPrefix(name, "*.") {
+		name = name[2:]
+		wildcard = true
+	}
+
+	if name != c.domain && !strings.HasSuffix(name, "."+c.domain) {
+		return "", false, false
+	}
+	// 其余位置出现通配符的名称不是合法主机名
+	if strings.Contains(name, "*") {
+		return "", false, false
+	}
+
+	return name, wildcard, true
+}
+
+func (c *subdomainCollector) result() []SubdomainEntry {
+	entries := make([]SubdomainEntry, 0, len(c.items))
+	for name, item := range c.items {
+		entry := SubdomainEntry{
+			Name:     name,
+			Wildcard: item.wildcard,
+			Sources:  sortedKeys(item.sources),
+			Issuers:  sortedKeys(item.issuers),
+		}
+		if !item.firstSeen.IsZero() {
+			entry.FirstSeen = item.firstSeen.Format(time.RFC3339)
+		}
+		if !item.lastSeen.IsZero() {
+			entry.LastSeen = item.lastSeen.Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"whosee/types"
+)
+
+type fakeCTSource struct {
+	name    string
+	entries []types.CTLogEntry
+	err     error
+}
+
+func (s *fakeCTSource) Name() string { return s.name }
+
+func (s *fakeCTSource) Search(ctx context.Context, domain string) ([]types.CTLogEntry, error) {
+	return s.entries, s.err
+}
+
+// TestSubdomainDiscoveryMergesSources 测试多数据源去重、通配符折叠及部分失败
+func TestSubdomainDiscoveryMergesSources(t *testing.T) {
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	discovery := NewSubdomainDiscovery(nil)
+	discovery.AddSource(&fakeCTSource{
+		name: "a",
+		entries: []types.CTLogEntry{
+			{Names: []string{"WWW.Example.com", "*.api.example.com", "other.org"}, Issuer: "CA1", NotBefore: notBefore, NotAfter: notAfter},
+		},
+	})
+	discovery.AddSource(&fakeCTSource{
+		name: "b",
+		entries: []types.CTLogEntry{
+			{Names: []string{"www.example.com.", "api.example.com", "a.*.example.com"}, Issuer: "CA2"},
+		},
+	})
+	discovery.AddSource(&fakeCTSource{name: "broken", err: errors.New("down")})
+
+	result, err := discovery.Discover(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Discover 返回错误: %v", err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("期望 2 个子域名, 实际 %d: %+v", result.Total, result.Subdomains)
+	}
+
+	api, www := result.Subdomains[0], result.Subdomains[1]
+	if api.Name != "api.example.com" || !api.Wildcard || len(api.Sources) != 2 {
+		t.Errorf("api.example.com 合并结果不正确: %+v", api)
+	}
+	if www.Name != "www.example.com" || www.Wildcard || len(www.Issuers) != 2 {
+		t.Errorf("www.example.com 合并结果不正确: %+v", www)
+	}
+	if www.FirstSeen != notBefore.Format(time.RFC3339) || www.LastSeen != notAfter.Format(time.RFC3339) {
+		t.Errorf("时间范围不正确: %s - %s", www.FirstSeen, www.LastSeen)
+	}
+	if result.Sources[2].Success {
+		t.Errorf("失败的数据源应标记为失败")
+	}
+}
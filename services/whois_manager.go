@@ -8,6 +8,7 @@ package services
 import (
 	"context"
 	"whosee/types"
+	"whosee/utils"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -193,7 +194,15 @@ func (m *WhoisManager) selectProvider() WhoisProvider {
 	return selected
 }
 
+// Query 查询域名WHOIS信息，输入会先按公共后缀列表归约为可注册域名
 func (m *WhoisManager) Query(domain string) (*types.WhoisResponse, error, bool) {
+	parts, lookupDomain := splitLookupDomain(domain)
+	response, err, cached := m.query(lookupDomain)
+	annotateDomainParts(response, parts)
+	return response, err, cached
+}
+
+func (m *WhoisManager) query(domain string) (*types.WhoisResponse, error, bool) {
 	// 创建一个空的WhoisResponse用于错误情况下返回
 	emptyResponse := &types.WhoisResponse{
 		Domain:        domain,
@@ -699,6 +708,13 @@ func (m *WhoisManager) GetOverallStatus() string {
 
 // QueryWithProvider 使用指定提供商查询域名信息
 func (m *WhoisManager) QueryWithProvider(domain string, providerName string) (*types.WhoisResponse, error, bool) {
+	parts, lookupDomain := splitLookupDomain(domain)
+	response, err, cached := m.queryWithProvider(lookupDomain, providerName)
+	annotateDomainParts(response, parts)
+	return response, err, cached
+}
+
+func (m *WhoisManager) queryWithProvider(domain string, providerName string) (*types.WhoisResponse, error, bool) {
 	// 创建一个空的WhoisResponse用于错误情况下返回
 	emptyResponse := &types.WhoisResponse{
 		Domain:         domain,
@@ -792,3 +808,26 @@ func (m *WhoisManager) QueryWithProvider(domain string, providerName string) (*t
 
 	return response, nil, cached
 }
+
+// splitLookupDomain 拆分查询输入，返回拆分结果和实际用于查询的可注册域名
+// 无法拆分时（如输入本身就是公共后缀）保持原样查询，由提供商给出具体错误
+func splitLookupDomain(domain string) (*utils.DomainParts, string) {
+	parts, err := utils.SplitDomain(domain)
+	if err != nil || parts.RegistrableDomain == "" {
+		return nil, domain
+	}
+	if parts.RegistrableDomain != domain {
+		log.Printf("域名 %s 归约为可注册域名 %s 进行查询", domain, parts.RegistrableDomain)
+	}
+	return parts, parts.RegistrableDomain
+}
+
+// annotateDomainParts 在响应中写入域名拆分信息
+func annotateDomainParts(response *types.WhoisResponse, parts *utils.DomainParts) {
+	if response == nil || parts == nil {
+		return
+	}
+	response.Subdomain = parts.Subdomain
+	response.RegistrableDomain = parts.RegistrableDomain
+	response.EffectiveTLD = parts.EffectiveTLD
+}
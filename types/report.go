@@ -0,0 +1,38 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 域名聚合报告类型定义
+ */
+package types
+
+// 报告分节状态
+const (
+	ReportStatusSuccess = "success" // 查询成功
+	ReportStatusError   = "error"   // 查询失败
+	ReportStatusTimeout = "timeout" // 超过分节超时时间
+	ReportStatusBusy    = "busy"    // 工作池已满，未能执行
+)
+
+// ReportSection 报告中单个分节的结果
+type ReportSection struct {
+	Status  string      `json:"status"`          // 分节状态
+	Data    interface{} `json:"data,omitempty"`  // 分节数据，结构与对应的单独接口一致
+	Error   string      `json:"error,omitempty"` // 失败原因
+	Latency int64       `json:"latencyMs"`       // 分节耗时（毫秒）
+	Cached  bool        `json:"cached"`          // 是否来自缓存
+}
+
+// ReportSummary 报告分节统计
+type ReportSummary struct {
+	Requested int `json:"requested"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// DomainReport 域名聚合报告
+type DomainReport struct {
+	Domain      string                    `json:"domain"`
+	GeneratedAt string                    `json:"generatedAt"`
+	Sections    map[string]*ReportSection `json:"sections"`
+	Summary     ReportSummary             `json:"summary"`
+}
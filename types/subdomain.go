@@ -0,0 +1,25 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 证书透明日志子域名发现类型定义
+ */
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// CTLogEntry 证书透明日志中的一条证书记录
+type CTLogEntry struct {
+	Names     []string  // 证书覆盖的主机名（CN与SAN）
+	Issuer    string    // 签发机构
+	NotBefore time.Time // 证书生效时间
+	NotAfter  time.Time // 证书过期时间
+}
+
+// CTLogSource 证书透明日志数据源接口
+type CTLogSource interface {
+	Search(ctx context.Context, domain string) ([]CTLogEntry, error)
+	Name() string
+}
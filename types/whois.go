@@ -5,6 +5,11 @@
  */
 package types
 
+import (
+	"strconv"
+	"strings"
+)
+
 // WhoisResponse 统一的WHOIS响应结构
 type WhoisResponse struct {
 	Available      bool     `json:"available"`
@@ -25,6 +30,11 @@ type WhoisResponse struct {
 	StatusCode     int      `json:"statusCode"`               // 查询状态码
 	StatusMessage  string   `json:"statusMessage,omitempty"`  // 状态描述信息
 	CachedAt       string   `json:"cachedAt,omitempty"`       // 数据缓存时间
+
+	// 基于公共后缀列表的域名拆分结果
+	Subdomain         string `json:"subdomain,omitempty"`         // 查询输入中的子域名部分
+	RegistrableDomain string `json:"registrableDomain,omitempty"` // 实际查询的可注册域名
+	EffectiveTLD      string `json:"effectiveTLD,omitempty"`      // 有效顶级域名
 }
 
 type Contact struct {
@@ -42,3 +52,49 @@ type WhoisProvider interface {
 	Query(domain string) (*WhoisResponse, error, bool)
 	Name() string
 }
+
+// WhoisCSVHeader WhoisResponse 展平为CSV时的列名，与 CSVRecord 的顺序一致
+var WhoisCSVHeader = []string{
+	"domain", "available", "registrar", "creationDate", "expiryDate", "updatedDate",
+	"status", "nameServers", "whoisServer", "domainAge", "contactEmail",
+	"registrantName", "registrantOrganization", "registrantEmail", "registrantCountry",
+	"sourceProvider", "statusCode", "statusMessage", "cachedAt",
+	"subdomain", "registrableDomain", "effectiveTLD",
+}
+
+// CSVRecord 将WHOIS响应展平为一行CSV，状态和域名服务器列表以分号连接
+func (r *WhoisResponse) CSVRecord() []string {
+	if r == nil {
+		return make([]string, len(WhoisCSVHeader))
+	}
+
+	registrant := r.Registrant
+	if registrant == nil {
+		registrant = &Contact{}
+	}
+
+	return []string{
+		r.Domain,
+		strconv.FormatBool(r.Available),
+		r.Registrar,
+		r.CreateDate,
+		r.ExpiryDate,
+		r.UpdateDate,
+		strings.Join(r.Status, "; "),
+		strings.Join(r.NameServers, "; "),
+		r.WhoisServer,
+		strconv.Itoa(r.DomainAge),
+		r.ContactEmail,
+		registrant.Name,
+		registrant.Organization,
+		registrant.Email,
+		registrant.Country,
+		r.SourceProvider,
+		strconv.Itoa(r.StatusCode),
+		r.StatusMessage,
+		r.CachedAt,
+		r.Subdomain,
+		r.RegistrableDomain,
+		r.EffectiveTLD,
+	}
+}
@@ -8,6 +8,8 @@ package utils
 import (
 	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +17,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -478,6 +481,7 @@ type ChromeDownloader struct {
 	chromeExecutable string
 	version          string
 	platformDetector *SmartPlatformDetector // 智能平台检测器
+	sources          ChromeSourceConfig     // 本地压缩包、内部镜像和SHA-256清单
 }
 
 // NewChromeDownloader 创建Chrome下载器
@@ -487,8 +491,15 @@ func NewChromeDownloader() *ChromeDownloader {
 
 	downloader := &ChromeDownloader{
 		downloadDir:      baseDir,
-		version:          "stable", // 或者指定具体版本
+		version:          "stable", // 或者通过CHROME_VERSION指定具体版本
 		platformDetector: detector,
+		sources:          loadChromeSourceConfig(),
+	}
+	if err := downloader.SetVersion(strings.TrimSpace(os.Getenv("CHROME_VERSION"))); err != nil {
+		log.Printf("[CHROME-DOWNLOADER] 忽略CHROME_VERSION: %v", err)
+	}
+	if downloader.sources.Strict {
+		log.Printf("[CHROME-DOWNLOADER] 严格离线模式：只从本地压缩包或内部镜像安装Chrome")
 	}
 
 	// 显示平台检测结果和建议
@@ -821,14 +832,25 @@ func (cd *ChromeDownloader) testNetworkConnectivity() error {
 
 // DownloadChrome 下载Chrome浏览器
 func (cd *ChromeDownloader) DownloadChrome() error {
-	// 先进行网络连接测试
-	if err := cd.testNetworkConnectivity(); err != nil {
-		log.Printf("[CHROME-DOWNLOADER] 网络连接测试失败: %v", err)
-		// 网络有问题但不完全阻止下载，继续尝试
+	// 配置了本地压缩包时直接安装，不访问网络
+	if cd.sources.ArchivePath != "" {
+		log.Printf("[CHROME-DOWNLOADER] 从本地压缩包安装Chrome: %s", cd.sources.ArchivePath)
+		if err := os.MkdirAll(cd.downloadDir, 0755); err != nil {
+			return fmt.Errorf("创建下载目录失败: %w", err)
+		}
+		return cd.installArchive(cd.sources.ArchivePath, cd.sources.ArchivePath)
+	}
+
+	// 先进行网络连接测试（测试的都是公网地址，使用内部镜像时跳过）
+	if !cd.sources.Strict && cd.sources.MirrorBase == "" {
+		if err := cd.testNetworkConnectivity(); err != nil {
+			log.Printf("[CHROME-DOWNLOADER] 网络连接测试失败: %v", err)
+			// 网络有问题但不完全阻止下载，继续尝试
+		}
 	}
 
 	// 使用新的URL获取方法
-	downloadUrls, err := cd.getWorkingChromeUrls()
+	downloadUrls, err := cd.getDownloadUrls()
 	if err != nil {
 		log.Printf("[CHROME-DOWNLOADER] 获取下载链接失败: %v", err)
 		return fmt.Errorf("无法获取Chrome下载链接: %w", err)
@@ -880,44 +902,15 @@ func (cd *ChromeDownloader) DownloadChrome() error {
 					log.Printf("[CHROME-DOWNLOADER] 下载文件大小: %.2f MB", float64(stat.Size())/(1024*1024))
 				}
 
-				// 解压文件
-				if extractErr := cd.extractZip(zipFilePath, cd.downloadDir); extractErr != nil {
-					log.Printf("[CHROME-DOWNLOADER] 解压失败: %v，尝试下一个源", extractErr)
-					os.Remove(zipFilePath)
-					lastErr = extractErr
-					break // 跳到下一个下载源
-				}
-
-				// 清理临时文件
+				// 校验并解压，完成后清理临时文件
+				installErr := cd.installArchive(zipFilePath, downloadUrl)
 				os.Remove(zipFilePath)
-
-				// 重新验证Chrome可执行文件是否存在（重置缓存）
-				cd.chromeExecutable = "" // 重置路径缓存，强制重新扫描
-
-				// 列出下载目录内容进行诊断
-				log.Printf("[CHROME-DOWNLOADER] 解压完成，诊断下载目录内容:")
-				cd.diagnoseDowloadDirectory()
-
-				// 检查Chrome可执行文件
-				if cd.IsChromeBinaryExists() {
-					execPath := cd.GetChromeExecutablePath()
-
-					// 设置可执行权限 (Linux/Mac)
-					if runtime.GOOS != "windows" {
-						if err := os.Chmod(execPath, 0755); err != nil {
-							log.Printf("[CHROME-DOWNLOADER] 设置可执行权限失败: %v", err)
-						}
-					}
-
-					log.Printf("[CHROME-DOWNLOADER] Chrome下载和解压完成: %s", execPath)
-					return nil
-				} else {
-					// 如果仍然找不到可执行文件，提供详细的诊断信息
-					log.Printf("[CHROME-DOWNLOADER] 解压成功但Chrome可执行文件仍不存在")
-					log.Printf("[CHROME-DOWNLOADER] 期望路径: %s", cd.GetChromeExecutablePath())
-					lastErr = fmt.Errorf("Chrome可执行文件不存在: %s", cd.GetChromeExecutablePath())
+				if installErr != nil {
+					log.Printf("[CHROME-DOWNLOADER] 安装失败: %v，尝试下一个源", installErr)
+					lastErr = installErr
 					break // 跳到下一个下载源
 				}
+				return nil
 			}
 
 			lastErr = err
@@ -947,6 +940,76 @@ func (cd *ChromeDownloader) DownloadChrome() error {
 	return fmt.Errorf("下载Chrome失败: 所有下载源都失败了，最后错误: %v", lastErr)
 }
 
+// installArchive 按SHA-256清单校验压缩包，解压到下载目录并写入安装记录
+// 解压时记录每个文件的校验和，供 whosee chrome verify 使用
+func (cd *ChromeDownloader) installArchive(archivePath, source string) error {
+	archiveSum, archiveMD5, err := archiveDigests(archivePath)
+	if err != nil {
+		return fmt.Errorf("计算压缩包校验和失败: %w", err)
+	}
+	if err := cd.checkPinnedChecksum(source, archiveSum); err != nil {
+		return err
+	}
+
+	// 解压文件
+	files, err := cd.extractZip(archivePath, cd.downloadDir)
+	if err != nil {
+		return fmt.Errorf("解压失败: %w", err)
+	}
+
+	// 重新验证Chrome可执行文件是否存在（重置缓存）
+	cd.chromeExecutable = "" // 重置路径缓存，强制重新扫描
+
+	// 列出下载目录内容进行诊断
+	log.Printf("[CHROME-DOWNLOADER] 解压完成，诊断下载目录内容:")
+	cd.diagnoseDowloadDirectory()
+
+	// 检查Chrome可执行文件
+	if !cd.IsChromeBinaryExists() {
+		// 如果仍然找不到可执行文件，提供详细的诊断信息
+		log.Printf("[CHROME-DOWNLOADER] 解压成功但Chrome可执行文件仍不存在")
+		log.Printf("[CHROME-DOWNLOADER] 期望路径: %s", cd.GetChromeExecutablePath())
+		return fmt.Errorf("Chrome可执行文件不存在: %s", cd.GetChromeExecutablePath())
+	}
+	execPath := cd.GetChromeExecutablePath()
+
+	// 设置可执行权限 (Linux/Mac)
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(execPath, 0755); err != nil {
+			log.Printf("[CHROME-DOWNLOADER] 设置可执行权限失败: %v", err)
+		}
+	}
+
+	// 版本号从解压出的Chrome读取，下载链接和CHROME_VERSION只是期望值，不能作为安装结果记录
+	version, err := readChromeVersion(execPath)
+	if err != nil {
+		log.Printf("[CHROME-DOWNLOADER] 读取Chrome版本失败: %v", err)
+	}
+	if versionPattern.MatchString(cd.version) && version != cd.version {
+		return fmt.Errorf("压缩包中的Chrome版本 %q 与指定版本 %s 不一致", version, cd.version)
+	}
+	var revision string
+	if urlVersion := versionFromURL(source); revisionPattern.MatchString(urlVersion) {
+		revision = urlVersion
+	}
+	record := &ChromeInstallRecord{
+		Version:       version,
+		Revision:      revision,
+		Platform:      cd.GetChromePlatform(),
+		SourceURL:     source,
+		ArchiveSHA256: archiveSum,
+		ArchiveMD5:    archiveMD5,
+		InstalledAt:   time.Now(),
+		Files:         files,
+	}
+	if err := cd.writeInstallRecord(record); err != nil {
+		log.Printf("[CHROME-DOWNLOADER] 写入安装记录失败: %v", err)
+	}
+
+	log.Printf("[CHROME-DOWNLOADER] Chrome下载和解压完成: %s", execPath)
+	return nil
+}
+
 // diagnoseDowloadDirectory 诊断下载目录内容
 func (cd *ChromeDownloader) diagnoseDowloadDirectory() {
 	entries, err := os.ReadDir(cd.downloadDir)
@@ -982,7 +1045,13 @@ func (cd *ChromeDownloader) downloadFile(url, filepath string) error {
 	log.Printf("[CHROME-DOWNLOADER] 开始下载文件: %s", url)
 
 	// 创建HTTP客户端，设置更优的网络配置
-	transport := optimizeNetworkSettings()
+	// 内部镜像使用系统DNS和代理配置，优化配置中的公共DNS无法解析内网域名
+	var transport *http.Transport
+	if cd.isMirrorURL(url) {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	} else {
+		transport = optimizeNetworkSettings()
+	}
 
 	client := &http.Client{
 		Timeout:   20 * time.Minute,
@@ -1045,21 +1114,22 @@ func (cd *ChromeDownloader) downloadFile(url, filepath string) error {
 	return nil
 }
 
-// extractZip 解压ZIP文件
-func (cd *ChromeDownloader) extractZip(src, dest string) error {
+// extractZip 解压ZIP文件，返回解压出的文件（/分隔的相对路径）及其SHA-256
+func (cd *ChromeDownloader) extractZip(src, dest string) (map[string]string, error) {
 	log.Printf("[CHROME-DOWNLOADER] 开始解压: %s -> %s", src, dest)
 
 	reader, err := zip.OpenReader(src)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer reader.Close()
 
 	// 确保目标目录存在
 	if err := os.MkdirAll(dest, 0755); err != nil {
-		return err
+		return nil, err
 	}
 
+	files := make(map[string]string)
 	extractedFiles := 0
 	for _, file := range reader.File {
 		// 构建目标路径
@@ -1067,26 +1137,28 @@ func (cd *ChromeDownloader) extractZip(src, dest string) error {
 
 		// 检查路径安全性
 		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return fmt.Errorf("无效的文件路径: %s", file.Name)
+			return nil, fmt.Errorf("无效的文件路径: %s", file.Name)
 		}
 
 		if file.FileInfo().IsDir() {
 			// 创建目录
 			if err := os.MkdirAll(path, file.FileInfo().Mode()); err != nil {
-				return err
+				return nil, err
 			}
 			continue
 		}
 
 		// 创建文件的目录
 		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			return err
+			return nil, err
 		}
 
 		// 解压文件
-		if err := cd.extractFile(file, path); err != nil {
-			return err
+		sum, err := cd.extractFile(file, path)
+		if err != nil {
+			return nil, err
 		}
+		files[filepath.ToSlash(file.Name)] = sum
 
 		extractedFiles++
 		if extractedFiles%100 == 0 {
@@ -1095,25 +1167,28 @@ func (cd *ChromeDownloader) extractZip(src, dest string) error {
 	}
 
 	log.Printf("[CHROME-DOWNLOADER] 解压完成，共解压 %d 个文件", extractedFiles)
-	return nil
+	return files, nil
 }
 
-// extractFile 解压单个文件
-func (cd *ChromeDownloader) extractFile(file *zip.File, destPath string) error {
+// extractFile 解压单个文件，返回文件内容的SHA-256
+func (cd *ChromeDownloader) extractFile(file *zip.File, destPath string) (string, error) {
 	rc, err := file.Open()
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer rc.Close()
 
 	outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileInfo().Mode())
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer outFile.Close()
 
-	_, err = io.Copy(outFile, rc)
-	return err
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(outFile, h), rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // EnsureChrome 确保Chrome可用，如果不存在则下载
@@ -1123,8 +1198,8 @@ func (cd *ChromeDownloader) EnsureChrome() (string, error) {
 	// 重置Chrome可执行文件路径缓存，确保重新扫描
 	cd.chromeExecutable = ""
 
-	// 首先检查下载的Chrome是否存在
-	if cd.IsChromeBinaryExists() {
+	// 首先检查下载的Chrome是否存在，指定了版本时还要求已安装的版本一致
+	if cd.IsChromeBinaryExists() && cd.matchesPinnedVersion() {
 		execPath := cd.GetChromeExecutablePath()
 		log.Printf("[CHROME-DOWNLOADER] 找到已下载的Chrome: %s", execPath)
 		return execPath, nil
@@ -1151,6 +1226,19 @@ func (cd *ChromeDownloader) EnsureChrome() (string, error) {
 	return execPath, nil
 }
 
+// matchesPinnedVersion 已安装的Chrome是否为指定的版本，未指定版本或没有安装记录时视为一致
+func (cd *ChromeDownloader) matchesPinnedVersion() bool {
+	if cd.version == "" || cd.version == "stable" {
+		return true
+	}
+	record, err := cd.readInstallRecord()
+	if err != nil || record.Version == cd.version || record.Revision == cd.version {
+		return true
+	}
+	log.Printf("[CHROME-DOWNLOADER] 已安装版本 %s 与指定版本 %s 不一致，重新安装", record.Version, cd.version)
+	return false
+}
+
 // findSystemChrome 查找系统中已安装的Chrome
 func (cd *ChromeDownloader) findSystemChrome() string {
 	if runtime.GOOS != "windows" {
@@ -1177,28 +1265,83 @@ func (cd *ChromeDownloader) findSystemChrome() string {
 	return ""
 }
 
-// TestChrome 测试Chrome是否可以正常运行
+// TestChrome 测试Chrome是否可以正常运行：无头模式加载空白页，确认动态库依赖和沙箱参数可用
 func (cd *ChromeDownloader) TestChrome() error {
+	if !cd.IsChromeBinaryExists() {
+		return fmt.Errorf("Chrome可执行文件不存在: %s", cd.GetChromeExecutablePath())
+	}
 	execPath := cd.GetChromeExecutablePath()
-	if execPath == "" {
-		return fmt.Errorf("Chrome可执行文件路径为空")
+	log.Printf("[CHROME-DOWNLOADER] 测试Chrome: %s", execPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Windows版chrome.exe不向控制台输出版本号
+	if runtime.GOOS != "windows" {
+		out, err := exec.CommandContext(ctx, execPath, "--version").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("获取Chrome版本失败: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		log.Printf("[CHROME-DOWNLOADER] Chrome版本: %s", strings.TrimSpace(string(out)))
 	}
 
-	// 简单的版本检查
-	log.Printf("[CHROME-DOWNLOADER] 测试Chrome: %s", execPath)
-	// 这里可以添加具体的测试逻辑
+	args := []string{"--headless=new", "--disable-gpu", "--no-sandbox", "--disable-dev-shm-usage", "--dump-dom", "about:blank"}
+	out, err := exec.CommandContext(ctx, execPath, args...).Output()
+	if err != nil {
+		return fmt.Errorf("Chrome无头模式启动失败: %w", err)
+	}
+	if !strings.Contains(string(out), "<html") {
+		return fmt.Errorf("Chrome无头模式未返回页面内容")
+	}
 
+	log.Printf("[CHROME-DOWNLOADER] Chrome测试通过")
 	return nil
 }
 
-// CleanupOldVersions 清理旧版本
-func (cd *ChromeDownloader) CleanupOldVersions() error {
+// CleanupOldVersions 清理下载目录中当前安装以外的内容（旧版本目录、残留压缩包），返回被删除的路径
+// 无法确定当前安装时只清理残留的压缩包
+func (cd *ChromeDownloader) CleanupOldVersions() ([]string, error) {
 	log.Printf("[CHROME-DOWNLOADER] 清理旧版本...")
 
-	// 这里可以添加清理逻辑，例如删除旧的Chrome目录
-	// 当前实现保持简单，不进行清理
+	entries, err := os.ReadDir(cd.downloadDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-	return nil
+	// 当前安装的顶层目录：安装记录中的文件和当前使用的可执行文件
+	keep := make(map[string]bool)
+	if record, err := cd.readInstallRecord(); err == nil {
+		for name := range record.Files {
+			keep[strings.SplitN(name, "/", 2)[0]] = true
+		}
+	}
+	if cd.IsChromeBinaryExists() {
+		if rel, err := filepath.Rel(cd.downloadDir, cd.GetChromeExecutablePath()); err == nil && !strings.HasPrefix(rel, "..") {
+			keep[strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]] = true
+		}
+	}
+
+	var removed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == installRecordFile || keep[name] {
+			continue
+		}
+		if len(keep) == 0 && !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		path := filepath.Join(cd.downloadDir, name)
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("删除 %s 失败: %w", path, err)
+		}
+		log.Printf("[CHROME-DOWNLOADER] 已删除: %s", path)
+		removed = append(removed, path)
+	}
+
+	return removed, nil
 }
 
 // GetChromeInfo 获取Chrome信息 (智能增强版本)
@@ -1214,6 +1357,18 @@ func (cd *ChromeDownloader) GetChromeInfo() map[string]interface{} {
 	info["exists"] = cd.IsChromeBinaryExists()
 	info["version"] = cd.version
 
+	// 通过下载器安装的Chrome
+	if record, err := cd.readInstallRecord(); err == nil {
+		info["installed"] = map[string]interface{}{
+			"version":        record.Version,
+			"revision":       record.Revision,
+			"source_url":     record.SourceURL,
+			"archive_sha256": record.ArchiveSHA256,
+			"installed_at":   record.InstalledAt,
+			"files":          len(record.Files),
+		}
+	}
+
 	// 智能平台信息
 	info["platform_info"] = map[string]interface{}{
 		"os":           platform.OS,
@@ -1226,9 +1381,17 @@ func (cd *ChromeDownloader) GetChromeInfo() map[string]interface{} {
 		"details":      platform.Details,
 	}
 
-	// 环境信息
+	// 安装来源
+	info["sources"] = map[string]interface{}{
+		"archive_path":    cd.sources.ArchivePath,
+		"mirror_base":     cd.sources.MirrorBase,
+		"sha256_manifest": cd.sources.ManifestPath,
+		"strict":          cd.sources.Strict,
+	}
+
+	// 环境信息（严格模式下不探测公网）
 	info["environment"] = map[string]interface{}{
-		"is_china_network": cd.isInChina(),
+		"is_china_network": !cd.sources.Strict && cd.isInChina(),
 		"cpu_cores":        runtime.NumCPU(),
 		"go_version":       runtime.Version(),
 	}
@@ -1239,7 +1402,7 @@ func (cd *ChromeDownloader) GetChromeInfo() map[string]interface{} {
 	}
 
 	// 支持的下载源统计
-	if urls, err := cd.getWorkingChromeUrls(); err == nil {
+	if urls, err := cd.getDownloadUrls(); err == nil {
 		info["available_sources"] = len(urls)
 		info["source_urls"] = urls[:min(len(urls), 3)] // 只显示前3个源
 	} else {
@@ -1373,8 +1536,11 @@ func (cd *ChromeDownloader) getDynamicChromeUrls() ([]string, error) {
 // getChinaMirrorUrls 获取国内镜像源链接
 func (cd *ChromeDownloader) getChinaMirrorUrls(platform string) []string {
 	// 使用较新的修订版本
-	revision := "1354089"
+	return cd.getSnapshotMirrorUrls(platform, "1354089")
+}
 
+// getSnapshotMirrorUrls 获取指定修订号的Chromium快照在国内镜像上的链接
+func (cd *ChromeDownloader) getSnapshotMirrorUrls(platform, revision string) []string {
 	var filename string
 	switch platform {
 	case "win64":
@@ -1410,6 +1576,74 @@ func (cd *ChromeDownloader) getChinaMirrorUrls(platform string) []string {
 	}
 }
 
+// SetVersion 指定下载的Chrome版本：Chrome for Testing版本号（如131.0.6778.108）或Chromium快照修订号
+// 空值或stable表示下载最新可用版本
+func (cd *ChromeDownloader) SetVersion(version string) error {
+	switch {
+	case version == "" || version == "stable":
+		cd.version = "stable"
+	case versionPattern.MatchString(version), revisionPattern.MatchString(version):
+		cd.version = version
+	default:
+		return fmt.Errorf("无效的Chrome版本: %s（应为如131.0.6778.108的版本号或快照修订号）", version)
+	}
+	return nil
+}
+
+// getDownloadUrls 获取下载链接：内部镜像优先；严格模式只使用内部镜像
+// 指定了版本时只使用该版本的公网链接，否则获取最新可用版本的链接
+func (cd *ChromeDownloader) getDownloadUrls() ([]string, error) {
+	platform := cd.platformDetector.GetPlatform()
+	chromePlatform := platform.ChromePlatform
+	if !cd.platformDetector.IsSupported() {
+		chromePlatform = cd.getFallbackPlatform(platform)
+	}
+
+	var urls []string
+	if cd.sources.MirrorBase != "" {
+		mirrorUrls, err := cd.getMirrorUrls(chromePlatform)
+		if err != nil {
+			if cd.sources.Strict {
+				return nil, err
+			}
+			log.Printf("[CHROME-DOWNLOADER] 内部镜像不可用，使用公网下载源: %v", err)
+		}
+		urls = append(urls, mirrorUrls...)
+	}
+	if cd.sources.Strict {
+		if len(urls) == 0 {
+			return nil, fmt.Errorf("严格离线模式需要配置CHROME_ARCHIVE_PATH或CHROME_MIRROR_BASE")
+		}
+		return urls, nil
+	}
+
+	if cd.version == "" || cd.version == "stable" {
+		publicUrls, err := cd.getWorkingChromeUrls()
+		if err != nil && len(urls) == 0 {
+			return nil, err
+		}
+		return append(urls, publicUrls...), nil
+	}
+
+	log.Printf("[CHROME-DOWNLOADER] 使用指定版本: %s，平台: %s", cd.version, chromePlatform)
+	return append(urls, cd.getVersionUrls(cd.version, chromePlatform)...), nil
+}
+
+// getVersionUrls 获取指定版本的下载链接：版本号对应Chrome for Testing发布，纯数字对应Chromium快照
+func (cd *ChromeDownloader) getVersionUrls(version, platform string) []string {
+	if revisionPattern.MatchString(version) {
+		urls := cd.getSnapshotMirrorUrls(platform, version)
+		basePath := strings.TrimPrefix(urls[0], "https://registry.npmmirror.com/-/binary/")
+		return append([]string{fmt.Sprintf("https://commondatastorage.googleapis.com/%s", basePath)}, urls...)
+	}
+
+	suffix := fmt.Sprintf("%s/%s/chrome-%s.zip", version, platform, platform)
+	return []string{
+		fmt.Sprintf("https://storage.googleapis.com/chrome-for-testing-public/%s", suffix),
+		fmt.Sprintf("https://registry.npmmirror.com/-/binary/chrome-for-testing/%s", suffix),
+	}
+}
+
 // getStaticBackupUrls 获取静态备用下载源
 func (cd *ChromeDownloader) getStaticBackupUrls(platform string) []string {
 	// 固定版本的备用源
@@ -0,0 +1,340 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome安装记录与校验 - 下载时记录压缩包和解压文件的SHA-256，校验时比对文件，按清单或上游校验和确认压缩包并检查平台代码签名
+ */
+package utils
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// installRecordFile 安装记录文件名，位于下载目录下
+const installRecordFile = "chrome_install.json"
+
+// signatureCheckTimeout 代码签名校验超时；versionCheckTimeout 读取Chrome版本超时
+const (
+	signatureCheckTimeout = 2 * time.Minute
+	versionCheckTimeout   = 30 * time.Second
+	upstreamCheckTimeout  = 15 * time.Second // 查询上游校验和的超时
+)
+
+// 代码签名校验结果
+const (
+	SignatureValid    = "valid"    // 签名有效
+	SignatureUnsigned = "unsigned" // 没有签名（如Chromium快照构建）
+	SignatureSkipped  = "skipped"  // 平台没有代码签名机制（Linux）
+)
+
+// versionPattern Chrome for Testing版本号；revisionPattern Chromium快照修订号
+var (
+	versionPattern  = regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+$`)
+	revisionPattern = regexp.MustCompile(`^\d{6,}$`)
+	urlVersionRegex = regexp.MustCompile(`/(\d+\.\d+\.\d+\.\d+|\d{6,})/`)
+
+	chromeVersionRegex = regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`)
+	plistVersionRegex  = regexp.MustCompile(`<key>CFBundleShortVersionString</key>\s*<string>([^<]+)</string>`)
+)
+
+// ChromeInstallRecord 下载安装的Chrome记录，用于校验解压后的文件是否被修改或损坏
+type ChromeInstallRecord struct {
+	Version       string            `json:"version,omitempty"`  // 从解压出的Chrome读取的版本号
+	Revision      string            `json:"revision,omitempty"` // Chromium快照修订号，来自下载链接
+	Platform      string            `json:"platform"`
+	SourceURL     string            `json:"source_url"`
+	ArchiveSHA256 string            `json:"archive_sha256"`
+	ArchiveMD5    string            `json:"archive_md5,omitempty"` // Base64编码，与Google Cloud Storage的x-goog-hash比对
+	InstalledAt   time.Time         `json:"installed_at"`
+	Files         map[string]string `json:"files"` // 相对下载目录的路径（/分隔） -> SHA-256
+}
+
+// ChromeVerifyResult Chrome安装校验结果
+type ChromeVerifyResult struct {
+	Record     *ChromeInstallRecord
+	Executable string
+	Checked    int      // 已校验的文件数
+	Missing    []string // 记录中存在但已被删除的文件
+	Modified   []string // 内容与记录不一致的文件
+	Signature  string   // valid/unsigned/skipped
+
+	ManifestChecked bool // 压缩包SHA-256已与CHROME_SHA256_MANIFEST清单比对
+	UpstreamChecked bool // 压缩包MD5已与下载源公布的x-goog-hash比对
+}
+
+// Trusted 安装是否经过可信来源确认：压缩包与固定清单或上游校验和一致，或可执行文件有有效的代码签名
+// 否则文件只与首次安装时写入的记录一致，记录本身可能连同文件一起被替换
+func (r *ChromeVerifyResult) Trusted() bool {
+	return r.ManifestChecked || r.UpstreamChecked || r.Signature == SignatureValid
+}
+
+// versionFromURL 从下载链接中提取版本号或快照修订号
+func versionFromURL(downloadUrl string) string {
+	if match := urlVersionRegex.FindStringSubmatch(downloadUrl); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// readChromeVersion 从解压出的Chrome读取版本号
+// Windows发行包在chrome.exe旁有以版本号命名的目录，macOS读取.app包的Info.plist，其余平台执行 --version
+func readChromeVersion(execPath string) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		entries, err := os.ReadDir(filepath.Dir(execPath))
+		if err != nil {
+			return "", err
+		}
+		var versions []string
+		for _, entry := range entries {
+			if entry.IsDir() && versionPattern.MatchString(entry.Name()) {
+				versions = append(versions, entry.Name())
+			}
+		}
+		if len(versions) != 1 {
+			return "", fmt.Errorf("%s 旁应有且只有一个版本目录，实际 %d 个", execPath, len(versions))
+		}
+		return versions[0], nil
+	case "darwin":
+		plist, err := os.ReadFile(filepath.Join(appBundle(execPath), "Contents", "Info.plist"))
+		if err != nil {
+			return "", err
+		}
+		if match := plistVersionRegex.FindSubmatch(plist); match != nil && versionPattern.Match(match[1]) {
+			return string(match[1]), nil
+		}
+		return "", fmt.Errorf("Info.plist中没有有效的版本号")
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), versionCheckTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, execPath, "--version").Output()
+		if err != nil {
+			return "", fmt.Errorf("执行 %s --version 失败: %w", execPath, err)
+		}
+		if version := chromeVersionRegex.FindString(string(out)); version != "" {
+			return version, nil
+		}
+		return "", fmt.Errorf("无法从输出中解析版本号: %q", strings.TrimSpace(string(out)))
+	}
+}
+
+// appBundle macOS可执行文件所在的.app包，不在.app包中时返回可执行文件本身
+func appBundle(execPath string) string {
+	bundle := execPath
+	for bundle != filepath.Dir(bundle) && !strings.HasSuffix(bundle, ".app") {
+		bundle = filepath.Dir(bundle)
+	}
+	if !strings.HasSuffix(bundle, ".app") {
+		return execPath
+	}
+	return bundle
+}
+
+// archiveDigests 计算压缩包的SHA-256（十六进制）和MD5（Base64），只读取一遍文件
+func archiveDigests(path string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	sha, sum := sha256.New(), md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha, sum), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(sha.Sum(nil)), base64.StdEncoding.EncodeToString(sum.Sum(nil)), nil
+}
+
+// fetchUpstreamMD5 读取下载源对压缩包公布的MD5，Google Cloud Storage在x-goog-hash响应头中返回（如 crc32c=...,md5=...）
+func fetchUpstreamMD5(source string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, source, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+
+	for _, value := range resp.Header.Values("X-Goog-Hash") {
+		for _, part := range strings.Split(value, ",") {
+			if sum, ok := strings.CutPrefix(strings.TrimSpace(part), "md5="); ok {
+				return sum, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("下载源没有公布MD5校验和")
+}
+
+// checkUpstreamChecksum 按下载源公布的校验和确认记录中的压缩包，来源不是HTTP链接、严格模式下的公网地址或没有记录MD5时跳过
+func (cd *ChromeDownloader) checkUpstreamChecksum(record *ChromeInstallRecord) (bool, error) {
+	u, err := url.Parse(record.SourceURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || record.ArchiveMD5 == "" {
+		return false, nil
+	}
+	if cd.sources.Strict && !cd.isMirrorURL(record.SourceURL) {
+		return false, nil
+	}
+
+	upstream, err := fetchUpstreamMD5(record.SourceURL)
+	if err != nil {
+		log.Printf("[CHROME-DOWNLOADER] 无法获取上游校验和: %v", err)
+		return false, nil
+	}
+	if upstream != record.ArchiveMD5 {
+		return false, fmt.Errorf("下载源公布的MD5为 %s，记录为 %s", upstream, record.ArchiveMD5)
+	}
+	return true, nil
+}
+
+// fileSHA256 计算文件的SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readInstallRecord 读取安装记录
+func (cd *ChromeDownloader) readInstallRecord() (*ChromeInstallRecord, error) {
+	data, err := os.ReadFile(filepath.Join(cd.downloadDir, installRecordFile))
+	if err != nil {
+		return nil, err
+	}
+	var record ChromeInstallRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析安装记录失败: %w", err)
+	}
+	return &record, nil
+}
+
+// writeInstallRecord 写入安装记录
+func (cd *ChromeDownloader) writeInstallRecord(record *ChromeInstallRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(cd.downloadDir, installRecordFile), data, 0644)
+}
+
+// VerifyChrome 按安装记录校验解压后的文件，并检查Chrome可执行文件的平台代码签名
+// 配置了CHROME_SHA256_MANIFEST时先按清单比对记录中的压缩包校验和，否则向下载源查询公布的校验和
+// 文件缺失、内容不一致、与清单或上游不一致、签名无效时返回错误；没有可信来源确认时只记录在结果中，由调用方通过Trusted判断
+func (cd *ChromeDownloader) VerifyChrome() (*ChromeVerifyResult, error) {
+	record, err := cd.readInstallRecord()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("未找到安装记录 %s，请先通过 whosee chrome download 下载Chrome", filepath.Join(cd.downloadDir, installRecordFile))
+		}
+		return nil, err
+	}
+
+	result := &ChromeVerifyResult{Record: record}
+
+	// 安装记录中的压缩包校验和可能在安装后被改写，配置了清单时重新比对
+	if cd.sources.ManifestPath != "" {
+		if err := cd.checkPinnedChecksum(record.SourceURL, record.ArchiveSHA256); err != nil {
+			return result, fmt.Errorf("安装记录与SHA-256清单不一致: %w", err)
+		}
+		result.ManifestChecked = true
+	} else if result.UpstreamChecked, err = cd.checkUpstreamChecksum(record); err != nil {
+		return result, fmt.Errorf("安装记录与上游校验和不一致: %w", err)
+	}
+
+	names := make([]string, 0, len(record.Files))
+	for name := range record.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sum, err := fileSHA256(filepath.Join(cd.downloadDir, filepath.FromSlash(name)))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Missing = append(result.Missing, name)
+		case err != nil:
+			return result, fmt.Errorf("读取文件失败 %s: %w", name, err)
+		case sum != record.Files[name]:
+			result.Modified = append(result.Modified, name)
+		}
+		result.Checked++
+	}
+	if len(result.Missing) > 0 || len(result.Modified) > 0 {
+		return result, fmt.Errorf("校验失败: %d 个文件缺失，%d 个文件被修改", len(result.Missing), len(result.Modified))
+	}
+
+	cd.chromeExecutable = ""
+	result.Executable = cd.GetChromeExecutablePath()
+	result.Signature, err = verifyCodeSignature(result.Executable)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// verifyCodeSignature 检查Chrome可执行文件的平台代码签名
+// macOS使用codesign校验.app包，Windows检查Authenticode签名；Linux发行的压缩包没有代码签名，返回skipped
+func verifyCodeSignature(execPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), signatureCheckTimeout)
+	defer cancel()
+
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.CommandContext(ctx, "codesign", "--verify", "--deep", "--strict", appBundle(execPath)).CombinedOutput()
+		if err == nil {
+			return SignatureValid, nil
+		}
+		if strings.Contains(string(out), "not signed at all") {
+			return SignatureUnsigned, nil
+		}
+		return "", fmt.Errorf("代码签名无效: %s", strings.TrimSpace(string(out)))
+	case "windows":
+		script := fmt.Sprintf("(Get-AuthenticodeSignature -LiteralPath '%s').Status", strings.ReplaceAll(execPath, "'", "''"))
+		out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("检查Authenticode签名失败: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		switch status := strings.TrimSpace(string(out)); status {
+		case "Valid":
+			return SignatureValid, nil
+		case "NotSigned":
+			return SignatureUnsigned, nil
+		default:
+			return "", fmt.Errorf("Authenticode签名无效: %s", status)
+		}
+	default:
+		return SignatureSkipped, nil
+	}
+}
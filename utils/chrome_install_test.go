@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDownloader(t *testing.T) *ChromeDownloader {
+	return &ChromeDownloader{
+		downloadDir:      t.TempDir(),
+		version:          "stable",
+		platformDetector: GetGlobalPlatformDetector(),
+	}
+}
+
+// writeTestArchive 生成模拟的Chrome压缩包
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVerifyChrome 测试解压时记录的校验和能发现被修改和删除的文件
+func TestVerifyChrome(t *testing.T) {
+	cd := newTestDownloader(t)
+	if _, err := cd.VerifyChrome(); err == nil {
+		t.Error("没有安装记录时应校验失败")
+	}
+
+	archive := filepath.Join(cd.downloadDir, "chrome.zip")
+	writeTestArchive(t, archive, map[string]string{
+		"chrome-linux64/chrome":         "binary",
+		"chrome-linux64/libEGL.so":      "lib",
+		"chrome-linux64/locales/en.pak": "pak",
+	})
+	files, err := cd.extractZip(archive, cd.downloadDir)
+	if err != nil {
+		t.Fatalf("解压失败: %v", err)
+	}
+	if len(files) != 3 || files["chrome-linux64/locales/en.pak"] == "" {
+		t.Fatalf("解压文件的校验和记录不正确: %v", files)
+	}
+	archiveSum, _ := fileSHA256(archive)
+	record := &ChromeInstallRecord{
+		Version:       versionFromURL("https://storage.googleapis.com/chrome-for-testing-public/131.0.6778.108/linux64/chrome-linux64.zip"),
+		SourceURL:     "https://example.com/chrome-linux64.zip",
+		ArchiveSHA256: archiveSum,
+		InstalledAt:   time.Now(),
+		Files:         files,
+	}
+	if err := cd.writeInstallRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cd.VerifyChrome()
+	if err != nil || result.Checked != 3 || result.Record.Version != "131.0.6778.108" {
+		t.Fatalf("未修改的安装应校验通过: %+v, %v", result, err)
+	}
+
+	os.WriteFile(filepath.Join(cd.downloadDir, "chrome-linux64", "libEGL.so"), []byte("patched"), 0644)
+	os.Remove(filepath.Join(cd.downloadDir, "chrome-linux64", "locales", "en.pak"))
+	result, err = cd.VerifyChrome()
+	if err == nil {
+		t.Fatal("文件被修改后应校验失败")
+	}
+	if len(result.Modified) != 1 || result.Modified[0] != "chrome-linux64/libEGL.so" ||
+		len(result.Missing) != 1 || result.Missing[0] != "chrome-linux64/locales/en.pak" {
+		t.Errorf("校验结果不正确: %+v", result)
+	}
+}
+
+// TestCleanupOldVersions 测试只保留当前安装的目录和安装记录
+func TestCleanupOldVersions(t *testing.T) {
+	cd := newTestDownloader(t)
+	for _, dir := range []string{"chrome-linux64", "chrome-linux", "notes"} {
+		os.MkdirAll(filepath.Join(cd.downloadDir, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(cd.downloadDir, "chrome.zip"), []byte("partial"), 0644)
+
+	// 没有安装记录时只清理残留的压缩包
+	removed, err := cd.CleanupOldVersions()
+	if err != nil || len(removed) != 1 || !strings.HasSuffix(removed[0], "chrome.zip") {
+		t.Fatalf("无法确定当前安装时只应删除压缩包: %v, %v", removed, err)
+	}
+
+	cd.writeInstallRecord(&ChromeInstallRecord{Files: map[string]string{"chrome-linux64/chrome": "x"}})
+	if removed, err = cd.CleanupOldVersions(); err != nil || len(removed) != 2 {
+		t.Fatalf("应删除当前安装以外的目录: %v, %v", removed, err)
+	}
+	for _, name := range []string{"chrome-linux64", installRecordFile} {
+		if _, err := os.Stat(filepath.Join(cd.downloadDir, name)); err != nil {
+			t.Errorf("%s 不应被删除", name)
+		}
+	}
+}
+
+// TestSetVersion 测试版本号校验和指定版本的下载链接
+func TestSetVersion(t *testing.T) {
+	cd := newTestDownloader(t)
+	for _, version := range []string{"131.0.6778.108", "1354089", "stable", ""} {
+		if err := cd.SetVersion(version); err != nil {
+			t.Errorf("%q 应为有效版本: %v", version, err)
+		}
+	}
+	for _, version := range []string{"131", "latest", "131.0.6778.108/../x"} {
+		if err := cd.SetVersion(version); err == nil {
+			t.Errorf("%q 应为无效版本", version)
+		}
+	}
+
+	urls := cd.getVersionUrls("131.0.6778.108", "linux64")
+	if urls[0] != "https://storage.googleapis.com/chrome-for-testing-public/131.0.6778.108/linux64/chrome-linux64.zip" {
+		t.Errorf("Chrome for Testing链接不正确: %v", urls)
+	}
+	urls = cd.getVersionUrls("1354089", "linux64")
+	if urls[0] != "https://commondatastorage.googleapis.com/chromium-browser-snapshots/Linux_x64/1354089/chrome-linux.zip" {
+		t.Errorf("快照链接不正确: %v", urls)
+	}
+	if versionFromURL(urls[0]) != "1354089" {
+		t.Errorf("应从快照链接提取修订号")
+	}
+}
+
+// TestVerifyChromeUpstream 测试按下载源公布的x-goog-hash确认压缩包，没有可信来源时不视为可信
+func TestVerifyChromeUpstream(t *testing.T) {
+	cd := newTestDownloader(t)
+	archive := filepath.Join(cd.downloadDir, "chrome.zip")
+	writeTestArchive(t, archive, map[string]string{"chrome-linux64/chrome": "binary"})
+	files, err := cd.extractZip(archive, cd.downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveSum, archiveMD5, err := archiveDigests(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published := archiveMD5
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if published != "" {
+			w.Header().Set("X-Goog-Hash", "crc32c=AAAAAA==,md5="+published)
+		}
+	}))
+	defer server.Close()
+
+	cd.writeInstallRecord(&ChromeInstallRecord{
+		SourceURL:     server.URL + "/131.0.6778.108/linux64/chrome-linux64.zip",
+		ArchiveSHA256: archiveSum,
+		ArchiveMD5:    archiveMD5,
+		Files:         files,
+	})
+	result, err := cd.VerifyChrome()
+	if err != nil || !result.UpstreamChecked || !result.Trusted() {
+		t.Fatalf("与上游校验和一致时应校验通过: %+v, %v", result, err)
+	}
+
+	published = "1B2M2Y8AsgTpgAmY7PhCfg=="
+	if _, err := cd.VerifyChrome(); err == nil || !strings.Contains(err.Error(), "上游") {
+		t.Errorf("与上游校验和不一致时应校验失败: %v", err)
+	}
+
+	published = ""
+	result, err = cd.VerifyChrome()
+	if err != nil || result.UpstreamChecked || (result.Signature != SignatureValid && result.Trusted()) {
+		t.Errorf("下载源没有公布校验和时只能首次信任: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: Chrome离线安装 - 本地压缩包、内部HTTP镜像、固定版本和SHA-256清单校验，严格模式下不访问公网
+ */
+package utils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChromeSourceConfig Chrome安装来源配置
+type ChromeSourceConfig struct {
+	ArchivePath  string // 本地Chrome压缩包，设置后直接从该文件安装
+	MirrorBase   string // 内部HTTP镜像地址，目录结构与Chrome for Testing相同：<base>/<版本>/<平台>/chrome-<平台>.zip
+	ManifestPath string // SHA-256清单，设置后压缩包必须有匹配的条目且校验和一致
+	Strict       bool   // 严格模式：只从本地压缩包或内部镜像安装，不访问任何公网地址
+}
+
+// loadChromeSourceConfig 从环境变量读取安装来源配置
+func loadChromeSourceConfig() ChromeSourceConfig {
+	return ChromeSourceConfig{
+		ArchivePath:  strings.TrimSpace(os.Getenv("CHROME_ARCHIVE_PATH")),
+		MirrorBase:   strings.TrimRight(strings.TrimSpace(os.Getenv("CHROME_MIRROR_BASE")), "/"),
+		ManifestPath: strings.TrimSpace(os.Getenv("CHROME_SHA256_MANIFEST")),
+		Strict:       os.Getenv("CHROME_OFFLINE_STRICT") == "true",
+	}
+}
+
+// isMirrorURL 是否为内部镜像上的链接
+func (cd *ChromeDownloader) isMirrorURL(downloadUrl string) bool {
+	return cd.sources.MirrorBase != "" && strings.HasPrefix(downloadUrl, cd.sources.MirrorBase+"/")
+}
+
+// getMirrorUrls 获取内部镜像上的下载链接，未指定版本时读取镜像的LATEST_RELEASE_STABLE
+func (cd *ChromeDownloader) getMirrorUrls(platform string) ([]string, error) {
+	version := cd.version
+	if version == "" || version == "stable" {
+		latest, err := cd.fetchMirrorLatest()
+		if err != nil {
+			return nil, fmt.Errorf("读取镜像最新版本失败，请设置CHROME_VERSION: %w", err)
+		}
+		version = latest
+	}
+	if !versionPattern.MatchString(version) {
+		return nil, fmt.Errorf("内部镜像只支持Chrome for Testing版本号: %s", version)
+	}
+	return []string{fmt.Sprintf("%s/%s/%s/chrome-%s.zip", cd.sources.MirrorBase, version, platform, platform)}, nil
+}
+
+// fetchMirrorLatest 读取镜像根目录的LATEST_RELEASE_STABLE（与Chrome for Testing发布的文件格式相同）
+func (cd *ChromeDownloader) fetchMirrorLatest() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", cd.sources.MirrorBase+"/LATEST_RELEASE_STABLE", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(string(body))
+	if !versionPattern.MatchString(version) {
+		return "", fmt.Errorf("无效的版本号: %q", version)
+	}
+	log.Printf("[CHROME-DOWNLOADER] 内部镜像最新版本: %s", version)
+	return version, nil
+}
+
+// checksumManifest SHA-256清单：路径 -> 校验和
+type checksumManifest map[string]string
+
+// loadChecksumManifest 读取sha256sum格式的清单，每行"<sha256>  <路径>"，#开头为注释
+// 路径是压缩包相对镜像根目录的位置，如 131.0.6778.108/linux64/chrome-linux64.zip
+func loadChecksumManifest(path string) (checksumManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取SHA-256清单失败: %w", err)
+	}
+	defer f.Close()
+
+	manifest := make(checksumManifest)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			return nil, fmt.Errorf("SHA-256清单第%d行格式无效", line)
+		}
+		// sha256sum二进制模式在文件名前加*
+		manifest[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// lookup 查找压缩包的校验和：条目路径与来源相同，或是来源路径以/分隔的后缀，多个条目匹配时取最长的
+func (m checksumManifest) lookup(source string) (string, bool) {
+	name := filepath.ToSlash(source)
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		name = u.Path
+	}
+
+	var matched, sum string
+	for entry, entrySum := range m {
+		if (name == entry || strings.HasSuffix(name, "/"+entry)) && len(entry) > len(matched) {
+			matched, sum = entry, entrySum
+		}
+	}
+	return sum, matched != ""
+}
+
+// checkPinnedChecksum 按SHA-256清单校验压缩包，未配置清单时不校验
+func (cd *ChromeDownloader) checkPinnedChecksum(source, sum string) error {
+	if cd.sources.ManifestPath == "" {
+		return nil
+	}
+	manifest, err := loadChecksumManifest(cd.sources.ManifestPath)
+	if err != nil {
+		return err
+	}
+	expected, ok := manifest.lookup(source)
+	if !ok {
+		return fmt.Errorf("SHA-256清单中没有 %s 的条目", source)
+	}
+	if expected != sum {
+		return fmt.Errorf("压缩包SHA-256不匹配: 期望 %s，实际 %s", expected, sum)
+	}
+	log.Printf("[CHROME-DOWNLOADER] 压缩包SHA-256与清单一致: %s", sum)
+	return nil
+}
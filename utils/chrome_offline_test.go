@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestChecksumManifest 测试sha256sum格式清单的解析和按路径后缀匹配
+func TestChecksumManifest(t *testing.T) {
+	sumA, sumB := strings.Repeat("a", 64), strings.Repeat("B", 64)
+	path := filepath.Join(t.TempDir(), "SHA256SUMS")
+	os.WriteFile(path, []byte(fmt.Sprintf("# Chrome压缩包\n%s  chrome-linux64.zip\n%s *131.0.6778.108/linux64/chrome-linux64.zip\n", sumA, sumB)), 0644)
+
+	manifest, err := loadChecksumManifest(path)
+	if err != nil {
+		t.Fatalf("解析清单失败: %v", err)
+	}
+	cases := map[string]string{
+		"https://mirror.internal/chrome/131.0.6778.108/linux64/chrome-linux64.zip": strings.ToLower(sumB), // 取最长的匹配
+		"https://mirror.internal/chrome/130.0.6723.58/linux64/chrome-linux64.zip":  sumA,
+		"/opt/images/chrome-linux64.zip":                                           sumA,
+	}
+	for source, want := range cases {
+		if got, ok := manifest.lookup(source); !ok || got != want {
+			t.Errorf("%s: got %q, want %q", source, got, want)
+		}
+	}
+	for _, source := range []string{"/opt/images/other-chrome-linux64.zip", "https://mirror.internal/chrome-mac-x64.zip"} {
+		if _, ok := manifest.lookup(source); ok {
+			t.Errorf("%s 不应匹配任何条目", source)
+		}
+	}
+
+	os.WriteFile(path, []byte("not-a-checksum chrome.zip\n"), 0644)
+	if _, err := loadChecksumManifest(path); err == nil {
+		t.Error("格式无效的清单应解析失败")
+	}
+}
+
+// TestInstallFromLocalArchive 测试从本地压缩包安装，并按清单校验压缩包
+func TestInstallFromLocalArchive(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("模拟的压缩包只包含Linux目录结构")
+	}
+	archive := filepath.Join(t.TempDir(), "chrome-linux64.zip")
+	writeTestArchive(t, archive, map[string]string{
+		"chrome-linux64/chrome":    "#!/bin/sh\necho Google Chrome for Testing 131.0.6778.108\nexit 0\n#" + strings.Repeat("x", 2<<20),
+		"chrome-linux64/libEGL.so": "lib",
+	})
+	sum, _ := fileSHA256(archive)
+	manifest := filepath.Join(t.TempDir(), "SHA256SUMS")
+
+	install := func(manifestContent string) (*ChromeDownloader, error) {
+		os.WriteFile(manifest, []byte(manifestContent), 0644)
+		cd := newTestDownloader(t)
+		cd.sources = ChromeSourceConfig{ArchivePath: archive, ManifestPath: manifest, Strict: true}
+		return cd, cd.DownloadChrome()
+	}
+
+	cd := newTestDownloader(t)
+	cd.version = "130.0.6723.58"
+	cd.sources = ChromeSourceConfig{ArchivePath: archive, Strict: true}
+	if err := cd.DownloadChrome(); err == nil || !strings.Contains(err.Error(), "与指定版本") {
+		t.Errorf("压缩包中的版本与指定版本不一致时应拒绝安装: %v", err)
+	}
+
+	if _, err := install(strings.Repeat("0", 64) + "  chrome-linux64.zip\n"); err == nil || !strings.Contains(err.Error(), "不匹配") {
+		t.Errorf("校验和不一致时应拒绝安装: %v", err)
+	}
+	if _, err := install(sum + "  chrome-mac-x64.zip\n"); err == nil {
+		t.Error("清单中没有对应条目时应拒绝安装")
+	}
+
+	cd, err := install(sum + "  chrome-linux64.zip\n")
+	if err != nil {
+		t.Fatalf("从本地压缩包安装失败: %v", err)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Error("本地压缩包不应被删除")
+	}
+	record, err := cd.readInstallRecord()
+	if err != nil || record.ArchiveSHA256 != sum || record.SourceURL != archive || len(record.Files) != 2 || record.Version != "131.0.6778.108" {
+		t.Errorf("安装记录不正确: %+v, %v", record, err)
+	}
+
+	// 校验时按清单重新比对记录中的压缩包校验和
+	if result, err := cd.VerifyChrome(); err != nil || !result.ManifestChecked {
+		t.Errorf("与清单一致的安装应校验通过: %+v, %v", result, err)
+	}
+	record.ArchiveSHA256 = strings.Repeat("0", 64)
+	cd.writeInstallRecord(record)
+	if _, err := cd.VerifyChrome(); err == nil || !strings.Contains(err.Error(), "清单") {
+		t.Errorf("安装记录被改写后应校验失败: %v", err)
+	}
+}
+
+// TestStrictModeSources 测试严格模式只使用内部镜像
+func TestStrictModeSources(t *testing.T) {
+	cd := newTestDownloader(t)
+	cd.sources = ChromeSourceConfig{Strict: true}
+	if _, err := cd.getDownloadUrls(); err == nil {
+		t.Error("严格模式下未配置本地压缩包或内部镜像时应报错")
+	}
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chrome/LATEST_RELEASE_STABLE" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "131.0.6778.108")
+	}))
+	defer mirror.Close()
+
+	cd.sources.MirrorBase = mirror.URL + "/chrome"
+	platform := cd.platformDetector.GetPlatform().ChromePlatform
+	urls, err := cd.getDownloadUrls()
+	want := fmt.Sprintf("%s/chrome/131.0.6778.108/%s/chrome-%s.zip", mirror.URL, platform, platform)
+	if err != nil || len(urls) != 1 || urls[0] != want {
+		t.Errorf("严格模式应只返回内部镜像的最新版本: %v, %v", urls, err)
+	}
+	if !cd.isMirrorURL(urls[0]) || cd.isMirrorURL("https://storage.googleapis.com/chrome.zip") {
+		t.Error("镜像链接识别不正确")
+	}
+
+	cd.SetVersion("130.0.6723.58")
+	if urls, err := cd.getDownloadUrls(); err != nil || !strings.Contains(urls[0], "/130.0.6723.58/") {
+		t.Errorf("指定版本时应使用该版本: %v, %v", urls, err)
+	}
+	cd.SetVersion("1354089")
+	if _, err := cd.getDownloadUrls(); err == nil {
+		t.Error("内部镜像不支持快照修订号")
+	}
+}
@@ -8,6 +8,8 @@ package utils
 import (
 	"crypto/md5"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -71,25 +73,28 @@ func GenerateSecureFilename(input string) string {
 	return safe
 }
 
-// ValidateURL 验证URL是否安全
-func ValidateURL(url string) bool {
-	// 检查是否包含危险的协议
-	dangerousProtocols := []string{"file://", "ftp://", "javascript:", "data:"}
-	urlLower := strings.ToLower(url)
-
-	for _, protocol := range dangerousProtocols {
-		if strings.HasPrefix(urlLower, protocol) {
-			return false
-		}
+// ValidateURL 验证URL是否安全：只允许http/https，主机不能是localhost，IP地址按出站策略检查
+// 域名在连接时才解析，解析结果由出站策略（Dialer.Control或浏览器请求拦截）检查
+func ValidateURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return false
 	}
 
-	// 检查是否包含本地地址
-	localAddresses := []string{"localhost", "127.0.0.1", "0.0.0.0", "::1", "10.", "192.168.", "172."}
-	for _, addr := range localAddresses {
-		if strings.Contains(urlLower, addr) {
-			return false
-		}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return GetNetworkPolicy().AllowAddr(addr)
 	}
 
-	return true
+	// 最后一段是数字的主机名会被浏览器按IPv4解析（如2130706433、0x7f.1），无法按域名检查，直接拒绝
+	labels := strings.Split(host, ".")
+	return !numericHostLabel.MatchString(labels[len(labels)-1])
 }
+
+var numericHostLabel = regexp.MustCompile(`^(0x[0-9a-f]*|[0-9]+)$`)
@@ -0,0 +1,164 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 数据导出工具 - 根据Accept头返回CSV或NDJSON，逐条写出并立即刷新
+ */
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出格式的MIME类型
+const (
+	MIMECSV    = "text/csv"
+	MIMENDJSON = "application/x-ndjson"
+)
+
+// ExportFormat 导出格式
+type ExportFormat string
+
+const (
+	ExportJSON   ExportFormat = "json"
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+// exportOffers 支持的导出格式，q值相同时按Accept中出现的顺序，其次按此顺序选择
+var exportOffers = []struct {
+	mime   string
+	format ExportFormat
+}{
+	{gin.MIMEJSON, ExportJSON},
+	{MIMECSV, ExportCSV},
+	{MIMENDJSON, ExportNDJSON},
+	{"application/ndjson", ExportNDJSON},
+}
+
+// NegotiateExportFormat 根据Accept头和q值选择导出格式，未指定或不匹配时使用JSON
+func NegotiateExportFormat(c *gin.Context) ExportFormat {
+	ranges := parseAccept(c.GetHeader("Accept"))
+	best, bestQ, bestPos := ExportJSON, 0.0, len(ranges)
+	for _, offer := range exportOffers {
+		q, pos := acceptQuality(ranges, offer.mime)
+		if q > bestQ || (q > 0 && q == bestQ && pos < bestPos) {
+			best, bestQ, bestPos = offer.format, q, pos
+		}
+	}
+	return best
+}
+
+// acceptRange Accept头中的一项媒体范围
+type acceptRange struct {
+	mime string
+	q    float64
+}
+
+// parseAccept 解析Accept头，q缺省为1，无效的q值视为0（不接受）
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mime: mime, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality 返回最具体的匹配范围（完整类型 > type/* > */*）的q值及其位置，不匹配时q为0
+func acceptQuality(ranges []acceptRange, mime string) (float64, int) {
+	mainType, _, _ := strings.Cut(mime, "/")
+	q, pos, specificity := 0.0, len(ranges), -1
+	for i, r := range ranges {
+		level := -1
+		switch r.mime {
+		case mime:
+			level = 2
+		case mainType + "/*":
+			level = 1
+		case "*/*":
+			level = 0
+		}
+		if level > specificity {
+			q, pos, specificity = r.q, i, level
+		}
+	}
+	return q, pos
+}
+
+// CSVStream 逐行写出CSV，每行写入后立即刷新到客户端
+type CSVStream struct {
+	c      *gin.Context
+	writer *csv.Writer
+}
+
+// NewCSVStream 写出响应头和CSV表头，filename用于下载时的文件名
+func NewCSVStream(c *gin.Context, filename string, header []string) (*CSVStream, error) {
+	c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	stream := &CSVStream{c: c, writer: csv.NewWriter(c.Writer)}
+	if err := stream.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// WriteRow 写出一行并刷新
+func (s *CSVStream) WriteRow(row []string) error {
+	if err := s.writer.Write(row); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	s.c.Writer.Flush()
+	return s.writer.Error()
+}
+
+// NDJSONStream 逐条写出NDJSON记录，每条记录占一行
+type NDJSONStream struct {
+	c       *gin.Context
+	encoder *json.Encoder
+}
+
+// NewNDJSONStream 写出响应头并返回NDJSON写入器
+func NewNDJSONStream(c *gin.Context) *NDJSONStream {
+	c.Header("Content-Type", MIMENDJSON+"; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	encoder.SetEscapeHTML(false)
+	return &NDJSONStream{c: c, encoder: encoder}
+}
+
+// WriteRecord 写出一条记录并刷新，json.Encoder会在每条记录后追加换行
+func (s *NDJSONStream) WriteRecord(record interface{}) error {
+	if err := s.encoder.Encode(record); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
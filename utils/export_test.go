@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestNegotiateExportFormat 测试根据Accept头选择导出格式
+func TestNegotiateExportFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := map[string]ExportFormat{
+		"":                                 ExportJSON,
+		"*/*":                              ExportJSON,
+		"application/json":                 ExportJSON,
+		"text/csv":                         ExportCSV,
+		"text/csv;q=0.9, application/json": ExportJSON,
+		"text/csv, application/json;q=0.5": ExportCSV,
+		"application/json;q=0, text/*":     ExportCSV,
+		"text/csv;q=0, */*;q=0.1":          ExportJSON,
+		"application/x-ndjson, text/csv":   ExportNDJSON,
+		"application/x-ndjson":             ExportNDJSON,
+		"application/ndjson":               ExportNDJSON,
+		"text/html":                        ExportJSON,
+	}
+
+	for accept, want := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		if accept != "" {
+			c.Request.Header.Set("Accept", accept)
+		}
+		if got := NegotiateExportFormat(c); got != want {
+			t.Errorf("Accept %q 协商结果 = %s, 期望 %s", accept, got, want)
+		}
+	}
+}
+
+// TestExportStreams 测试CSV与NDJSON逐条写出
+func TestExportStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	stream, err := NewCSVStream(c, "out.csv", []string{"name", "value"})
+	if err != nil {
+		t.Fatalf("创建CSV写入器失败: %v", err)
+	}
+	if err := stream.WriteRow([]string{"a", "x, y"}); err != nil {
+		t.Fatalf("写出CSV失败: %v", err)
+	}
+	if got := recorder.Body.String(); got != "name,value\na,\"x, y\"\n" {
+		t.Errorf("CSV输出不正确: %q", got)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), MIMECSV) {
+		t.Errorf("CSV Content-Type不正确: %s", recorder.Header().Get("Content-Type"))
+	}
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	ndjson := NewNDJSONStream(c)
+	ndjson.WriteRecord(map[string]string{"name": "a<b"})
+	ndjson.WriteRecord(map[string]int{"n": 1})
+	if got := recorder.Body.String(); got != "{\"name\":\"a<b\"}\n{\"n\":1}\n" {
+		t.Errorf("NDJSON输出不正确: %q", got)
+	}
+}
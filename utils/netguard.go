@@ -0,0 +1,192 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 出站地址策略 - 拒绝内网、回环、链路本地、云元数据和组播地址，防止SSRF访问内部服务
+ */
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ErrAddressBlocked 目标地址被出站策略拒绝
+var ErrAddressBlocked = errors.New("目标地址不允许访问")
+
+// blockedPrefixes 默认拒绝的地址段
+var blockedPrefixes = []string{
+	"0.0.0.0/8",       // 本网络
+	"10.0.0.0/8",      // 私有网络
+	"100.64.0.0/10",   // 运营商级NAT，含阿里云元数据地址100.100.100.200
+	"127.0.0.0/8",     // 回环
+	"169.254.0.0/16",  // 链路本地，含云元数据地址169.254.169.254
+	"172.16.0.0/12",   // 私有网络
+	"192.0.0.0/24",    // IETF协议分配
+	"192.0.2.0/24",    // 文档示例
+	"192.168.0.0/16",  // 私有网络
+	"198.18.0.0/15",   // 基准测试
+	"198.51.100.0/24", // 文档示例
+	"203.0.113.0/24",  // 文档示例
+	"224.0.0.0/4",     // 组播
+	"240.0.0.0/4",     // 保留地址和广播
+	"::/128",          // 未指定地址
+	"::1/128",         // 回环
+	"64:ff9b::/96",    // NAT64，可映射到内网IPv4
+	"64:ff9b:1::/48",  // 本地NAT64
+	"100::/64",        // 丢弃前缀
+	"2001:db8::/32",   // 文档示例
+	"fc00::/7",        // 唯一本地地址，含AWS IPv6元数据地址fd00:ec2::254
+	"fe80::/10",       // 链路本地
+	"ff00::/8",        // 组播
+}
+
+// NetworkPolicy 出站地址策略
+// 检查顺序：拒绝列表 > 允许列表 > 默认拒绝的地址段，允许列表用于放行内网中确需访问的服务
+type NetworkPolicy struct {
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	blocked []netip.Prefix
+}
+
+var (
+	globalNetworkPolicy *NetworkPolicy
+	networkPolicyOnce   sync.Once
+)
+
+// GetNetworkPolicy 获取全局出站地址策略（首次调用时读取OUTBOUND_ALLOW_CIDRS和OUTBOUND_DENY_CIDRS）
+func GetNetworkPolicy() *NetworkPolicy {
+	networkPolicyOnce.Do(func() {
+		globalNetworkPolicy = NewNetworkPolicy(
+			splitCIDRList(os.Getenv("OUTBOUND_ALLOW_CIDRS")),
+			splitCIDRList(os.Getenv("OUTBOUND_DENY_CIDRS")),
+		)
+	})
+	return globalNetworkPolicy
+}
+
+// NewNetworkPolicy 创建出站地址策略，条目可以是CIDR或单个IP，无效条目记录日志后忽略
+func NewNetworkPolicy(allow, deny []string) *NetworkPolicy {
+	p := &NetworkPolicy{
+		allow: parsePrefixes(allow),
+		deny:  parsePrefixes(deny),
+	}
+	for _, cidr := range blockedPrefixes {
+		p.blocked = append(p.blocked, netip.MustParsePrefix(cidr))
+	}
+	return p
+}
+
+func splitCIDRList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func parsePrefixes(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				log.Printf("[NETGUARD] 忽略无效的地址: %s", entry)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			log.Printf("[NETGUARD] 忽略无效的CIDR: %s", entry)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowAddr 检查IP是否允许访问，IPv4映射的IPv6地址按IPv4处理，忽略IPv6区域标识
+func (p *NetworkPolicy) AllowAddr(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsValid() || containsAddr(p.deny, addr) {
+		return false
+	}
+	if containsAddr(p.allow, addr) {
+		return true
+	}
+	return !containsAddr(p.blocked, addr)
+}
+
+// CheckHost 解析主机名并检查全部地址，任一地址被拒绝即拒绝，避免混合记录绕过
+// 主机名为IP字面量时不做解析；解析失败时返回解析错误，调用方应按拒绝处理
+func (p *NetworkPolicy) CheckHost(ctx context.Context, host string) error {
+	_, err := p.ResolveHost(ctx, host)
+	return err
+}
+
+// ResolveHost 解析主机名并返回检查通过的地址，检查规则与CheckHost相同
+// 调用方应直接连接返回的地址，不再重新解析，避免DNS重绑定在检查和连接之间更换地址
+func (p *NetworkPolicy) ResolveHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if host == "" {
+		return nil, fmt.Errorf("%w: 主机名为空", ErrAddressBlocked)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !p.AllowAddr(addr) {
+			return nil, fmt.Errorf("%w: %s", ErrAddressBlocked, host)
+		}
+		return []netip.Addr{addr.Unmap().WithZone("")}, nil
+	}
+	if lower := strings.ToLower(host); lower == "localhost" || strings.HasSuffix(lower, ".localhost") {
+		return nil, fmt.Errorf("%w: %s", ErrAddressBlocked, host)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for i, addr := range addrs {
+		if !p.AllowAddr(addr) {
+			return nil, fmt.Errorf("%w: %s 解析到 %s", ErrAddressBlocked, host, addr)
+		}
+		addrs[i] = addr.Unmap().WithZone("")
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s 没有解析到地址", host)
+	}
+	return addrs, nil
+}
+
+// Control 用作net.Dialer.Control，在建立连接前检查实际连接的IP
+// 检查发生在DNS解析之后，DNS重绑定无法绕过
+func (p *NetworkPolicy) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, address)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !p.AllowAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, host)
+	}
+	return nil
+}
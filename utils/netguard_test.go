@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// TestNetworkPolicyDefaults 测试默认拒绝内网、回环、链路本地、元数据和组播地址
+func TestNetworkPolicyDefaults(t *testing.T) {
+	policy := NewNetworkPolicy(nil, nil)
+	blocked := []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "172.31.255.255", "192.168.1.1",
+		"169.254.169.254", "100.100.100.200", "0.0.0.0", "224.0.0.1", "255.255.255.255",
+		"::1", "fd00:ec2::254", "fe80::1", "fe80::1%eth0", "ff02::1", "::ffff:127.0.0.1", "64:ff9b::a00:1",
+	}
+	for _, ip := range blocked {
+		if policy.AllowAddr(netip.MustParseAddr(ip)) {
+			t.Errorf("%s 应被拒绝", ip)
+		}
+	}
+	allowed := []string{"8.8.8.8", "172.32.0.1", "93.184.216.34", "2606:4700:4700::1111"}
+	for _, ip := range allowed {
+		if !policy.AllowAddr(netip.MustParseAddr(ip)) {
+			t.Errorf("%s 应被允许", ip)
+		}
+	}
+}
+
+// TestNetworkPolicyLists 测试拒绝列表优先于允许列表，允许列表优先于默认规则
+func TestNetworkPolicyLists(t *testing.T) {
+	policy := NewNetworkPolicy([]string{"10.20.0.0/16", "192.168.1.10", "bad-entry"}, []string{"10.20.30.0/24", "8.8.4.4"})
+
+	cases := map[string]bool{
+		"10.20.1.1":    true,  // 允许列表放行内网
+		"192.168.1.10": true,  // 单个IP
+		"192.168.1.11": false, // 不在允许列表
+		"10.20.30.5":   false, // 拒绝列表优先
+		"8.8.4.4":      false, // 拒绝列表可以拒绝公网地址
+		"8.8.8.8":      true,
+	}
+	for ip, want := range cases {
+		if got := policy.AllowAddr(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("%s: got %v, want %v", ip, got, want)
+		}
+	}
+}
+
+// TestNetworkPolicyCheckHost 测试主机名检查
+func TestNetworkPolicyCheckHost(t *testing.T) {
+	policy := NewNetworkPolicy(nil, nil)
+	ctx := context.Background()
+	for _, host := range []string{"localhost", "app.localhost", "127.0.0.1", "[::1]", "169.254.169.254", ""} {
+		if err := policy.CheckHost(ctx, host); !errors.Is(err, ErrAddressBlocked) {
+			t.Errorf("%q 应被拒绝: %v", host, err)
+		}
+	}
+	if err := policy.CheckHost(ctx, "8.8.8.8"); err != nil {
+		t.Errorf("公网地址应被允许: %v", err)
+	}
+}
+
+// TestNetworkPolicyControl 测试通过Dialer.Control在连接前拒绝内网地址
+func TestNetworkPolicyControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("无法监听本地端口: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	dialer := &net.Dialer{Timeout: time.Second, Control: NewNetworkPolicy(nil, nil).Control}
+	if _, err := dialer.Dial("tcp", listener.Addr().String()); !errors.Is(err, ErrAddressBlocked) {
+		t.Errorf("连接回环地址应被拒绝: %v", err)
+	}
+
+	dialer.Control = NewNetworkPolicy([]string{"127.0.0.1"}, nil).Control
+	conn, err := dialer.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("允许列表中的地址应可连接: %v", err)
+	}
+	conn.Close()
+}
+
+// TestValidateURL 测试URL安全检查不再按子串误判
+func TestValidateURL(t *testing.T) {
+	valid := []string{
+		"https://example.com",
+		"http://172.example.com/path",
+		"https://10.example.org",
+		"https://example.com/?next=localhost",
+		"https://8.8.8.8/",
+	}
+	for _, u := range valid {
+		if !ValidateURL(u) {
+			t.Errorf("%s 应校验通过", u)
+		}
+	}
+
+	invalid := []string{
+		"file:///etc/passwd",
+		"javascript:alert(1)",
+		"ftp://example.com",
+		"chrome://settings",
+		"example.com",
+		"http://localhost:8080",
+		"http://LOCALHOST.",
+		"http://admin.localhost",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://[fd00:ec2::254]/",
+		"http://2130706433/",
+		"http://0x7f.1/",
+		"http://10.0.0.1/",
+	}
+	for _, u := range invalid {
+		if ValidateURL(u) {
+			t.Errorf("%s 应校验失败", u)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+/*
+ * @Author: AsisYu
+ * @Date: 2026-10-18
+ * @Description: 公共后缀列表(Public Suffix List)解析与可注册域名计算
+ */
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// 内置的公共后缀列表快照，保证离线环境也能正确拆分域名
+//
+//go:embed data/public_suffix_list.dat
+var embeddedPublicSuffixList []byte
+
+const (
+	// DefaultPublicSuffixListURL 官方公共后缀列表地址
+	DefaultPublicSuffixListURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+	// 刷新下载的最大体积，防止异常响应耗尽内存
+	maxPublicSuffixListSize = 4 * 1024 * 1024
+	// 刷新结果的最少规则数，低于此值视为下载内容异常
+	minPublicSuffixRules = 1000
+)
+
+// suffixRule 单条规则，icann标识规则是否来自ICANN段（否则为私有段）
+type suffixRule struct {
+	icann bool
+}
+
+// PublicSuffixList 公共后缀列表
+type PublicSuffixList struct {
+	mu         sync.RWMutex
+	rules      map[string]suffixRule // 普通规则，如 co.uk
+	wildcards  map[string]suffixRule // 通配规则，如 *.ck 存储为 ck
+	exceptions map[string]suffixRule // 例外规则，如 !www.ck 存储为 www.ck
+	source     string
+	loadedAt   time.Time
+}
+
+// DomainParts 域名拆分结果
+type DomainParts struct {
+	Domain            string `json:"domain"`            // 清理后的完整主机名
+	Subdomain         string `json:"subdomain"`         // 子域名部分，如 www
+	RegistrableDomain string `json:"registrableDomain"` // 可注册域名，如 example.co.uk
+	EffectiveTLD      string `json:"effectiveTLD"`      // 有效顶级域名，如 co.uk
+	ICANN             bool   `json:"icann"`             // 有效顶级域名是否由ICANN段规则命中
+}
+
+var (
+	globalPublicSuffixList *PublicSuffixList
+	publicSuffixListOnce   sync.Once
+)
+
+// GetPublicSuffixList 获取全局公共后缀列表（首次调用时加载内置快照）
+func GetPublicSuffixList() *PublicSuffixList {
+	publicSuffixListOnce.Do(func() {
+		list, err := ParsePublicSuffixList(bytes.NewReader(embeddedPublicSuffixList), "embedded")
+		if err != nil {
+			// 内置快照随代码发布，解析失败说明构建产物损坏
+			log.Printf("[PSL] 解析内置公共后缀列表失败: %v", err)
+			list = newPublicSuffixList("empty")
+		}
+		globalPublicSuffixList = list
+	})
+	return globalPublicSuffixList
+}
+
+func newPublicSuffixList(source string) *PublicSuffixList {
+	return &PublicSuffixList{
+		rules:      make(map[string]suffixRule),
+		wildcards:  make(map[string]suffixRule),
+		exceptions: make(map[string]suffixRule),
+		source:     source,
+		loadedAt:   time.Now(),
+	}
+}
+
+// ParsePublicSuffixList 从reader解析publicsuffix.org格式的规则文件
+func ParsePublicSuffixList(r io.Reader, source string) (*PublicSuffixList, error) {
+	list := newPublicSuffixList(source)
+
+	icann := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// 注释行中携带分段标记
+		if strings.HasPrefix(line, "//") {
+			switch {
+			case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+				icann = true
+			case strings.Contains(line, "===END ICANN DOMAINS==="):
+				icann = false
+			}
+			continue
+		}
+
+		// 规则在第一个空白字符处结束
+		if idx := strings.IndexAny(line, " \t"); idx != -1 {
+			line = line[:idx]
+		}
+
+		rule := suffixRule{icann: icann}
+		switch {
+		case strings.HasPrefix(line, "!"):
+			if name, ok := toASCIIDomain(line[1:]); ok {
+				list.exceptions[name] = rule
+			}
+		case strings.HasPrefix(line, "*."):
+			if name, ok := toASCIIDomain(line[2:]); ok {
+				list.wildcards[name] = rule
+			}
+		default:
+			if name, ok := toASCIIDomain(line); ok {
+				list.rules[name] = rule
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取公共后缀列表失败: %v", err)
+	}
+
+	return list, nil
+}
+
+// toASCIIDomain 将规则或域名统一转换为小写ASCII(Punycode)形式
+func toASCIIDomain(name string) (string, bool) {
+	name = strings.Trim(strings.ToLower(name), ".")
+	if name == "" {
+		return "", false
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		// 无法转换的输入保持原样，由后续匹配自然失败
+		return name, true
+	}
+	return ascii, true
+}
+
+// RuleCount 返回规则总数
+func (l *PublicSuffixList) RuleCount() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// Info 返回列表的来源与加载状态
+func (l *PublicSuffixList) Info() map[string]interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return map[string]interface{}{
+		"source":    l.source,
+		"loadedAt":  l.loadedAt.Format(time.RFC3339),
+		"ruleCount": len(l.rules) + len(l.wildcards) + len(l.exceptions),
+	}
+}
+
+// replace 用新列表的规则原子替换当前规则
+func (l *PublicSuffixList) replace(other *PublicSuffixList) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = other.rules
+	l.wildcards = other.wildcards
+	l.exceptions = other.exceptions
+	l.source = other.source
+	l.loadedAt = other.loadedAt
+}
+
+// PublicSuffix 返回域名的有效顶级域名
+// includePrivate为false时忽略私有段规则（如blogspot.com），只按注册局层级拆分
+func (l *PublicSuffixList) PublicSuffix(domain string, includePrivate bool) (string, bool) {
+	labels := strings.Split(domain, ".")
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	accept := func(rule suffixRule, ok bool) bool {
+		return ok && (includePrivate || rule.icann)
+	}
+
+	// 从最长的候选后缀开始匹配，第一条命中的即为最长规则
+	for i := 0; i < len(labels); i++ {
+		candidate := strings.Join(labels[i:], ".")
+
+		// 例外规则：后缀为去掉最左侧标签后的部分
+		if rule, ok := l.exceptions[candidate]; accept(rule, ok) {
+			return strings.Join(labels[i+1:], "."), rule.icann
+		}
+
+		if rule, ok := l.rules[candidate]; accept(rule, ok) {
+			return candidate, rule.icann
+		}
+
+		// 通配规则：*.parent 匹配 parent 下任意一级
+		if i+1 < len(labels) {
+			if rule, ok := l.wildcards[strings.Join(labels[i+1:], ".")]; accept(rule, ok) {
+				return candidate, rule.icann
+			}
+		}
+	}
+
+	// 默认规则 "*"：最后一个标签即为顶级域名
+	return labels[len(labels)-1], false
+}
+
+// Split 将域名拆分为子域名、可注册域名和有效顶级域名
+func (l *PublicSuffixList) Split(domain string, includePrivate bool) (*DomainParts, error) {
+	host, ok := toASCIIDomain(SanitizeDomain(strings.TrimSpace(domain)))
+	if !ok {
+		return nil, fmt.Errorf("域名为空")
+	}
+
+	suffix, icann := l.PublicSuffix(host, includePrivate)
+	parts := &DomainParts{
+		Domain:       host,
+		EffectiveTLD: suffix,
+		ICANN:        icann,
+	}
+
+	if host == suffix {
+		return parts, fmt.Errorf("域名本身是公共后缀: %s", host)
+	}
+
+	// 可注册域名 = 有效顶级域名 + 左侧一个标签
+	rest := strings.TrimSuffix(host, "."+suffix)
+	if idx := strings.LastIndex(rest, "."); idx != -1 {
+		parts.Subdomain = rest[:idx]
+		parts.RegistrableDomain = rest[idx+1:] + "." + suffix
+	} else {
+		parts.RegistrableDomain = host
+	}
+
+	return parts, nil
+}
+
+// Refresh 从指定地址下载最新的公共后缀列表并替换当前规则
+func (l *PublicSuffixList) Refresh(ctx context.Context, url string) error {
+	if url == "" {
+		url = DefaultPublicSuffixListURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("User-Agent", "Whosee-PSL-Refresher/1.0")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("下载公共后缀列表失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载公共后缀列表失败: HTTP %d", resp.StatusCode)
+	}
+
+	fresh, err := ParsePublicSuffixList(io.LimitReader(resp.Body, maxPublicSuffixListSize), url)
+	if err != nil {
+		return err
+	}
+
+	if count := fresh.RuleCount(); count < minPublicSuffixRules {
+		return fmt.Errorf("公共后缀列表规则数异常: %d", count)
+	}
+
+	l.replace(fresh)
+	log.Printf("[PSL] 公共后缀列表已刷新，来源: %s，规则数: %d", url, fresh.RuleCount())
+	return nil
+}
+
+// StartPublicSuffixListRefresher 按固定间隔刷新全局公共后缀列表，返回停止函数
+func StartPublicSuffixListRefresher(url string, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	list := GetPublicSuffixList()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			refreshCtx, refreshCancel := context.WithTimeout(ctx, time.Minute)
+			if err := list.Refresh(refreshCtx, url); err != nil {
+				log.Printf("[PSL] 刷新失败，继续使用当前列表: %v", err)
+			}
+			refreshCancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// SplitDomain 使用全局列表按注册局层级（仅ICANN段）拆分域名
+// WHOIS/RDAP只认识注册局层级的域名，私有后缀（如blogspot.com）下的站点需要查询其上级域名
+func SplitDomain(domain string) (*DomainParts, error) {
+	return GetPublicSuffixList().Split(domain, false)
+}
+
+// RegistrableDomain 返回域名的可注册域名，无法拆分时返回清理后的原始域名
+func RegistrableDomain(domain string) string {
+	parts, err := SplitDomain(domain)
+	if err != nil || parts.RegistrableDomain == "" {
+		return SanitizeDomain(domain)
+	}
+	return parts.RegistrableDomain
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSplitDomainWithEmbeddedList 测试内置公共后缀列表的域名拆分
+func TestSplitDomainWithEmbeddedList(t *testing.T) {
+	cases := []struct {
+		input        string
+		subdomain    string
+		registrable  string
+		effectiveTLD string
+	}{
+		{"example.com", "", "example.com", "com"},
+		{"www.example.co.uk", "www", "example.co.uk", "co.uk"},
+		{"https://a.b.Example.COM.au:8443/path", "a.b", "example.com.au", "com.au"},
+		// 私有段规则在WHOIS场景下被忽略，查询其上级的注册局域名
+		{"foo.blogspot.com", "foo", "blogspot.com", "com"},
+		// 国际化域名统一转换为Punycode
+		{"www.例子.中国", "www", "xn--fsqu00a.xn--fiqs8s", "xn--fiqs8s"},
+	}
+
+	for _, tc := range cases {
+		parts, err := SplitDomain(tc.input)
+		if err != nil {
+			t.Errorf("SplitDomain(%q) 返回错误: %v", tc.input, err)
+			continue
+		}
+		if parts.Subdomain != tc.subdomain || parts.RegistrableDomain != tc.registrable || parts.EffectiveTLD != tc.effectiveTLD {
+			t.Errorf("SplitDomain(%q) = {%q, %q, %q}, 期望 {%q, %q, %q}", tc.input,
+				parts.Subdomain, parts.RegistrableDomain, parts.EffectiveTLD,
+				tc.subdomain, tc.registrable, tc.effectiveTLD)
+		}
+	}
+
+	if _, err := SplitDomain("co.uk"); err == nil {
+		t.Errorf("公共后缀本身应返回错误")
+	}
+}
+
+// TestPublicSuffixRuleTypes 测试通配规则、例外规则和私有段规则
+func TestPublicSuffixRuleTypes(t *testing.T) {
+	const rules = `
+// ===BEGIN ICANN DOMAINS===
+com
+*.ck
+!www.ck
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+blogspot.com
+// ===END PRIVATE DOMAINS===
+`
+	list, err := ParsePublicSuffixList(strings.NewReader(rules), "test")
+	if err != nil {
+		t.Fatalf("解析规则失败: %v", err)
+	}
+
+	cases := []struct {
+		input          string
+		includePrivate bool
+		registrable    string
+	}{
+		{"a.b.foo.ck", false, "b.foo.ck"},
+		{"shop.www.ck", false, "www.ck"},
+		{"foo.blogspot.com", true, "foo.blogspot.com"},
+		{"foo.blogspot.com", false, "blogspot.com"},
+		{"host.example.unknowntld", false, "example.unknowntld"},
+	}
+
+	for _, tc := range cases {
+		parts, err := list.Split(tc.input, tc.includePrivate)
+		if err != nil {
+			t.Errorf("Split(%q, %v) 返回错误: %v", tc.input, tc.includePrivate, err)
+			continue
+		}
+		if parts.RegistrableDomain != tc.registrable {
+			t.Errorf("Split(%q, %v) 可注册域名 = %q, 期望 %q", tc.input, tc.includePrivate, parts.RegistrableDomain, tc.registrable)
+		}
+	}
+}